@@ -1,7 +1,6 @@
 package code
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -59,29 +58,39 @@ func injectModifiedCode(pkg Package, nodeCount int) error {
 			modifiedCode.WriteString(fmt.Sprintf("%snode_count%s: int = %d\n", m.VariablePrefix, m.VariableSuffix, nodeCount))
 			modifiedCode.WriteString(fmt.Sprintf("%snode%s: int = %d\n\n\n", m.VariablePrefix, m.VariableSuffix, i))
 
-			moduleFile, err := os.Open(modulePath)
+			src, err := os.ReadFile(modulePath)
 			if err != nil {
 				return err
 			}
 
-			scanner := bufio.NewScanner(moduleFile)
-			scanner.Split(bufio.ScanLines)
-			line := 0
-			for scanner.Scan() {
-				line++
-				text := scanner.Text()
-				stmt := m.GetStatement(line)
-
-				if stmt == nil {
-					modifiedCode.WriteString(fmt.Sprintf("%s\n", text))
-				} else if !stmt.ShouldSkip {
-					modifiedCode.WriteString(fmt.Sprintf("%s\n", stmt.ModifiedCode))
-				}
+			lines := strings.Split(strings.TrimSuffix(string(src), "\n"), "\n")
+			for i := range lines {
+				lines[i] = strings.TrimSuffix(lines[i], "\r")
 			}
 
-			err = moduleFile.Close()
-			if err != nil {
-				return err
+			for i := 0; i < len(lines); i++ {
+				statements := m.GetStatementsOnLine(i + 1)
+				if len(statements) == 0 {
+					modifiedCode.WriteString(fmt.Sprintf("%s\n", lines[i]))
+					continue
+				}
+
+				endLine := i + 1
+				for line := i + 1; line <= endLine; line++ {
+					if line > i+1 {
+						statements = append(statements, m.GetStatementsOnLine(line)...)
+					}
+					for _, s := range statements {
+						if s.EndLine > endLine {
+							endLine = s.EndLine
+						}
+					}
+				}
+
+				if code, ok := getModifiedLines(lines[i:endLine], i+1, statements); ok {
+					modifiedCode.WriteString(fmt.Sprintf("%s\n", code))
+				}
+				i = endLine - 1
 			}
 
 			err = os.WriteFile(modulePath, []byte(modifiedCode.String()), 0775)
@@ -93,3 +102,43 @@ func injectModifiedCode(pkg Package, nodeCount int) error {
 
 	return nil
 }
+
+// getModifiedLines replaces the source of each statement found within the
+// lines (starting at firstLine) with its modified code, returning false if
+// the lines should be dropped altogether.
+func getModifiedLines(lines []string, firstLine int, statements []*Statement) (string, bool) {
+	text := strings.Join(lines, "\n")
+	rest := text
+	skipped := true
+
+	offset := func(line, column int) int {
+		o := column
+		for l := firstLine; l < line; l++ {
+			o += len(lines[l-firstLine]) + 1
+		}
+		return o
+	}
+
+	for i := len(statements) - 1; i >= 0; i-- {
+		s := statements[i]
+		start := offset(s.Line, s.Column) - len(s.Indentation)
+		end := offset(s.EndLine, s.EndColumn)
+
+		code := s.ModifiedCode
+		if s.ShouldSkip {
+			code = s.Indentation + "pass"
+		} else {
+			skipped = false
+		}
+
+		text = text[:start] + code + text[end:]
+		rest = rest[:start] + rest[end:]
+	}
+
+	rest = strings.TrimSpace(strings.ReplaceAll(rest, ";", ""))
+	if skipped && (rest == "" || strings.HasPrefix(rest, "#")) {
+		return "", false
+	}
+
+	return text, true
+}
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"tonysoft.com/gothon/internal/io"
+	"tonysoft.com/gothon/internal/memory"
 	"tonysoft.com/gothon/internal/memory/config"
 )
 
@@ -36,19 +39,58 @@ func interpretStatements(pkg Package) error {
 }
 
 func interpretStatement(s *Statement) error {
+	if s.Actions.Contains(VariableDefinition) && !s.ShouldSkip && s.TargetVariable.Type == Object {
+		// the initial value can only be serialized by the interpreter, so it's
+		// sent by every node and kept only by the first to arrive
+		init := getFuncCall(s.TargetVariable.ID, "set", strings.TrimSpace(s.OriginalRValue)+", True")
+		s.ModifiedCode = fmt.Sprintf("%s%s= %s", s.Indentation, s.OriginalLValue, init)
+		return nil
+	}
+
+	if s.Actions.Contains(VariableDefinition) && !s.ShouldSkip && s.TargetVariable.Type == Condition {
+		// the lock is managed by Gothon, so there's nothing for the interpreter
+		// to construct a condition from
+		s.ModifiedCode = fmt.Sprintf("%s%s= None", s.Indentation, s.OriginalLValue)
+		return nil
+	}
+
 	if s.Actions.Contains(VariableDefinition) || s.ShouldSkip {
 		return nil
 	}
 
+	for _, v := range s.UsedVariables {
+		if supportsAtomicOperations(v) {
+			s.ModifiedRValue = translateAtomicReferences(s.ModifiedRValue, v)
+		}
+
+		if v.Type == Event || v.Type == Condition {
+			s.ModifiedRValue = translateSignalReferences(s.ModifiedRValue, v)
+		}
+
+		if v.IsQueue() {
+			s.ModifiedRValue = translateTaskReferences(s.ModifiedRValue, v)
+		}
+
+		if v.Type == Chan {
+			s.ModifiedRValue = translateChanReferences(s.ModifiedRValue, v)
+		}
+
+		if v.IsSemaphoreFunc() || v.Type == Barrier || v.Type == Batch || v.Type == Transaction {
+			s.ModifiedRValue = translateReferences(s.ModifiedRValue, v.Name, func(ref reference) (string, bool) {
+				return getFuncCall(v.ID, "call", ref.args), ref.kind == callReference
+			})
+		}
+	}
+
 	if s.Actions.Contains(VariableAssignment) {
 		for _, v := range s.UsedVariables {
-			regex, err := regexp.Compile("([_A-Za-z][_A-Za-z0-9]*\\.)?(" + v.Name + ")")
-			if err != nil {
-				panic(err)
+			if v.Type == Dict || v.Type == List || v.Type == Set {
+				s.ModifiedRValue = strings.TrimSpace(translateCollectionReferences(s.ModifiedRValue, v))
+				continue
 			}
 
 			get := getFuncCall(v.ID, "get")
-			s.ModifiedRValue = strings.TrimSpace(regex.ReplaceAllString(s.OriginalRValue, get))
+			s.ModifiedRValue = strings.TrimSpace(replaceVariable(s.ModifiedRValue, v.Name, get))
 		}
 
 		s.ModifiedRValue = getFuncCall(s.TargetVariable.ID, "set", s.ModifiedRValue)
@@ -57,7 +99,7 @@ func interpretStatement(s *Statement) error {
 	}
 
 	if s.Actions.Contains(MutexLock) || s.Actions.Contains(MutexUnlock) {
-		regex, err := regexp.Compile("([_A-Za-z][_A-Za-z0-9]*\\.)?(" + s.TargetVariable.Name + ")")
+		regex, err := regexp.Compile("([_A-Za-z][_A-Za-z0-9]*\\.)?(" + s.TargetVariable.Name + ")\\(\\)")
 		if err != nil {
 			panic(err)
 		}
@@ -79,15 +121,26 @@ func interpretStatement(s *Statement) error {
 		return nil
 	}
 
+	extremum := ""
+	switch {
+	case s.Actions.Contains(VariableMax):
+		extremum = "max"
+	case s.Actions.Contains(VariableMin):
+		extremum = "min"
+	}
+	if extremum != "" {
+		// only the other operand is sent, the comparison is made by the register
+		_, s.ModifiedRValue = getExtremumUpdate(s.ModifiedRValue, s.TargetVariable)
+	}
+
 	if s.Actions.Contains(VariableUsage) {
 		for _, v := range s.UsedVariables {
-			regex, err := regexp.Compile("([_A-Za-z][_A-Za-z0-9]*\\.)?(" + v.Name + ")")
-			if err != nil {
-				panic(err)
+			if !v.IsScalar() {
+				continue
 			}
 
 			get := getFuncCall(v.ID, "get")
-			s.ModifiedRValue = strings.TrimSpace(regex.ReplaceAllString(s.ModifiedRValue, get))
+			s.ModifiedRValue = strings.TrimSpace(replaceVariable(s.ModifiedRValue, v.Name, get))
 		}
 
 		if s.Actions == VariableUsage && s.OriginalLValue == "" {
@@ -96,6 +149,36 @@ func interpretStatement(s *Statement) error {
 		}
 	}
 
+	for _, v := range s.UsedVariables {
+		if v.Type == Dict || v.Type == List || v.Type == Set {
+			s.ModifiedRValue = translateCollectionReferences(s.ModifiedRValue, v)
+		}
+	}
+
+	if s.Actions.Contains(DictSet) || s.Actions.Contains(DictAdd) || s.Actions.Contains(DictDelete) ||
+		s.Actions.Contains(ListSet) {
+		target := s.OriginalLValue
+		if s.Actions.Contains(DictDelete) {
+			target = s.OriginalRValue
+		}
+
+		refs := findReferences(target, s.TargetVariable.Name)
+		if len(refs) == 0 || refs[0].kind != subscriptReference {
+			return errors.New("interpretation error: subscript not found")
+		}
+		key := strings.TrimSpace(refs[0].args)
+
+		switch {
+		case s.Actions.Contains(DictDelete):
+			s.ModifiedCode = s.Indentation + getFuncCall(s.TargetVariable.ID, "del", key)
+		case s.Actions.Contains(DictAdd):
+			s.ModifiedCode = s.Indentation + getFuncCall(s.TargetVariable.ID, "add", key+", "+strings.TrimSpace(s.ModifiedRValue))
+		default:
+			s.ModifiedCode = s.Indentation + getFuncCall(s.TargetVariable.ID, "set", key+", "+strings.TrimSpace(s.ModifiedRValue))
+		}
+		return nil
+	}
+
 	if s.Actions.Contains(VariableAdd) {
 		s.ModifiedRValue = getFuncCall(s.TargetVariable.ID, "add", s.ModifiedRValue)
 	}
@@ -118,6 +201,16 @@ func interpretStatement(s *Statement) error {
 		s.ModifiedRValue = getFuncCall(s.TargetVariable.ID, "div", s.ModifiedRValue)
 	}
 
+	for action, name := range integerOperatorActions {
+		if s.Actions.Contains(action) {
+			s.ModifiedRValue = getFuncCall(s.TargetVariable.ID, name, s.ModifiedRValue)
+		}
+	}
+
+	if extremum != "" {
+		s.ModifiedRValue = getFuncCall(s.TargetVariable.ID, extremum, s.ModifiedRValue)
+	}
+
 	subType := ""
 	if s.TargetVariable != nil {
 		subType = fmt.Sprintf("%s_", s.TargetVariable.SubType)
@@ -170,32 +263,254 @@ func interpretStatement(s *Statement) error {
 		return errors.New("interpretation error: ModifiedRValue == OriginalRValue")
 	}
 
+	if s.OriginalLValue == "" {
+		s.ModifiedCode = s.Indentation + strings.TrimSpace(s.ModifiedRValue)
+		return nil
+	}
+
 	s.ModifiedCode = fmt.Sprintf("%s%s= %s", s.Indentation, s.OriginalLValue, strings.TrimSpace(s.ModifiedRValue))
 	return nil
 }
 
-func getFuncDefinition(variableID string, varType VariableType, action string) (name, def string) {
+func getFuncDefinition(v *Variable, varType VariableType, action string) (name, def string) {
 	switch {
-	case action == "mutex", action == "sync":
-		name = fmt.Sprintf("gothon_%s", translateID(variableID))
-		def = fillTemplate(templates[action], translateID(variableID), action)
-		return name, def
+	case action == "mutex", action == "sync", action == "acquire", action == "release", action == "barrier",
+		action == "batch", action == "transaction":
+		name = fmt.Sprintf("gothon_%s", translateID(v.ID))
+		def = fillTemplate(templates[action], translateID(v.ID), action)
 	case strings.HasPrefix(action, "queue_"):
 		queueAction := strings.TrimPrefix(action, "queue_")
-		name = fmt.Sprintf("gothon_%s_%s", translateID(variableID), queueAction)
-		def = fillTemplate(templates[action], translateID(variableID), queueAction)
-		return name, def
+		name = fmt.Sprintf("gothon_%s_%s", translateID(v.ID), queueAction)
+		def = fillTemplate(templates[action], translateID(v.ID), queueAction)
+		action = queueAction
 	case strings.Contains(action, "_queue_"):
 		actionParts := strings.Split(action, "_")
 		queueAction := actionParts[2]
-		name = fmt.Sprintf("gothon_%s_%s", translateID(variableID), queueAction)
-		def = fillTemplate(templates[action], translateID(variableID), queueAction)
-		return name, def
+		name = fmt.Sprintf("gothon_%s_%s", translateID(v.ID), queueAction)
+		def = fillTemplate(templates[action], translateID(v.ID), queueAction)
+		action = queueAction
 	default:
-		name = fmt.Sprintf("gothon_%s_%s", translateID(variableID), action)
-		def = fillTemplate(templates[varType.String()+"_"+action], translateID(variableID), action)
-		return name, def
+		name = fmt.Sprintf("gothon_%s_%s", translateID(v.ID), action)
+		def = fillTemplate(templates[varType.String()+"_"+action], translateID(v.ID), action)
+	}
+	return name, strings.ReplaceAll(def, "{{request}}", getRequest(v, action))
+}
+
+func getActionFuncDefinition(v *Variable, action string) (name, def string) {
+	valueType := v.SubType
+	if v.IsScalar() {
+		valueType = v.Type
+	}
+
+	name = fmt.Sprintf("gothon_%s_%s", translateID(v.ID), action)
+	def = fillTemplate(templates[v.Type.String()+"_"+action], translateID(v.ID), action)
+	def = strings.ReplaceAll(def, "{{request}}", getRequest(v, action))
+	def = strings.ReplaceAll(def, "{{key_type}}", v.KeyType.String())
+	def = strings.ReplaceAll(def, "{{value_type}}", valueType.String())
+	def = strings.ReplaceAll(def, "{{key_encode}}", strings.ReplaceAll(encodeTemplates[v.KeyType.String()], "{{val}}", "key"))
+	def = strings.ReplaceAll(def, "{{value_encode}}", strings.ReplaceAll(encodeTemplates[valueType.String()], "{{val}}", "val"))
+	def = strings.ReplaceAll(def, "{{expected_encode}}", strings.ReplaceAll(encodeTemplates[valueType.String()], "{{val}}", "expected"))
+	def = strings.ReplaceAll(def, "{{key_decode}}", decodeTemplates[v.KeyType.String()])
+	def = strings.ReplaceAll(def, "{{value_decode}}", decodeTemplates[valueType.String()])
+	def = strings.ReplaceAll(def, "{{value_zero}}", zeroTemplates[valueType.String()])
+	return name, def
+}
+
+func translateCollectionReferences(code string, v *Variable) string {
+	switch v.Type {
+	case Dict:
+		return translateDictReferences(code, v)
+	case List:
+		return translateListReferences(code, v)
+	case Set:
+		return translateSetReferences(code, v)
+	default:
+		return code
+	}
+}
+
+func translateListReferences(code string, v *Variable) string {
+	return translateReferences(code, v.Name, func(ref reference) (string, bool) {
+		switch {
+		case ref.kind == subscriptReference:
+			bounds := getSliceBounds(ref.args)
+			if bounds == nil {
+				return getFuncCall(v.ID, "get", ref.args), true
+			}
+			return getFuncCall(v.ID, "slice", strings.Join(bounds, ", ")), true
+		case ref.kind == methodReference && ref.method == "append":
+			return getFuncCall(v.ID, "append", ref.args), true
+		case ref.kind == lengthReference:
+			return getFuncCall(v.ID, "size"), true
+		case ref.kind == membershipReference:
+			op := " in "
+			if ref.negated {
+				op = " not in "
+			}
+			return ref.operand + op + getFuncCall(v.ID, "slice"), true
+		case ref.kind == iterationReference, ref.kind == bareReference:
+			return getFuncCall(v.ID, "slice"), true
+		default:
+			return "", false
+		}
+	})
+}
+
+// getSliceBounds returns the start, stop and step of a slice (None where
+// omitted), or nil if the subscript is a plain index.
+func getSliceBounds(subscript string) []string {
+	all, err := tokenize(subscript)
+	if err != nil {
+		return nil
+	}
+
+	tokens := make([]token, 0)
+	for _, t := range all {
+		if t.Type != newlineToken && t.Type != nlToken && t.Type != endMarkerToken {
+			tokens = append(tokens, t)
+		}
+	}
+	if findAtDepthZero(tokens, ":") < 0 {
+		return nil
+	}
+
+	bounds := make([]string, 0)
+	for {
+		end := findAtDepthZero(tokens, ":")
+		if end < 0 {
+			end = len(tokens)
+		}
+
+		if end == 0 {
+			bounds = append(bounds, "None")
+		} else {
+			bounds = append(bounds, subscript[tokens[0].start:tokens[end-1].end])
+		}
+
+		if end >= len(tokens) {
+			return bounds
+		}
+		tokens = tokens[end+1:]
+	}
+}
+
+// translateSignalReferences translates the calls to the methods of an Event or
+// Condition, which are named after those of the threading module's.
+func translateSignalReferences(code string, v *Variable) string {
+	actions := map[string]string{"set": "set", "clear": "clear", "is_set": "isset", "wait": "wait"}
+	if v.Type == Condition {
+		actions = map[string]string{"wait": "wait", "notify": "notify", "notify_all": "notifyall"}
 	}
+
+	return translateReferences(code, v.Name, func(ref reference) (string, bool) {
+		action, ok := actions[ref.method]
+		if ref.kind != methodReference || !ok {
+			return "", false
+		}
+		return getFuncCall(v.ID, action, ref.args), true
+	})
+}
+
+// translateTaskReferences translates the calls to a queue's task_done() and
+// join() methods.
+func translateTaskReferences(code string, v *Variable) string {
+	actions := map[string]string{"task_done": "taskdone", "join": "join"}
+
+	return translateReferences(code, v.Name, func(ref reference) (string, bool) {
+		action, ok := actions[ref.method]
+		if ref.kind != methodReference || !ok {
+			return "", false
+		}
+		return getFuncCall(v.ID, action, ref.args), true
+	})
+}
+
+// translateChanReferences translates the calls to a channel's send(), recv()
+// and close() methods, and the for loops receiving from it until it's closed.
+func translateChanReferences(code string, v *Variable) string {
+	actions := map[string]string{"send": "send", "recv": "recv", "close": "close"}
+
+	return translateReferences(code, v.Name, func(ref reference) (string, bool) {
+		if ref.kind == iterationReference {
+			return getFuncCall(v.ID, "iter", ""), true
+		}
+
+		action, ok := actions[ref.method]
+		if ref.kind != methodReference || !ok {
+			return "", false
+		}
+		return getFuncCall(v.ID, action, ref.args), true
+	})
+}
+
+func translateSetReferences(code string, v *Variable) string {
+	return translateReferences(code, v.Name, func(ref reference) (string, bool) {
+		switch {
+		case ref.kind == methodReference && ref.method == "add":
+			return getFuncCall(v.ID, "add", ref.args), true
+		case ref.kind == methodReference && ref.method == "discard":
+			return getFuncCall(v.ID, "del", ref.args), true
+		case ref.kind == methodReference && ref.method == "remove":
+			return getFuncCall(v.ID, "del", strings.TrimSpace(ref.args)+", True"), true
+		case ref.kind == membershipReference:
+			call := getFuncCall(v.ID, "contains", ref.operand)
+			if ref.negated {
+				call = "not " + call
+			}
+			return call, true
+		case ref.kind == lengthReference:
+			return getFuncCall(v.ID, "size"), true
+		case ref.kind == iterationReference:
+			return getFuncCall(v.ID, "keys"), true
+		case ref.kind == bareReference:
+			return "set(" + getFuncCall(v.ID, "keys") + ")", true
+		default:
+			return "", false
+		}
+	})
+}
+
+func translateAtomicReferences(code string, v *Variable) string {
+	return translateReferences(code, v.Name, func(ref reference) (string, bool) {
+		switch {
+		case ref.kind == methodReference && ref.method == "fetch_add" && v.Type != Bool:
+			return getFuncCall(v.ID, "fetch_add", ref.args), true
+		case ref.kind == methodReference && ref.method == "swap":
+			return getFuncCall(v.ID, "swap", ref.args), true
+		case ref.kind == methodReference && ref.method == "compare_and_swap":
+			return getFuncCall(v.ID, "cas", ref.args), true
+		default:
+			return "", false
+		}
+	})
+}
+
+func translateDictReferences(code string, v *Variable) string {
+	return translateReferences(code, v.Name, func(ref reference) (string, bool) {
+		switch {
+		case ref.kind == subscriptReference:
+			return getFuncCall(v.ID, "get", ref.args), true
+		case ref.kind == methodReference && ref.method == "get":
+			args := ref.args
+			tokens, err := tokenize(args)
+			if err == nil && findAtDepthZero(tokens, ",") < 0 {
+				args = strings.TrimSpace(args) + ", None"
+			}
+			return getFuncCall(v.ID, "get", args), true
+		case ref.kind == methodReference && ref.method == "keys", ref.kind == iterationReference:
+			return getFuncCall(v.ID, "keys"), true
+		case ref.kind == lengthReference:
+			return getFuncCall(v.ID, "size"), true
+		case ref.kind == membershipReference:
+			call := getFuncCall(v.ID, "contains", ref.operand)
+			if ref.negated {
+				call = "not " + call
+			}
+			return call, true
+		default:
+			return "", false
+		}
+	})
 }
 
 func getFuncCall(variableID string, action string, arg ...string) string {
@@ -205,7 +520,14 @@ func getFuncCall(variableID string, action string, arg ...string) string {
 		return fmt.Sprintf("gothon_%s()", variableID)
 	case "sync":
 		return fmt.Sprintf("gothon_%s(%s)", variableID, arg[0])
-	case "get", "size", "empty", "full":
+	case "call":
+		return fmt.Sprintf("gothon_%s(%s)", variableID, strings.TrimSpace(arg[0]))
+	case "fetch_add":
+		return fmt.Sprintf("gothon_%s_%s(%s)", variableID, action, strings.TrimSpace(arg[0]))
+	case "get", "size", "empty", "full", "keys", "slice":
+		if len(arg) > 0 {
+			return fmt.Sprintf("gothon_%s_%s(%s)", variableID, action, strings.TrimSpace(arg[0]))
+		}
 		return fmt.Sprintf("gothon_%s_%s()", variableID, action)
 	default:
 		actionParts := strings.Split(action, "_")
@@ -258,27 +580,6 @@ func getQueueFuncCall(rValue string, variableName string, variableID string, act
 	return rValue
 }
 
-func getSocketInit(variableID string, action string) (name, code string) {
-	switch action {
-	case "mutex":
-		name = fmt.Sprintf("%s", translateID(variableID))
-		code = fillTemplate(socketInitTemplateForMutex, translateID(variableID), "")
-		return name, code
-	case "sync":
-		name = fmt.Sprintf("%s", translateID(variableID))
-		code = fillTemplate(socketInitTemplateForSync, translateID(variableID), "")
-		return name, code
-	case "queue_get":
-		name = fmt.Sprintf("%s", translateID(variableID))
-		code = fillTemplate(socketInitTemplateForQueueGet, translateID(variableID), "")
-		return name, code
-	default:
-		name = fmt.Sprintf("%s_%s", translateID(variableID), action)
-		code = fillTemplate(socketInitTemplate, translateID(variableID), action)
-		return name, code
-	}
-}
-
 func fillTemplate(template string, variableID string, action string) string {
 	result := strings.ReplaceAll(template, "{{var_id}}", variableID)
 	result = strings.ReplaceAll(result, "{{action}}", action)
@@ -295,269 +596,125 @@ func translateID(variableID string) string {
 func getSocketModule(pkg Package) (SocketModule, error) {
 	sb := strings.Builder{}
 
+	sb.WriteString("import collections\n")
+	sb.WriteString("import fcntl\n")
+	sb.WriteString("import math\n")
+	sb.WriteString("import mmap\n")
+	sb.WriteString("import os\n")
+	sb.WriteString("import pickle\n")
+	sb.WriteString("import select\n")
 	sb.WriteString("import struct\n")
 	sb.WriteString("import sys\n")
-	sb.WriteString("import socket\n\n")
+	sb.WriteString("import socket\n")
+	sb.WriteString("import threading\n")
+	sb.WriteString("import time\n\n")
+	socket := strings.ReplaceAll(socketTemplate, "{{protocol_version}}", strconv.Itoa(int(io.ProtocolVersion)))
+	socket = strings.ReplaceAll(socket, "{{cancel_opcode}}", strconv.Itoa(int(io.GetOpcode("cancel"))))
+	sb.WriteString(socket + "\n\n")
 
-	socks, addrs, funcs, init, err := getModuleParts(pkg)
-	if err != nil {
-		return "", err
+	funcs := getModuleParts(pkg)
+
+	if len(pkg.GetSharedMemoryVariables()) > 0 {
+		sb.WriteString(strings.ReplaceAll(sharedMemoryTemplate, "{{slot_size}}", strconv.Itoa(memory.SharedMemorySlotSize)) + "\n\n")
 	}
 
-	writeSocketDefinitions(socks, &sb)
-	writeAddressDefinitions(addrs, &sb)
 	writeFunctionDefinitions(funcs, &sb)
-	writeSocketInit(init, &sb)
+	sb.WriteString(socketInitTemplate)
 
 	return SocketModule(sb.String()), nil
 }
 
-func getModuleParts(pkg Package) (socks, addrs, funcs, init map[string]string, err error) {
-	socks = make(map[string]string)
-	addrs = make(map[string]string)
+func getModuleParts(pkg Package) (funcs map[string]string) {
 	funcs = make(map[string]string)
-	init = make(map[string]string)
 
 	for _, m := range pkg {
 		for _, s := range m.Statements {
-			setSocketDefinitions(socks, s)
-			setAddressDefinitions(addrs, s)
 			setFunctionDefinitions(funcs, s)
-			setSocketInit(init, s)
 		}
 	}
 
-	if len(socks) != len(addrs) {
-		return nil, nil, nil, nil, errors.New("interpretation error: len(socks) != len(addrs)")
+	for _, v := range pkg.GetSharedMemoryVariables() {
+		setSharedMemoryDefinitions(funcs, v)
 	}
 
-	return socks, addrs, funcs, init, nil
+	return funcs
 }
 
-func setSocketDefinitions(defs map[string]string, s *Statement) {
-	getDef := func(name string) string {
-		return fmt.Sprintf("%s = socket.socket(socket.AF_UNIX, socket.SOCK_DGRAM)", name)
-	}
-
-	if s.ShouldSkip {
-		return
-	}
-
-	if s.Actions.Contains(VariableDefinition) || s.Actions.Contains(VariableAssignment) {
-		if s.TargetVariable.Type == LockFunc || s.TargetVariable.Type == UnlockFunc || s.TargetVariable.Type == WaitGroup {
-			name := fmt.Sprintf("_sock_%s_in", translateID(s.TargetVariable.ID))
-			defs[name] = getDef(name)
-
-			name = fmt.Sprintf("_sock_%s_out", translateID(s.TargetVariable.ID))
-			defs[name] = getDef(name)
-		} else {
-			name := fmt.Sprintf("_sock_%s_set_in", translateID(s.TargetVariable.ID))
-			defs[name] = getDef(name)
-
-			name = fmt.Sprintf("_sock_%s_set_out", translateID(s.TargetVariable.ID))
-			defs[name] = getDef(name)
-		}
-	}
-
-	if s.Actions.Contains(VariableUsage) {
-		for _, v := range s.UsedVariables {
-			name := fmt.Sprintf("_sock_%s_get_in", translateID(v.ID))
-			defs[name] = getDef(name)
-
-			name = fmt.Sprintf("_sock_%s_get_out", translateID(v.ID))
-			defs[name] = getDef(name)
-		}
-	}
-
-	if s.Actions.Contains(VariableAdd) {
-		name := fmt.Sprintf("_sock_%s_add_in", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-
-		name = fmt.Sprintf("_sock_%s_add_out", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-	}
-
-	if s.Actions.Contains(VariableSubtract) {
-		name := fmt.Sprintf("_sock_%s_sub_in", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-
-		name = fmt.Sprintf("_sock_%s_sub_out", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-	}
-
-	if s.Actions.Contains(VariableMultiply) {
-		name := fmt.Sprintf("_sock_%s_mul_in", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-
-		name = fmt.Sprintf("_sock_%s_mul_out", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-	}
-
-	if s.Actions.Contains(VariableDivide) {
-		name := fmt.Sprintf("_sock_%s_div_in", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-
-		name = fmt.Sprintf("_sock_%s_div_out", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-	}
-
-	if s.Actions == QueueSize {
-		for _, v := range s.UsedVariables {
-			name := fmt.Sprintf("_sock_%s_size_in", translateID(v.ID))
-			defs[name] = getDef(name)
-
-			name = fmt.Sprintf("_sock_%s_size_out", translateID(v.ID))
-			defs[name] = getDef(name)
-		}
-	}
-
-	if s.Actions == QueueEmpty {
-		for _, v := range s.UsedVariables {
-			name := fmt.Sprintf("_sock_%s_empty_in", translateID(v.ID))
-			defs[name] = getDef(name)
+// setSharedMemoryDefinitions replaces the functions of a variable kept in the
+// shared memory file with those accessing it there.
+func setSharedMemoryDefinitions(funcs map[string]string, v *Variable) {
+	id := translateID(v.ID)
 
-			name = fmt.Sprintf("_sock_%s_empty_out", translateID(v.ID))
-			defs[name] = getDef(name)
+	for key, template := range sharedMemoryTemplates {
+		typeName, action, _ := strings.Cut(key, "_")
+		if typeName != v.Type.String() {
+			continue
 		}
-	}
-
-	if s.Actions == QueueFull {
-		for _, v := range s.UsedVariables {
-			name := fmt.Sprintf("_sock_%s_full_in", translateID(v.ID))
-			defs[name] = getDef(name)
 
-			name = fmt.Sprintf("_sock_%s_full_out", translateID(v.ID))
-			defs[name] = getDef(name)
-		}
+		def := fillTemplate(template, id, action)
+		funcs[fmt.Sprintf("gothon_%s_%s", id, action)] = strings.ReplaceAll(def, "{{value_type}}", typeName)
 	}
 
-	if s.Actions.Contains(QueuePut) {
-		name := fmt.Sprintf("_sock_%s_set_in", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-
-		name = fmt.Sprintf("_sock_%s_set_out", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-	}
-
-	if s.Actions == QueueGet {
-		name := fmt.Sprintf("_sock_%s_get_in", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-
-		name = fmt.Sprintf("_sock_%s_get_out", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-
-		name = fmt.Sprintf("_sock_%s_get_ok", translateID(s.TargetVariable.ID))
-		defs[name] = getDef(name)
-	}
+	funcs["_shm_"+id] = fmt.Sprintf("_shm_%s = _GothonSharedValue('%s', %d, %s)", id, v.Name, v.SharedMemorySlot, v.Type)
 }
 
-func setAddressDefinitions(addrs map[string]string, s *Statement) {
-	getDef := func(name, id, action string) string {
-		return fmt.Sprintf("%s = '{{gothon_dir}}/sock/{{node_id}}/%s/%s'", name, id, action)
-	}
-
-	if s.ShouldSkip {
-		return
-	}
-
-	if s.Actions.Contains(VariableDefinition) || s.Actions.Contains(VariableAssignment) || s.Actions.Contains(QueuePut) {
-		if s.TargetVariable.Type == LockFunc || s.TargetVariable.Type == UnlockFunc || s.TargetVariable.Type == WaitGroup {
-			name := fmt.Sprintf("_addr_%s_in", translateID(s.TargetVariable.ID))
-			addrs[name] = fmt.Sprintf("%s = '{{gothon_dir}}/sock/{{node_id}}/%s_in'", name, s.TargetVariable.ID)
-
-			name = fmt.Sprintf("_addr_%s_out", translateID(s.TargetVariable.ID))
-			addrs[name] = fmt.Sprintf("%s = '{{gothon_dir}}/sock/{{node_id}}/%s_out'", name, s.TargetVariable.ID)
-		} else {
-			name := fmt.Sprintf("_addr_%s_set_in", translateID(s.TargetVariable.ID))
-			addrs[name] = getDef(name, s.TargetVariable.ID, "set_in")
-
-			name = fmt.Sprintf("_addr_%s_set_out", translateID(s.TargetVariable.ID))
-			addrs[name] = getDef(name, s.TargetVariable.ID, "set_out")
+// getRequest returns the arguments the generated functions pass to the node's
+// connection along with the payload of a request for the action on the
+// variable: the opcode and variable ID it's sent with, then the variable's name
+// and the operation (as named in errors), its timeout and its access (see
+// getAccess).  Callables have a single operation, sent as a call.
+func getRequest(v *Variable, action string) string {
+	opcode := io.GetOpcode("call")
+	if v.IsLockFunc() || v.IsSemaphoreFunc() || v.Type == WaitGroup || v.Type == Barrier || v.Type == Batch ||
+		v.Type == Transaction {
+		action = strings.TrimSuffix(v.Type.String(), "_func")
+		if v.Type == WaitGroup {
+			action = "sync"
 		}
+	} else {
+		opcode = io.GetOpcode(action)
 	}
 
-	if s.Actions.Contains(VariableUsage) {
-		for _, v := range s.UsedVariables {
-			name := fmt.Sprintf("_addr_%s_get_in", translateID(v.ID))
-			addrs[name] = getDef(name, v.ID, "get_in")
-
-			name = fmt.Sprintf("_addr_%s_get_out", translateID(v.ID))
-			addrs[name] = getDef(name, v.ID, "get_out")
-		}
+	timeout := "_gothon_timeout"
+	if v.Timeout > 0 {
+		timeout = strconv.FormatFloat(v.Timeout, 'f', -1, 64)
 	}
 
-	if s.Actions.Contains(VariableAdd) {
-		name := fmt.Sprintf("_addr_%s_add_in", translateID(s.TargetVariable.ID))
-		addrs[name] = getDef(name, s.TargetVariable.ID, "add_in")
-
-		name = fmt.Sprintf("_addr_%s_add_out", translateID(s.TargetVariable.ID))
-		addrs[name] = getDef(name, s.TargetVariable.ID, "add_out")
+	access := "None"
+	if a := getAccess(v, action); a != "" {
+		access = "'" + a + "'"
 	}
 
-	if s.Actions.Contains(VariableSubtract) {
-		name := fmt.Sprintf("_addr_%s_sub_in", translateID(s.TargetVariable.ID))
-		addrs[name] = getDef(name, s.TargetVariable.ID, "sub_in")
-
-		name = fmt.Sprintf("_addr_%s_sub_out", translateID(s.TargetVariable.ID))
-		addrs[name] = getDef(name, s.TargetVariable.ID, "sub_out")
-	}
-
-	if s.Actions.Contains(VariableMultiply) {
-		name := fmt.Sprintf("_addr_%s_mul_in", translateID(s.TargetVariable.ID))
-		addrs[name] = getDef(name, s.TargetVariable.ID, "mul_in")
-
-		name = fmt.Sprintf("_addr_%s_mul_out", translateID(s.TargetVariable.ID))
-		addrs[name] = getDef(name, s.TargetVariable.ID, "mul_out")
-	}
-
-	if s.Actions.Contains(VariableDivide) {
-		name := fmt.Sprintf("_addr_%s_div_in", translateID(s.TargetVariable.ID))
-		addrs[name] = getDef(name, s.TargetVariable.ID, "div_in")
+	return fmt.Sprintf("%d, '%s', '%s', '%s', %s, %s", opcode, v.ID, v.Name, action, timeout, access)
+}
 
-		name = fmt.Sprintf("_addr_%s_div_out", translateID(s.TargetVariable.ID))
-		addrs[name] = getDef(name, s.TargetVariable.ID, "div_out")
+// getAccess returns how batches and transactions treat the operation on the
+// variable: writes can be buffered by a batch (those with nothing to reply but
+// whether they succeeded), while both reads and writes are made within
+// transactions, unlike the atomic operations and those on variables that
+// aren't scalars kept by Gothon, which can't be.  Batches and transactions
+// themselves aren't treated at all.
+func getAccess(v *Variable, operation string) string {
+	if v.Type == Batch || v.Type == Transaction {
+		return ""
 	}
-
-	if s.Actions.Contains(QueueSize) {
-		for _, v := range s.UsedVariables {
-			name := fmt.Sprintf("_addr_%s_size_in", translateID(v.ID))
-			addrs[name] = getDef(name, v.ID, "size_in")
-
-			name = fmt.Sprintf("_addr_%s_size_out", translateID(v.ID))
-			addrs[name] = getDef(name, v.ID, "size_out")
-		}
+	if !v.IsScalar() || v.SharedMemory {
+		return "none"
 	}
 
-	if s.Actions.Contains(QueueEmpty) {
-		for _, v := range s.UsedVariables {
-			name := fmt.Sprintf("_addr_%s_empty_in", translateID(v.ID))
-			addrs[name] = getDef(name, v.ID, "empty_in")
-
-			name = fmt.Sprintf("_addr_%s_empty_out", translateID(v.ID))
-			addrs[name] = getDef(name, v.ID, "empty_out")
-		}
+	switch operation {
+	case "get":
+		return "read"
+	case "set", "add", "sub", "mul", "div":
+		return "write"
 	}
 
-	if s.Actions.Contains(QueueFull) {
-		for _, v := range s.UsedVariables {
-			name := fmt.Sprintf("_addr_%s_full_in", translateID(v.ID))
-			addrs[name] = getDef(name, v.ID, "full_in")
-
-			name = fmt.Sprintf("_addr_%s_full_out", translateID(v.ID))
-			addrs[name] = getDef(name, v.ID, "full_out")
+	for _, name := range integerOperatorActions {
+		if operation == name {
+			return "write"
 		}
 	}
-
-	if s.Actions == QueueGet {
-		name := fmt.Sprintf("_addr_%s_get_in", translateID(s.TargetVariable.ID))
-		addrs[name] = getDef(name, s.TargetVariable.ID, "get_in")
-
-		name = fmt.Sprintf("_addr_%s_get_out", translateID(s.TargetVariable.ID))
-		addrs[name] = getDef(name, s.TargetVariable.ID, "get_out")
-
-		name = fmt.Sprintf("_addr_%s_get_ok", translateID(s.TargetVariable.ID))
-		addrs[name] = getDef(name, s.TargetVariable.ID, "get_ok")
-	}
+	return "atomic"
 }
 
 func setFunctionDefinitions(funcs map[string]string, s *Statement) {
@@ -568,48 +725,71 @@ func setFunctionDefinitions(funcs map[string]string, s *Statement) {
 	}
 
 	if s.Actions.Contains(VariableDefinition) || s.Actions.Contains(VariableAssignment) {
-		name, def = getFuncDefinition(s.TargetVariable.ID, s.TargetVariable.Type, "set")
+		if s.TargetVariable.Type == Dict || s.TargetVariable.Type == List {
+			name, def = getActionFuncDefinition(s.TargetVariable, "set")
+		} else if s.TargetVariable.Type == AcquireFunc {
+			name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "acquire")
+		} else if s.TargetVariable.Type == ReleaseFunc {
+			name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "release")
+		} else if s.TargetVariable.Type == Barrier {
+			name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "barrier")
+		} else if s.TargetVariable.Type == Batch {
+			name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "batch")
+		} else if s.TargetVariable.Type == Transaction {
+			name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "transaction")
+		} else {
+			name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "set")
+		}
+	}
+
+	for _, a := range s.GetRegisterActions() {
+		n, d := getActionFuncDefinition(a.Variable, a.Action)
+		funcs[n] = d
 	}
 
 	if s.Actions.Contains(QueuePut) {
-		name, def = getFuncDefinition(s.TargetVariable.ID, s.TargetVariable.SubType, fmt.Sprintf("%s_queue_set", s.TargetVariable.SubType))
+		name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.SubType, fmt.Sprintf("%s_queue_set", s.TargetVariable.SubType))
 	}
 
 	if s.Actions.Contains(MutexLock) || s.Actions.Contains(MutexUnlock) {
-		name, def = getFuncDefinition(s.TargetVariable.ID, s.TargetVariable.Type, "mutex")
+		name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "mutex")
 	}
 
 	if s.Actions.Contains(Wait) {
-		name, def = getFuncDefinition(s.TargetVariable.ID, s.TargetVariable.Type, "sync")
+		name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "sync")
 	}
 
 	if s.Actions.Contains(VariableUsage) {
 		for _, v := range s.UsedVariables {
-			name, def = getFuncDefinition(v.ID, v.Type, "get")
+			if !v.IsScalar() {
+				continue
+			}
+
+			name, def = getFuncDefinition(v, v.Type, "get")
 			funcs[name] = def
 		}
 	}
 
 	if s.Actions.Contains(VariableAdd) {
-		name, def = getFuncDefinition(s.TargetVariable.ID, s.TargetVariable.Type, "add")
+		name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "add")
 	}
 
 	if s.Actions.Contains(VariableSubtract) {
-		name, def = getFuncDefinition(s.TargetVariable.ID, s.TargetVariable.Type, "sub")
+		name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "sub")
 	}
 
 	if s.Actions.Contains(VariableMultiply) {
-		name, def = getFuncDefinition(s.TargetVariable.ID, s.TargetVariable.Type, "mul")
+		name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "mul")
 	}
 
 	if s.Actions.Contains(VariableDivide) {
-		name, def = getFuncDefinition(s.TargetVariable.ID, s.TargetVariable.Type, "div")
+		name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.Type, "div")
 	}
 
 	if s.Actions.Contains(QueueSize) {
 		for _, v := range s.UsedVariables {
-			if v.Type == Queue || v.Type == LifoQueue {
-				name, def = getFuncDefinition(v.ID, v.SubType, "queue_size")
+			if v.IsQueue() {
+				name, def = getFuncDefinition(v, v.SubType, "queue_size")
 				funcs[name] = def
 			}
 		}
@@ -617,8 +797,8 @@ func setFunctionDefinitions(funcs map[string]string, s *Statement) {
 
 	if s.Actions.Contains(QueueEmpty) {
 		for _, v := range s.UsedVariables {
-			if v.Type == Queue || v.Type == LifoQueue {
-				name, def = getFuncDefinition(v.ID, v.SubType, "queue_empty")
+			if v.IsQueue() {
+				name, def = getFuncDefinition(v, v.SubType, "queue_empty")
 				funcs[name] = def
 			}
 		}
@@ -626,137 +806,23 @@ func setFunctionDefinitions(funcs map[string]string, s *Statement) {
 
 	if s.Actions.Contains(QueueFull) {
 		for _, v := range s.UsedVariables {
-			if v.Type == Queue || v.Type == LifoQueue {
-				name, def = getFuncDefinition(v.ID, v.SubType, "queue_full")
+			if v.IsQueue() {
+				name, def = getFuncDefinition(v, v.SubType, "queue_full")
 				funcs[name] = def
 			}
 		}
 	}
 
 	if s.Actions == QueueGet {
-		name, def = getFuncDefinition(s.TargetVariable.ID, s.TargetVariable.SubType, fmt.Sprintf("%s_queue_get", s.TargetVariable.SubType))
+		name, def = getFuncDefinition(s.TargetVariable, s.TargetVariable.SubType, fmt.Sprintf("%s_queue_get", s.TargetVariable.SubType))
 	}
 
 	funcs[name] = def
 }
 
-func setSocketInit(init map[string]string, s *Statement) {
-	var name, code string
-
-	if s.ShouldSkip {
-		return
-	}
-
-	if s.Actions.Contains(VariableDefinition) || s.Actions.Contains(VariableAssignment) || s.Actions.Contains(QueuePut) {
-		if s.TargetVariable.Type == LockFunc || s.TargetVariable.Type == UnlockFunc {
-			name, code = getSocketInit(s.TargetVariable.ID, "mutex")
-		} else if s.TargetVariable.Type == WaitGroup {
-			name, code = getSocketInit(s.TargetVariable.ID, "sync")
-		} else {
-			name, code = getSocketInit(s.TargetVariable.ID, "set")
-		}
-	}
-
-	if s.Actions.Contains(VariableUsage) {
-		for _, v := range s.UsedVariables {
-			name, code = getSocketInit(v.ID, "get")
-			init[name] = code
-		}
-	}
-
-	if s.Actions == QueueGet {
-		name, code = getSocketInit(s.TargetVariable.ID, "get")
-		init[name] = code
-
-		name, code = getSocketInit(s.TargetVariable.ID, "queue_get")
-		init[name] = code
-	}
-
-	if s.Actions.Contains(VariableAdd) {
-		name, code = getSocketInit(s.TargetVariable.ID, "add")
-	}
-
-	if s.Actions.Contains(VariableSubtract) {
-		name, code = getSocketInit(s.TargetVariable.ID, "sub")
-	}
-
-	if s.Actions.Contains(VariableMultiply) {
-		name, code = getSocketInit(s.TargetVariable.ID, "mul")
-	}
-
-	if s.Actions.Contains(VariableDivide) {
-		name, code = getSocketInit(s.TargetVariable.ID, "div")
-	}
-
-	if s.Actions.Contains(QueueSize) {
-		for _, v := range s.UsedVariables {
-			if v.Type == Queue || v.Type == LifoQueue {
-				name, code = getSocketInit(v.ID, "size")
-				if name != "" && code != "" {
-					init[name] = code
-				}
-			}
-		}
-	}
-
-	if s.Actions.Contains(QueueEmpty) {
-		for _, v := range s.UsedVariables {
-			if v.Type == Queue || v.Type == LifoQueue {
-				name, code = getSocketInit(v.ID, "empty")
-				if name != "" && code != "" {
-					init[name] = code
-				}
-			}
-		}
-	}
-
-	if s.Actions.Contains(QueueFull) {
-		for _, v := range s.UsedVariables {
-			if v.Type == Queue || v.Type == LifoQueue {
-				name, code = getSocketInit(v.ID, "full")
-				if name != "" && code != "" {
-					init[name] = code
-				}
-			}
-		}
-	}
-
-	if name != "" && code != "" {
-		init[name] = code
-	}
-}
-
-func writeSocketDefinitions(socks map[string]string, sb *strings.Builder) {
-	for _, s := range socks {
-		sb.WriteString(s + "\n")
-	}
-	sb.WriteString("\n")
-}
-
-func writeAddressDefinitions(addrs map[string]string, sb *strings.Builder) {
-	for _, a := range addrs {
-		sb.WriteString(a + "\n")
-	}
-	sb.WriteString("\n")
-}
-
 func writeFunctionDefinitions(funcs map[string]string, sb *strings.Builder) {
 	for _, f := range funcs {
 		sb.WriteString(f + "\n\n")
 	}
 	sb.WriteString("\n")
 }
-
-func writeSocketInit(init map[string]string, sb *strings.Builder) {
-	if len(init) == 0 {
-		return
-	}
-
-	sb.WriteString("try:")
-
-	for _, i := range init {
-		sb.WriteString(i)
-	}
-
-	sb.WriteString("\nexcept socket.error as msg:\n    print(msg, file=sys.stderr)\n    sys.exit(1)\n")
-}
@@ -1,5 +1,7 @@
 package code
 
+import "sort"
+
 type VariableType byte
 
 const (
@@ -7,11 +9,28 @@ const (
 	Int
 	Float
 	Str
+	Bytes
+	Object
 	LockFunc
 	UnlockFunc
+	RLockFunc
+	RUnlockFunc
+	AcquireFunc
+	ReleaseFunc
 	WaitGroup
+	Barrier
 	Queue
 	LifoQueue
+	PriorityQueue
+	Topic
+	Chan
+	Dict
+	List
+	Set
+	Event
+	Condition
+	Batch
+	Transaction
 )
 
 func (v VariableType) String() string {
@@ -24,56 +43,206 @@ func (v VariableType) String() string {
 		return "float"
 	case Str:
 		return "str"
+	case Bytes:
+		return "bytes"
+	case Object:
+		return "object"
 	case LockFunc:
 		return "lock_func"
 	case UnlockFunc:
 		return "unlock_func"
+	case RLockFunc:
+		return "rlock_func"
+	case RUnlockFunc:
+		return "runlock_func"
+	case AcquireFunc:
+		return "acquire_func"
+	case ReleaseFunc:
+		return "release_func"
 	case WaitGroup:
 		return "wait_group"
+	case Barrier:
+		return "barrier"
 	case Queue:
 		return "fifo_queue"
 	case LifoQueue:
 		return "lifo_queue"
+	case PriorityQueue:
+		return "priority_queue"
+	case Topic:
+		return "topic"
+	case Chan:
+		return "chan"
+	case Dict:
+		return "dict"
+	case List:
+		return "list"
+	case Set:
+		return "set"
+	case Event:
+		return "event"
+	case Condition:
+		return "condition"
+	case Batch:
+		return "batch"
+	case Transaction:
+		return "transaction"
 	default:
 		return ""
 	}
 }
 
-type ActionFlag int
+type ActionFlag uint64
 
 const (
-	VariableDefinition ActionFlag = 0x1
-	VariableAssignment ActionFlag = 0x2
-	VariableAdd        ActionFlag = 0x4
-	VariableSubtract   ActionFlag = 0x8
-	VariableMultiply   ActionFlag = 0x10
-	VariableDivide     ActionFlag = 0x20
-	VariableUsage      ActionFlag = 0x40
-	MutexLock          ActionFlag = 0x80
-	MutexUnlock        ActionFlag = 0x100
-	Wait               ActionFlag = 0x200
-	QueueFull          ActionFlag = 0x400
-	QueueEmpty         ActionFlag = 0x800
-	QueueSize          ActionFlag = 0x1000
-	QueuePut           ActionFlag = 0x2000
-	QueueGet           ActionFlag = 0x4000
+	VariableDefinition   ActionFlag = 0x1
+	VariableAssignment   ActionFlag = 0x2
+	VariableAdd          ActionFlag = 0x4
+	VariableSubtract     ActionFlag = 0x8
+	VariableMultiply     ActionFlag = 0x10
+	VariableDivide       ActionFlag = 0x20
+	VariableUsage        ActionFlag = 0x40
+	MutexLock            ActionFlag = 0x80
+	MutexUnlock          ActionFlag = 0x100
+	Wait                 ActionFlag = 0x200
+	QueueFull            ActionFlag = 0x400
+	QueueEmpty           ActionFlag = 0x800
+	QueueSize            ActionFlag = 0x1000
+	QueuePut             ActionFlag = 0x2000
+	QueueGet             ActionFlag = 0x4000
+	DictGet              ActionFlag = 0x8000
+	DictSet              ActionFlag = 0x10000
+	DictAdd              ActionFlag = 0x20000
+	DictDelete           ActionFlag = 0x40000
+	DictContains         ActionFlag = 0x80000
+	DictLength           ActionFlag = 0x100000
+	DictKeys             ActionFlag = 0x200000
+	ListGet              ActionFlag = 0x400000
+	ListSet              ActionFlag = 0x800000
+	ListAppend           ActionFlag = 0x1000000
+	ListSlice            ActionFlag = 0x2000000
+	ListLength           ActionFlag = 0x4000000
+	SetAdd               ActionFlag = 0x8000000
+	SetDiscard           ActionFlag = 0x10000000
+	SetContains          ActionFlag = 0x20000000
+	SetLength            ActionFlag = 0x40000000
+	SetItems             ActionFlag = 0x80000000
+	AtomicFetchAdd       ActionFlag = 0x100000000
+	AtomicSwap           ActionFlag = 0x200000000
+	AtomicCompareAndSwap ActionFlag = 0x400000000
+	VariableFloorDivide  ActionFlag = 0x800000000
+	VariableModulo       ActionFlag = 0x1000000000
+	VariablePower        ActionFlag = 0x2000000000
+	VariableAnd          ActionFlag = 0x4000000000
+	VariableOr           ActionFlag = 0x8000000000
+	VariableXor          ActionFlag = 0x10000000000
+	VariableLeftShift    ActionFlag = 0x20000000000
+	VariableRightShift   ActionFlag = 0x40000000000
+	VariableMax          ActionFlag = 0x80000000000
+	VariableMin          ActionFlag = 0x100000000000
+	SemaphoreAcquire     ActionFlag = 0x200000000000
+	SemaphoreRelease     ActionFlag = 0x400000000000
+	BarrierWait          ActionFlag = 0x800000000000
+	EventSet             ActionFlag = 0x1000000000000
+	EventClear           ActionFlag = 0x2000000000000
+	EventIsSet           ActionFlag = 0x4000000000000
+	EventWait            ActionFlag = 0x8000000000000
+	ConditionWait        ActionFlag = 0x10000000000000
+	ConditionNotify      ActionFlag = 0x20000000000000
+	ConditionNotifyAll   ActionFlag = 0x40000000000000
+	QueueTaskDone        ActionFlag = 0x80000000000000
+	QueueJoin            ActionFlag = 0x100000000000000
+	ChanSend             ActionFlag = 0x200000000000000
+	ChanReceive          ActionFlag = 0x400000000000000
+	ChanClose            ActionFlag = 0x800000000000000
+	ChanIterate          ActionFlag = 0x1000000000000000
+	BatchStart           ActionFlag = 0x2000000000000000
+	TransactionStart     ActionFlag = 0x4000000000000000
 )
 
+// integerOperatorActions names the actions for the augmented assignments that
+// are only supported by int variables.
+var integerOperatorActions = map[ActionFlag]string{
+	VariableFloorDivide: "floordiv",
+	VariableModulo:      "mod",
+	VariablePower:       "pow",
+	VariableAnd:         "and",
+	VariableOr:          "or",
+	VariableXor:         "xor",
+	VariableLeftShift:   "lshift",
+	VariableRightShift:  "rshift",
+}
+
 type Variable struct {
 	ID           string
 	Type         VariableType
 	SubType      VariableType
+	KeyType      VariableType
 	Name         string
 	Tag          string
 	DefaultValue any
+	Timeout      float64
+
+	// SharedMemory is set for the variables whose values nodes access directly
+	// in the shared memory file, at SharedMemorySlot, rather than over their
+	// connection to Gothon.
+	SharedMemory     bool
+	SharedMemorySlot int
 }
 
 func (v *Variable) String() string {
 	return v.Name
 }
 
+func (v *Variable) IsScalar() bool {
+	switch v.Type {
+	case Bool, Int, Float, Str, Bytes, Object:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsSharedMemory returns whether the variable's value fits in a slot of
+// the shared memory file.
+func (v *Variable) SupportsSharedMemory() bool {
+	switch v.Type {
+	case Bool, Int, Float:
+		return true
+	default:
+		return false
+	}
+}
+
+func (v *Variable) IsLockFunc() bool {
+	switch v.Type {
+	case LockFunc, UnlockFunc, RLockFunc, RUnlockFunc:
+		return true
+	default:
+		return false
+	}
+}
+
+func (v *Variable) IsSemaphoreFunc() bool {
+	return v.Type == AcquireFunc || v.Type == ReleaseFunc
+}
+
+// IsQueue reports whether the variable is one of the queue types or a topic,
+// the latter being used (and translated) like a queue.
+func (v *Variable) IsQueue() bool {
+	switch v.Type {
+	case Queue, LifoQueue, PriorityQueue, Topic:
+		return true
+	default:
+		return false
+	}
+}
+
 type Statement struct {
 	Line           int
+	Column         int
+	EndLine        int
+	EndColumn      int
 	Indentation    string
 	Actions        ActionFlag
 	TargetVariable *Variable
@@ -86,15 +255,103 @@ type Statement struct {
 	ShouldSkip     bool
 }
 
+// VariableAction pairs a variable with the name of an action performed on it,
+// as used in the names of its sockets and generated functions.
+type VariableAction struct {
+	Variable *Variable
+	Action   string
+}
+
+// GetRegisterActions returns the actions performed on variables by way of
+// their methods (or len(), in, etc), each of which has its own sockets and
+// generated function.
+func (s *Statement) GetRegisterActions() []VariableAction {
+	actions := make([]VariableAction, 0)
+	add := func(v *Variable, action ActionFlag, name string) {
+		if s.Actions.Contains(action) {
+			actions = append(actions, VariableAction{v, name})
+		}
+	}
+
+	for _, v := range s.UsedVariables {
+		switch v.Type {
+		case Bool, Int, Float, Str:
+			add(v, AtomicFetchAdd, "fetch_add")
+			add(v, AtomicSwap, "swap")
+			add(v, AtomicCompareAndSwap, "cas")
+		case Dict:
+			add(v, DictGet, "get")
+			add(v, DictContains, "contains")
+			add(v, DictLength, "size")
+			add(v, DictKeys, "keys")
+		case List:
+			add(v, ListGet, "get")
+			add(v, ListAppend, "append")
+			add(v, ListSlice, "slice")
+			add(v, ListLength, "size")
+		case Set:
+			add(v, SetAdd, "add")
+			add(v, SetDiscard, "del")
+			add(v, SetContains, "contains")
+			add(v, SetLength, "size")
+			add(v, SetItems, "keys")
+		case Event:
+			// set() uses the setter created for the declaration
+			add(v, EventClear, "clear")
+			add(v, EventIsSet, "isset")
+			add(v, EventWait, "wait")
+		case Condition:
+			add(v, ConditionWait, "wait")
+			add(v, ConditionNotify, "notify")
+			add(v, ConditionNotifyAll, "notifyall")
+		case Queue, LifoQueue, PriorityQueue:
+			add(v, QueueTaskDone, "taskdone")
+			add(v, QueueJoin, "join")
+		case Chan:
+			add(v, ChanSend, "send")
+			add(v, ChanReceive, "recv")
+			add(v, ChanClose, "close")
+			add(v, ChanIterate, "iter")
+		}
+	}
+
+	if s.TargetVariable != nil {
+		switch s.TargetVariable.Type {
+		case Dict:
+			add(s.TargetVariable, DictSet, "set")
+			add(s.TargetVariable, DictAdd, "add")
+			add(s.TargetVariable, DictDelete, "del")
+		case List:
+			add(s.TargetVariable, ListSet, "set")
+		case Int:
+			for action, name := range integerOperatorActions {
+				add(s.TargetVariable, action, name)
+			}
+			add(s.TargetVariable, VariableMax, "max")
+			add(s.TargetVariable, VariableMin, "min")
+		case Float:
+			add(s.TargetVariable, VariableMax, "max")
+			add(s.TargetVariable, VariableMin, "min")
+		}
+	}
+
+	return actions
+}
+
 type Module struct {
-	Name             string
-	AbsolutePath     string
-	RelativePath     string
-	PackageDirectory string
-	RequireParens    bool
-	VariablePrefix   string
-	VariableSuffix   string
-	Statements       []*Statement
+	Name                 string
+	AbsolutePath         string
+	RelativePath         string
+	PackageDirectory     string
+	RequireParens        bool
+	VariablePrefix       string
+	VariableSuffix       string
+	Timeout              float64
+	VariableTimeouts     map[string]float64
+	SharedMemory         bool
+	VariableSharedMemory map[string]bool
+	Statements           []*Statement
+	syntax               *syntaxTree
 }
 
 func (m *Module) GetVariables() []*Variable {
@@ -131,9 +388,9 @@ func (m *Module) GetVariableByID(id string) *Variable {
 	return nil
 }
 
-func (m *Module) GetStatement(line int) *Statement {
+func (m *Module) GetStatement(line, column int) *Statement {
 	for _, statement := range m.Statements {
-		if statement.Line == line {
+		if statement.Line == line && statement.Column == column {
 			return statement
 		}
 	}
@@ -141,6 +398,32 @@ func (m *Module) GetStatement(line int) *Statement {
 	return nil
 }
 
+func (m *Module) GetStatementsOnLine(line int) []*Statement {
+	statements := make([]*Statement, 0)
+	for _, statement := range m.Statements {
+		if statement.Line != line {
+			continue
+		}
+
+		duplicate := false
+		for _, s := range statements {
+			if s.Column == statement.Column {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			statements = append(statements, statement)
+		}
+	}
+
+	sort.Slice(statements, func(i, j int) bool {
+		return statements[i].Column < statements[j].Column
+	})
+
+	return statements
+}
+
 type Package []*Module
 
 func (p Package) Directory() string {
@@ -159,6 +442,18 @@ func (p Package) GetVariables() []*Variable {
 	return vars
 }
 
+// GetSharedMemoryVariables returns the variables kept in the shared memory
+// file, in the order of their slots.
+func (p Package) GetSharedMemoryVariables() []*Variable {
+	vars := make([]*Variable, 0)
+	for _, v := range p.GetVariables() {
+		if v.SharedMemory {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
 func (p Package) GetVariableByID(id string) *Variable {
 	for _, mod := range p {
 		variable := mod.GetVariableByID(id)
@@ -1,7 +1,6 @@
 package code
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"os"
@@ -9,23 +8,50 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
 	variableUsageRequireParensKey = "gothon:var_usage:require_parens"
 	variableDefinitionPrefixKey   = "gothon:var_def:prefix"
 	variableDefinitionSuffixKey   = "gothon:var_def:suffix"
+	timeoutKey                    = "gothon:timeout"
+	sharedMemoryKey               = "gothon:shared_memory"
 )
 
 var (
-	ignoredLinePrefixes      = []string{"import ", "from ", "class ", "def ", "try:", "except ", "finally:", "else:", "\"\"\"", "#", "@"}
-	controlStructurePrefixes = []string{"if ", "if(", "elif ", "elif(", "while ", "while(", "for ", "for("}
-	supportedTypes           = []string{"bool", "int", "float", "str", "callable"}
-	supportedQueueTypes      = []string{"Queue[bool]", "Queue[int]", "Queue[float]", "Queue[str]"}
-	supportedLifoQueueTypes  = []string{"LifoQueue[bool]", "LifoQueue[int]", "LifoQueue[float]", "LifoQueue[str]"}
-	supportedTypesCombined   = append(append(supportedTypes, supportedQueueTypes...), supportedLifoQueueTypes...)
+	supportedTypes              = []string{"bool", "int", "float", "str", "bytes", "object", "Any", "callable", "Event", "Condition"}
+	supportedQueueTypes         = []string{"Queue[bool]", "Queue[int]", "Queue[float]", "Queue[str]", "Queue[bytes]", "Queue[object]", "Queue[Any]"}
+	supportedLifoQueueTypes     = []string{"LifoQueue[bool]", "LifoQueue[int]", "LifoQueue[float]", "LifoQueue[str]", "LifoQueue[bytes]", "LifoQueue[object]", "LifoQueue[Any]"}
+	supportedPriorityQueueTypes = []string{"PriorityQueue[int]", "PriorityQueue[float]", "PriorityQueue[str]"}
+	supportedTopicTypes         = []string{"Topic[bool]", "Topic[int]", "Topic[float]", "Topic[str]", "Topic[bytes]", "Topic[object]", "Topic[Any]"}
+	supportedChanTypes          = []string{"Chan[bool]", "Chan[int]", "Chan[float]", "Chan[str]", "Chan[bytes]", "Chan[object]", "Chan[Any]"}
+	supportedDictTypes          = []string{"dict[str,bool]", "dict[str,int]", "dict[str,float]", "dict[str,str]", "dict[int,bool]", "dict[int,int]", "dict[int,float]", "dict[int,str]"}
+	supportedListTypes          = []string{"list[bool]", "list[int]", "list[float]", "list[str]"}
+	supportedSetTypes           = []string{"set[int]", "set[str]"}
+	supportedTypesCombined      = combineTypes(supportedTypes, supportedQueueTypes, supportedLifoQueueTypes, supportedPriorityQueueTypes, supportedTopicTypes, supportedChanTypes, supportedDictTypes, supportedListTypes, supportedSetTypes)
+
+	integerOperators = map[string]ActionFlag{
+		"//=": VariableFloorDivide,
+		"%=":  VariableModulo,
+		"**=": VariablePower,
+		"&=":  VariableAnd,
+		"|=":  VariableOr,
+		"^=":  VariableXor,
+		"<<=": VariableLeftShift,
+		">>=": VariableRightShift,
+	}
 )
 
+func combineTypes(types ...[]string) []string {
+	combined := make([]string, 0)
+	for _, t := range types {
+		combined = append(combined, t...)
+	}
+	return combined
+}
+
 func Parse(projectDir string) (Package, error) {
 	projectDir, err := filepath.Abs(projectDir)
 	if err != nil {
@@ -57,6 +83,31 @@ func Parse(projectDir string) (Package, error) {
 		return nil, err
 	}
 
+	err = getDictOperations(modules)
+	if err != nil {
+		return nil, err
+	}
+
+	err = getListOperations(modules)
+	if err != nil {
+		return nil, err
+	}
+
+	err = getSetOperations(modules)
+	if err != nil {
+		return nil, err
+	}
+
+	err = getChanOperations(modules)
+	if err != nil {
+		return nil, err
+	}
+
+	err = getAtomicOperations(modules)
+	if err != nil {
+		return nil, err
+	}
+
 	err = getMutexLocksAndUnlocks(modules)
 	if err != nil {
 		return nil, err
@@ -67,6 +118,26 @@ func Parse(projectDir string) (Package, error) {
 		return nil, err
 	}
 
+	err = getSemaphoreAcquiresAndReleases(modules)
+	if err != nil {
+		return nil, err
+	}
+
+	err = getBarrierWaits(modules)
+	if err != nil {
+		return nil, err
+	}
+
+	err = getEventAndConditionOperations(modules)
+	if err != nil {
+		return nil, err
+	}
+
+	err = getBatchesAndTransactions(modules)
+	if err != nil {
+		return nil, err
+	}
+
 	return modules, nil
 }
 
@@ -86,12 +157,23 @@ func getModules(packageDir string) ([]*Module, error) {
 		moduleName = strings.ReplaceAll(moduleName, "/", "_")
 		relativePath := strings.TrimPrefix(strings.TrimPrefix(path, packageDir), "/")
 
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		tree, err := parseSyntax(string(src))
+		if err != nil {
+			return fmt.Errorf("%s: %w", relativePath, err)
+		}
+
 		module := &Module{
 			Name:             moduleName,
 			AbsolutePath:     path,
 			RelativePath:     relativePath,
 			PackageDirectory: packageDir,
 			Statements:       make([]*Statement, 0),
+			syntax:           tree,
 		}
 
 		err = processDirectives(module)
@@ -107,20 +189,9 @@ func getModules(packageDir string) ([]*Module, error) {
 }
 
 func processDirectives(module *Module) error {
-	moduleFile, err := os.Open(module.AbsolutePath)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = moduleFile.Close()
-	}()
-
-	scanner := bufio.NewScanner(moduleFile)
-	scanner.Split(bufio.ScanLines)
-
-	for scanner.Scan() {
-		text := scanner.Text()
-		if !strings.HasPrefix(text, "# gothon:") {
+	for _, comment := range module.syntax.comments {
+		text := strings.TrimSpace(comment.Value)
+		if comment.Column != 0 || !strings.HasPrefix(text, "# gothon:") {
 			continue
 		}
 
@@ -148,6 +219,49 @@ func processDirectives(module *Module) error {
 			module.VariableSuffix = val
 			continue
 		}
+
+		if strings.HasPrefix(text, "# "+timeoutKey) {
+			kv := strings.Split(text, "=")
+			val := strings.TrimSpace(kv[1])
+			valFloat, e := strconv.ParseFloat(val, 64)
+			if e != nil {
+				return e
+			}
+			if valFloat <= 0 {
+				return fmt.Errorf("invalid timeout %s (must be greater than zero)", val)
+			}
+
+			key := strings.TrimSpace(strings.TrimPrefix(kv[0], "# "+timeoutKey))
+			if strings.HasPrefix(key, ":") {
+				if module.VariableTimeouts == nil {
+					module.VariableTimeouts = make(map[string]float64)
+				}
+				module.VariableTimeouts[strings.TrimPrefix(key, ":")] = valFloat
+			} else {
+				module.Timeout = valFloat
+			}
+			continue
+		}
+
+		if strings.HasPrefix(text, "# "+sharedMemoryKey) {
+			kv := strings.Split(text, "=")
+			val := strings.TrimSpace(kv[1])
+			valBool, e := strconv.ParseBool(val)
+			if e != nil {
+				return e
+			}
+
+			key := strings.TrimSpace(strings.TrimPrefix(kv[0], "# "+sharedMemoryKey))
+			if strings.HasPrefix(key, ":") {
+				if module.VariableSharedMemory == nil {
+					module.VariableSharedMemory = make(map[string]bool)
+				}
+				module.VariableSharedMemory[strings.TrimPrefix(key, ":")] = valBool
+			} else {
+				module.SharedMemory = valBool
+			}
+			continue
+		}
 	}
 
 	if module.VariablePrefix == "" {
@@ -166,34 +280,15 @@ func processDirectives(module *Module) error {
 }
 
 func getVariableDefinitions(modules []*Module) error {
-	for _, module := range modules {
-		f, err := os.Open(module.AbsolutePath)
-		if err != nil {
-			return err
-		}
-
-		scanner := bufio.NewScanner(f)
-		scanner.Split(bufio.ScanLines)
-		line := 0
-		for scanner.Scan() {
-			line++
-			text := strings.TrimSpace(scanner.Text())
-
-			if shouldSkipLine(text) {
-				continue
-			}
-
-			textPartsEq := strings.Split(text, "=")
-			if len(textPartsEq) < 2 {
-				continue
-			}
+	sharedMemorySlot := 0
 
-			textPartsCol := strings.Split(textPartsEq[0], ":")
-			if len(textPartsCol) != 2 {
+	for _, module := range modules {
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) || n.kind != annotatedAssignmentNode || n.operator != "=" {
 				continue
 			}
 
-			varType := strings.TrimSpace(textPartsCol[1])
+			varType := n.annotation
 			varTypeSupported := false
 			for _, dt := range supportedTypesCombined {
 				if varType == dt {
@@ -205,7 +300,7 @@ func getVariableDefinitions(modules []*Module) error {
 				continue
 			}
 
-			varname := strings.Replace(strings.TrimSpace(textPartsCol[0]), "self.", "", 1)
+			varname := strings.Replace(strings.TrimSpace(n.target), "self.", "", 1)
 			if !strings.HasPrefix(varname, module.VariablePrefix) || !strings.HasSuffix(varname, module.VariableSuffix) {
 				continue
 			}
@@ -225,12 +320,9 @@ func getVariableDefinitions(modules []*Module) error {
 				shouldSkip = true
 			}
 			if shouldSkip {
-				statement := &Statement{
-					Line:         line,
-					Actions:      VariableDefinition,
-					OriginalCode: scanner.Text(),
-					ShouldSkip:   true,
-				}
+				statement := newStatement(n)
+				statement.Actions = VariableDefinition
+				statement.ShouldSkip = true
 
 				module.Statements = append(module.Statements, statement)
 				continue
@@ -238,51 +330,87 @@ func getVariableDefinitions(modules []*Module) error {
 
 			if varType == "callable" &&
 				(!strings.HasPrefix(varnameTrimmed, "lock_") && !strings.HasPrefix(varnameTrimmed, "unlock_") &&
-					!strings.HasPrefix(varnameTrimmed, "sync_")) {
+					!strings.HasPrefix(varnameTrimmed, "rlock_") && !strings.HasPrefix(varnameTrimmed, "runlock_") &&
+					!strings.HasPrefix(varnameTrimmed, "acquire_") && !strings.HasPrefix(varnameTrimmed, "release_") &&
+					!strings.HasPrefix(varnameTrimmed, "sync_") && !strings.HasPrefix(varnameTrimmed, "barrier_") &&
+					!strings.HasPrefix(varnameTrimmed, "batch_") && !strings.HasPrefix(varnameTrimmed, "transaction_")) {
 				continue
 			}
 
 			tag := ""
 			if varType == "callable" {
-				t := strings.TrimPrefix(varnameTrimmed, "unlock_")
-				t = strings.TrimPrefix(t, "lock_")
+				t := varnameTrimmed
+				for _, prefix := range []string{"unlock_", "lock_", "runlock_", "rlock_"} {
+					if strings.HasPrefix(t, prefix) {
+						t = strings.TrimPrefix(t, prefix)
+						break
+					}
+				}
 				t = fmt.Sprintf("%smutex_%s%s", module.VariablePrefix, t, module.VariableSuffix)
 				tag = t
+
+				if strings.HasPrefix(varnameTrimmed, "acquire_") || strings.HasPrefix(varnameTrimmed, "release_") {
+					t = strings.TrimPrefix(varnameTrimmed, "acquire_")
+					t = strings.TrimPrefix(t, "release_")
+					tag = fmt.Sprintf("%ssemaphore_%s%s", module.VariablePrefix, t, module.VariableSuffix)
+				}
 			}
 
-			lValue, rValue := getLRValues(scanner.Text())
-			defaultValue, e := getDefaultValue(varType, rValue)
+			defaultValue, e := getDefaultValue(varType, n.rValue, n.valueTokens)
 			if e != nil {
 				return e
 			}
 
+			timeout := module.Timeout
+			if t, ok := module.VariableTimeouts[varname]; ok {
+				timeout = t
+			}
+
 			variable := &Variable{
 				ID:           filepath.Join(module.Name, varname),
 				Type:         getVariableType(varType, varnameTrimmed),
 				SubType:      getVariableSubType(varType),
+				KeyType:      getVariableKeyType(varType),
 				Name:         varname,
 				Tag:          tag,
 				DefaultValue: defaultValue,
+				Timeout:      timeout,
 			}
 
-			statement := &Statement{
-				Line:           line,
-				Indentation:    getIndentation(scanner.Text()),
-				Actions:        VariableDefinition,
-				TargetVariable: variable,
-				OriginalCode:   scanner.Text(),
-				ModifiedCode:   scanner.Text(),
-				OriginalLValue: lValue,
-				OriginalRValue: rValue,
-				ModifiedRValue: rValue,
+			sharedMemory, explicit := module.VariableSharedMemory[varname]
+			if !explicit {
+				sharedMemory = module.SharedMemory
+			}
+			if sharedMemory && !variable.SupportsSharedMemory() {
+				if explicit {
+					return fmt.Errorf("%s: shared memory not supported for variable %s of type %s",
+						module.RelativePath, varname, varType)
+				}
+				sharedMemory = false
 			}
+			if sharedMemory {
+				variable.SharedMemory = true
+				variable.SharedMemorySlot = sharedMemorySlot
+				sharedMemorySlot++
+			}
+
+			statement := newStatement(n)
+			statement.Actions = VariableDefinition
+			statement.TargetVariable = variable
 
 			module.Statements = append(module.Statements, statement)
 		}
 
-		err = f.Close()
-		if err != nil {
-			return err
+		for name := range module.VariableTimeouts {
+			if module.GetVariableByName(name) == nil {
+				return fmt.Errorf("%s: timeout set for unknown variable %s", module.RelativePath, name)
+			}
+		}
+
+		for name := range module.VariableSharedMemory {
+			if module.GetVariableByName(name) == nil {
+				return fmt.Errorf("%s: shared memory set for unknown variable %s", module.RelativePath, name)
+			}
 		}
 	}
 
@@ -291,69 +419,44 @@ func getVariableDefinitions(modules []*Module) error {
 
 func getVariableAssignments(modules []*Module) error {
 	for _, module := range modules {
-		f, err := os.Open(module.AbsolutePath)
-		if err != nil {
-			return err
-		}
-
-		scanner := bufio.NewScanner(f)
-		scanner.Split(bufio.ScanLines)
-		line := 0
-		for scanner.Scan() {
-			line++
-			text := strings.TrimSpace(scanner.Text())
-
-			shouldContinue := false
-			for _, prefix := range ignoredLinePrefixes {
-				if strings.HasPrefix(text, prefix) {
-					shouldContinue = true
-					break
-				}
-			}
-			if shouldContinue {
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) {
 				continue
 			}
 
-			lValue, rValue := getLRValues(scanner.Text())
+			statement := newStatement(n)
 
-			statement := &Statement{
-				Line:           line,
-				Indentation:    getIndentation(scanner.Text()),
-				UsedVariables:  make([]*Variable, 0),
-				OriginalCode:   scanner.Text(),
-				ModifiedCode:   scanner.Text(),
-				OriginalLValue: lValue,
-				OriginalRValue: rValue,
-				ModifiedRValue: rValue,
-			}
-
-			textParts := strings.Split(text, "=")
-			if len(textParts) == 2 {
-				varname := getVariableName(textParts[0])
+			switch n.kind {
+			case assignmentNode, annotatedAssignmentNode:
+				if n.kind == assignmentNode {
+					varname := getVariableName(n.target)
 
-				targetVariable := module.GetVariableByName(varname)
-				if targetVariable != nil {
-					statement.Actions = VariableAssignment
-					statement.TargetVariable = targetVariable
+					targetVariable := module.GetVariableByName(varname)
+					if targetVariable != nil {
+						statement.Actions = VariableAssignment
+						statement.TargetVariable = targetVariable
+					}
 
+					if action, operand := getExtremumUpdate(n.rValue, targetVariable); action != 0 {
+						statement.Actions = action
+						checkForVariableUsage(statement, operand, module.GetVariables(), module.RequireParens)
+						break
+					}
 				}
 
-				if module.GetStatement(line) == nil {
-					checkForVariableUsage(statement, textParts[1], module.GetVariables(), module.RequireParens)
+				if module.GetStatement(n.line, n.column) == nil {
+					checkForVariableUsage(statement, n.rValue, module.GetVariables(), module.RequireParens)
 				}
-			} else {
-				checkForVariableUsage(statement, text, module.GetVariables(), module.RequireParens)
+			case augmentedAssignmentNode:
+				continue
+			default:
+				checkForVariableUsage(statement, n.code, module.GetVariables(), module.RequireParens)
 			}
 
 			if statement.Actions != 0 {
 				module.Statements = append(module.Statements, statement)
 			}
 		}
-
-		err = f.Close()
-		if err != nil {
-			return err
-		}
 	}
 
 	return nil
@@ -361,103 +464,45 @@ func getVariableAssignments(modules []*Module) error {
 
 func getVariableNumericalOperations(modules []*Module) error {
 	for _, module := range modules {
-		f, err := os.Open(module.AbsolutePath)
-		if err != nil {
-			return err
-		}
-
-		scanner := bufio.NewScanner(f)
-		scanner.Split(bufio.ScanLines)
-		line := 0
-		for scanner.Scan() {
-			line++
-			text := strings.TrimSpace(scanner.Text())
-
-			shouldContinue := false
-			for _, prefix := range ignoredLinePrefixes {
-				if strings.HasPrefix(text, prefix) {
-					shouldContinue = true
-					break
-				}
-			}
-			if shouldContinue {
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) || n.kind != augmentedAssignmentNode {
 				continue
 			}
 
-			lValue, rValue := getLRValues(scanner.Text())
-
-			statement := &Statement{
-				Line:           line,
-				Indentation:    getIndentation(scanner.Text()),
-				UsedVariables:  make([]*Variable, 0),
-				OriginalCode:   scanner.Text(),
-				ModifiedCode:   scanner.Text(),
-				OriginalLValue: lValue,
-				OriginalRValue: rValue,
-				ModifiedRValue: rValue,
-			}
-
-			textParts := strings.Split(text, "+=")
-			if len(textParts) == 2 {
-				varname := getVariableName(textParts[0])
-
-				targetVariable := module.GetVariableByName(varname)
-				if targetVariable != nil {
-					statement.Actions = VariableAdd
-					statement.TargetVariable = targetVariable
-				}
-
-				checkForVariableUsage(statement, textParts[1], module.GetVariables(), module.RequireParens)
+			statement := newStatement(n)
+
+			var action ActionFlag
+			switch n.operator {
+			case "+=":
+				action = VariableAdd
+			case "-=":
+				action = VariableSubtract
+			case "*=":
+				action = VariableMultiply
+			case "/=":
+				action = VariableDivide
+			default:
+				action = integerOperators[n.operator]
 			}
 
-			textParts = strings.Split(text, "-=")
-			if len(textParts) == 2 {
-				varname := getVariableName(textParts[0])
-
-				targetVariable := module.GetVariableByName(varname)
-				if targetVariable != nil {
-					statement.Actions = VariableSubtract
-					statement.TargetVariable = targetVariable
-				}
+			varname := getVariableName(n.target)
 
-				checkForVariableUsage(statement, textParts[1], module.GetVariables(), module.RequireParens)
+			targetVariable := module.GetVariableByName(varname)
+			if targetVariable != nil && integerOperators[n.operator] != 0 && targetVariable.Type != Int {
+				return fmt.Errorf("unsupported operator %s for variable %s of type %s", n.operator, targetVariable.Name, targetVariable.Type)
 			}
 
-			textParts = strings.Split(text, "*=")
-			if len(textParts) == 2 {
-				varname := getVariableName(textParts[0])
-
-				targetVariable := module.GetVariableByName(varname)
-				if targetVariable != nil {
-					statement.Actions = VariableMultiply
-					statement.TargetVariable = targetVariable
-				}
-
-				checkForVariableUsage(statement, textParts[1], module.GetVariables(), module.RequireParens)
+			if targetVariable != nil && action != 0 {
+				statement.Actions = action
+				statement.TargetVariable = targetVariable
 			}
 
-			textParts = strings.Split(text, "/=")
-			if len(textParts) == 2 {
-				varname := getVariableName(textParts[0])
-
-				targetVariable := module.GetVariableByName(varname)
-				if targetVariable != nil {
-					statement.Actions = VariableDivide
-					statement.TargetVariable = targetVariable
-				}
-
-				checkForVariableUsage(statement, textParts[1], module.GetVariables(), module.RequireParens)
-			}
+			checkForVariableUsage(statement, n.rValue, module.GetVariables(), module.RequireParens)
 
 			if statement.Actions != 0 {
 				module.Statements = append(module.Statements, statement)
 			}
 		}
-
-		err = f.Close()
-		if err != nil {
-			return err
-		}
 	}
 
 	return nil
@@ -465,44 +510,16 @@ func getVariableNumericalOperations(modules []*Module) error {
 
 func getQueueOperations(modules []*Module) error {
 	for _, module := range modules {
-		f, err := os.Open(module.AbsolutePath)
-		if err != nil {
-			return err
-		}
-
-		scanner := bufio.NewScanner(f)
-		scanner.Split(bufio.ScanLines)
-		line := 0
-		for scanner.Scan() {
-			line++
-			text := strings.TrimSpace(scanner.Text())
-
-			shouldContinue := false
-			for _, prefix := range ignoredLinePrefixes {
-				if strings.HasPrefix(text, prefix) {
-					shouldContinue = true
-					break
-				}
-			}
-			if shouldContinue {
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) {
 				continue
 			}
 
-			lValue, rValue := getLRValues(scanner.Text())
-
-			statement := &Statement{
-				Line:           line,
-				Indentation:    getIndentation(scanner.Text()),
-				UsedVariables:  make([]*Variable, 0),
-				OriginalCode:   scanner.Text(),
-				ModifiedCode:   scanner.Text(),
-				OriginalLValue: lValue,
-				OriginalRValue: rValue,
-				ModifiedRValue: rValue,
-			}
+			text := n.code
+			statement := newStatement(n)
 
 			for _, v := range module.GetVariables() {
-				if v.Type != Queue && v.Type != LifoQueue {
+				if !v.IsQueue() {
 					continue
 				}
 
@@ -553,6 +570,28 @@ func getQueueOperations(modules []*Module) error {
 					statement.TargetVariable = v
 				}
 
+				for _, ref := range findReferences(text, v.Name) {
+					if ref.kind == methodReference && (ref.method == "get" || ref.method == "put") {
+						if e = checkQueueArguments(v, ref.method, ref.args); e != nil {
+							return e
+						}
+					}
+				}
+
+				for _, ref := range findReferences(text, v.Name) {
+					if ref.kind != methodReference || v.Type == Topic {
+						continue
+					}
+					if ref.method == "task_done" {
+						statement.Actions |= QueueTaskDone
+						appendVar = true
+					}
+					if ref.method == "join" {
+						statement.Actions |= QueueJoin
+						appendVar = true
+					}
+				}
+
 				if appendVar {
 					statement.UsedVariables = append(statement.UsedVariables, v)
 				}
@@ -562,276 +601,845 @@ func getQueueOperations(modules []*Module) error {
 				module.Statements = append(module.Statements, statement)
 			}
 		}
-
-		err = f.Close()
-		if err != nil {
-			return err
-		}
 	}
 
 	return nil
 }
 
 func checkForVariableUsage(statement *Statement, expression string, variables []*Variable, requireParens bool) {
-	expression = strings.TrimSpace(expression)
 	for _, variable := range variables {
-		if variable.Type == LockFunc || variable.Type == UnlockFunc ||
-			variable.Type == WaitGroup ||
-			variable.Type == Queue || variable.Type == LifoQueue {
+		if !variable.IsScalar() {
 			continue
 		}
 
-		if requireParens {
-			if strings.Contains(expression, fmt.Sprintf("(%s)", variable.Name)) {
-				statement.Actions |= VariableUsage
-				statement.UsedVariables = append(statement.UsedVariables, variable)
-			}
-		} else {
-			if containsVariable(expression, variable.Name) {
-				statement.Actions |= VariableUsage
-				statement.UsedVariables = append(statement.UsedVariables, variable)
-			}
+		expression := strings.TrimSpace(expression)
+		if supportsAtomicOperations(variable) {
+			// calls to the atomic methods are separate actions and not uses of
+			// the variable's value
+			expression = translateReferences(expression, variable.Name, func(ref reference) (string, bool) {
+				return "None", isAtomicReference(ref, variable)
+			})
+		}
+
+		if containsVariable(expression, variable.Name, requireParens) {
+			statement.Actions |= VariableUsage
+			statement.UsedVariables = append(statement.UsedVariables, variable)
 		}
 	}
 }
 
-func getMutexLocksAndUnlocks(modules []*Module) error {
+func getDictOperations(modules []*Module) error {
 	for _, module := range modules {
-		f, err := os.Open(module.AbsolutePath)
-		if err != nil {
-			return err
-		}
-
-		scanner := bufio.NewScanner(f)
-		scanner.Split(bufio.ScanLines)
-		line := 0
-		for scanner.Scan() {
-			line++
-			text := strings.TrimSpace(scanner.Text())
-
-			shouldContinue := false
-			for _, prefix := range ignoredLinePrefixes {
-				if strings.HasPrefix(text, prefix) {
-					shouldContinue = true
-					break
-				}
-			}
-			if shouldContinue {
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) {
 				continue
 			}
 
-			lValue, rValue := getLRValues(scanner.Text())
-
-			statement := &Statement{
-				Line:           line,
-				Indentation:    getIndentation(scanner.Text()),
-				Actions:        0,
-				UsedVariables:  make([]*Variable, 0),
-				OriginalCode:   scanner.Text(),
-				ModifiedCode:   scanner.Text(),
-				OriginalLValue: lValue,
-				OriginalRValue: rValue,
-				ModifiedRValue: rValue,
+			statement := module.GetStatement(n.line, n.column)
+			isNew := statement == nil
+			if isNew {
+				statement = newStatement(n)
 			}
 
-			for _, variable := range module.GetVariables() {
-				if containsOnlyVariable(text, fmt.Sprintf("%s()", variable.Name)) {
-					if variable.Type == LockFunc {
-						statement.Actions = MutexLock
-						statement.TargetVariable = variable
-						break
-					} else if variable.Type == UnlockFunc {
-						statement.Actions = MutexUnlock
-						statement.TargetVariable = variable
-						break
+			for _, v := range module.GetVariables() {
+				if v.Type != Dict {
+					continue
+				}
+
+				expression := n.rValue
+				switch {
+				case n.kind == assignmentNode && isSubscriptOf(n.target, v.Name):
+					statement.Actions |= DictSet
+					statement.TargetVariable = v
+				case n.kind == augmentedAssignmentNode && n.operator == "+=" && isSubscriptOf(n.target, v.Name):
+					statement.Actions |= DictAdd
+					statement.TargetVariable = v
+				case n.kind == expressionNode && len(n.tokens) > 1 && n.tokens[0].is(nameToken, "del") &&
+					isSubscriptOf(n.code[n.tokens[1].start-n.tokens[0].start:], v.Name):
+					statement.Actions |= DictDelete
+					statement.TargetVariable = v
+					expression = ""
+				}
+
+				used := false
+				for _, ref := range findReferences(expression, v.Name) {
+					switch {
+					case ref.kind == subscriptReference, ref.kind == methodReference && ref.method == "get":
+						statement.Actions |= DictGet
+					case ref.kind == methodReference && ref.method == "keys", ref.kind == iterationReference:
+						statement.Actions |= DictKeys
+					case ref.kind == lengthReference:
+						statement.Actions |= DictLength
+					case ref.kind == membershipReference:
+						statement.Actions |= DictContains
+					default:
+						continue
 					}
+					used = true
+				}
+
+				if used {
+					statement.UsedVariables = append(statement.UsedVariables, v)
 				}
 			}
 
-			if statement.Actions != 0 {
+			if isNew && statement.Actions != 0 {
 				module.Statements = append(module.Statements, statement)
 			}
 		}
-
-		err = f.Close()
-		if err != nil {
-			return err
-		}
 	}
 
 	return nil
 }
 
-func getSyncs(modules []*Module) error {
+func getListOperations(modules []*Module) error {
 	for _, module := range modules {
-		f, err := os.Open(module.AbsolutePath)
-		if err != nil {
-			return err
-		}
-
-		scanner := bufio.NewScanner(f)
-		scanner.Split(bufio.ScanLines)
-		line := 0
-		for scanner.Scan() {
-			line++
-			text := strings.TrimSpace(scanner.Text())
-
-			shouldContinue := false
-			for _, prefix := range ignoredLinePrefixes {
-				if strings.HasPrefix(text, prefix) {
-					shouldContinue = true
-					break
-				}
-			}
-			if shouldContinue {
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) {
 				continue
 			}
 
-			lValue, rValue := getLRValues(scanner.Text())
-
-			statement := &Statement{
-				Line:           line,
-				Indentation:    getIndentation(scanner.Text()),
-				Actions:        0,
-				UsedVariables:  make([]*Variable, 0),
-				OriginalCode:   scanner.Text(),
-				ModifiedCode:   scanner.Text(),
-				OriginalLValue: lValue,
-				OriginalRValue: rValue,
-				ModifiedRValue: rValue,
+			statement := module.GetStatement(n.line, n.column)
+			isNew := statement == nil
+			if isNew {
+				statement = newStatement(n)
 			}
 
-			for _, variable := range module.GetVariables() {
-				if startsWithVariable(text, fmt.Sprintf("%s(", variable.Name)) {
-					switch variable.Type {
-					case WaitGroup:
-						statement.Actions = Wait
-						statement.TargetVariable = variable
-						break
+			for _, v := range module.GetVariables() {
+				if v.Type != List {
+					continue
+				}
+
+				if n.kind == assignmentNode && isSubscriptOf(n.target, v.Name) {
+					statement.Actions |= ListSet
+					statement.TargetVariable = v
+				}
+
+				used := false
+				for _, ref := range findReferences(n.rValue, v.Name) {
+					switch {
+					case ref.kind == subscriptReference:
+						if getSliceBounds(ref.args) == nil {
+							statement.Actions |= ListGet
+						} else {
+							statement.Actions |= ListSlice
+						}
+					case ref.kind == methodReference && ref.method == "append":
+						statement.Actions |= ListAppend
+					case ref.kind == lengthReference:
+						statement.Actions |= ListLength
+					case ref.kind == membershipReference, ref.kind == iterationReference, ref.kind == bareReference:
+						statement.Actions |= ListSlice
+					default:
+						continue
 					}
+					used = true
+				}
+
+				if used {
+					statement.UsedVariables = append(statement.UsedVariables, v)
 				}
 			}
 
-			if statement.Actions != 0 {
+			if isNew && statement.Actions != 0 {
 				module.Statements = append(module.Statements, statement)
 			}
 		}
-
-		err = f.Close()
-		if err != nil {
-			return err
-		}
 	}
 
 	return nil
 }
 
-func getVariableType(pythonType string, name string) VariableType {
-	switch pythonType {
-	case "bool":
-		return Bool
-	case "int":
-		return Int
-	case "float":
-		return Float
-	case "str":
-		return Str
-	case "callable":
-		if strings.HasPrefix(name, "lock_") {
-			return LockFunc
+func getSetOperations(modules []*Module) error {
+	for _, module := range modules {
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) {
+				continue
+			}
+
+			statement := module.GetStatement(n.line, n.column)
+			isNew := statement == nil
+			if isNew {
+				statement = newStatement(n)
+			}
+
+			for _, v := range module.GetVariables() {
+				if v.Type != Set {
+					continue
+				}
+
+				used := false
+				for _, ref := range findReferences(n.rValue, v.Name) {
+					switch {
+					case ref.kind == methodReference && ref.method == "add":
+						statement.Actions |= SetAdd
+					case ref.kind == methodReference && (ref.method == "discard" || ref.method == "remove"):
+						statement.Actions |= SetDiscard
+					case ref.kind == membershipReference:
+						statement.Actions |= SetContains
+					case ref.kind == lengthReference:
+						statement.Actions |= SetLength
+					case ref.kind == iterationReference, ref.kind == bareReference:
+						statement.Actions |= SetItems
+					default:
+						continue
+					}
+					used = true
+				}
+
+				if used {
+					statement.UsedVariables = append(statement.UsedVariables, v)
+				}
+			}
+
+			if isNew && statement.Actions != 0 {
+				module.Statements = append(module.Statements, statement)
+			}
 		}
+	}
 
-		if strings.HasPrefix(name, "unlock_") {
-			return UnlockFunc
+	return nil
+}
+
+func getChanOperations(modules []*Module) error {
+	for _, module := range modules {
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) {
+				continue
+			}
+
+			statement := module.GetStatement(n.line, n.column)
+			isNew := statement == nil
+			if isNew {
+				statement = newStatement(n)
+			}
+
+			for _, v := range module.GetVariables() {
+				if v.Type != Chan {
+					continue
+				}
+
+				used := false
+				for _, ref := range findReferences(n.rValue, v.Name) {
+					switch {
+					case ref.kind == methodReference && ref.method == "send":
+						statement.Actions |= ChanSend
+					case ref.kind == methodReference && ref.method == "recv":
+						statement.Actions |= ChanReceive
+					case ref.kind == methodReference && ref.method == "close":
+						statement.Actions |= ChanClose
+					case ref.kind == iterationReference:
+						statement.Actions |= ChanIterate
+					default:
+						continue
+					}
+					used = true
+				}
+
+				if used && !includesVariable(statement.UsedVariables, v) {
+					statement.UsedVariables = append(statement.UsedVariables, v)
+				}
+			}
+
+			if isNew && statement.Actions != 0 {
+				module.Statements = append(module.Statements, statement)
+			}
 		}
+	}
 
-		if strings.HasPrefix(name, "sync_") {
-			return WaitGroup
+	return nil
+}
+
+func getAtomicOperations(modules []*Module) error {
+	for _, module := range modules {
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) {
+				continue
+			}
+
+			statement := module.GetStatement(n.line, n.column)
+			isNew := statement == nil
+			if isNew {
+				statement = newStatement(n)
+			}
+
+			for _, v := range module.GetVariables() {
+				if !supportsAtomicOperations(v) {
+					continue
+				}
+
+				used := false
+				for _, ref := range findReferences(n.rValue, v.Name) {
+					if !isAtomicReference(ref, v) {
+						continue
+					}
+
+					switch ref.method {
+					case "fetch_add":
+						statement.Actions |= AtomicFetchAdd
+					case "swap":
+						statement.Actions |= AtomicSwap
+					case "compare_and_swap":
+						statement.Actions |= AtomicCompareAndSwap
+					}
+					used = true
+				}
+
+				if used && !includesVariable(statement.UsedVariables, v) {
+					statement.UsedVariables = append(statement.UsedVariables, v)
+				}
+			}
+
+			if isNew && statement.Actions != 0 {
+				module.Statements = append(module.Statements, statement)
+			}
 		}
 	}
 
-	if strings.HasPrefix(pythonType, "LifoQueue") {
-		return LifoQueue
+	return nil
+}
+
+// supportsAtomicOperations reports whether the variable is of a type whose
+// register implements fetch_add(), swap() and compare_and_swap().
+func supportsAtomicOperations(v *Variable) bool {
+	switch v.Type {
+	case Bool, Int, Float, Str:
+		return true
+	default:
+		return false
 	}
+}
 
-	if strings.HasPrefix(pythonType, "Queue") {
-		return Queue
+func isAtomicReference(ref reference, v *Variable) bool {
+	if ref.kind != methodReference {
+		return false
 	}
 
-	return 0
+	switch ref.method {
+	case "fetch_add":
+		return v.Type != Bool
+	case "swap", "compare_and_swap":
+		return true
+	default:
+		return false
+	}
 }
 
-func getVariableSubType(pythonType string) VariableType {
-	if (strings.HasPrefix(pythonType, "LifoQueue[") || strings.HasPrefix(pythonType, "Queue[")) &&
-		strings.HasSuffix(pythonType, "]") {
-		varType := pythonType[strings.Index(pythonType, "[")+1 : strings.LastIndex(pythonType, "]")]
-		return getVariableType(varType, "")
+func includesVariable(variables []*Variable, v *Variable) bool {
+	for _, variable := range variables {
+		if variable == v {
+			return true
+		}
 	}
-	return 0
+	return false
 }
 
-func shouldSkipLine(code string) bool {
+// checkQueueArguments returns an error if the arguments passed to a queue's
+// get()/put() method aren't ones the generated functions accept, namely the
+// item (put() only, passed positionally) followed by block and timeout, as with
+// Python's queue module.
+func checkQueueArguments(v *Variable, method string, args string) error {
+	params := []string{"block", "timeout"}
+	if method == "put" {
+		params = []string{"item", "block", "timeout"}
+	}
+
+	all, err := tokenize(args)
+	if err != nil {
+		return err
+	}
+
+	tokens := make([]token, 0)
+	for _, t := range all {
+		if t.Type != newlineToken && t.Type != nlToken && t.Type != endMarkerToken && t.Type != commentToken {
+			tokens = append(tokens, t)
+		}
+	}
+
+	for position := 0; len(tokens) > 0; position++ {
+		arg := tokens
+		comma := findAtDepthZero(tokens, ",")
+		if comma >= 0 {
+			arg, tokens = tokens[:comma], tokens[comma+1:]
+		} else {
+			tokens = nil
+		}
+
+		if len(arg) > 1 && arg[0].Type == nameToken && arg[1].is(operatorToken, "=") {
+			if arg[0].Value == "item" || !containsString(params, arg[0].Value) {
+				return fmt.Errorf("unsupported argument %s for %s.%s()", arg[0].Value, v.Name, method)
+			}
+			continue
+		}
+
+		if len(arg) > 0 && position >= len(params) {
+			return fmt.Errorf("too many arguments for %s.%s() (expected at most %d)", v.Name, method, len(params))
+		}
+	}
+
+	if method == "put" && strings.TrimSpace(args) == "" {
+		return fmt.Errorf("missing item for %s.put()", v.Name)
+	}
+
+	return nil
+}
+
+// getExtremumUpdate returns the action for code of the form max(_x_, val) or
+// min(_x_, val) (in either order), where _x_ is a numeric variable, along with
+// the other operand.
+func getExtremumUpdate(code string, v *Variable) (ActionFlag, string) {
+	if v == nil || (v.Type != Int && v.Type != Float) {
+		return 0, ""
+	}
+
 	code = strings.TrimSpace(code)
-	if code == "" {
-		return true
+	all, err := tokenize(code)
+	if err != nil {
+		return 0, ""
 	}
 
-	for _, prefix := range ignoredLinePrefixes {
-		if strings.HasPrefix(code, prefix) {
-			return true
+	tokens := make([]token, 0)
+	for _, t := range all {
+		if t.Type != newlineToken && t.Type != nlToken && t.Type != endMarkerToken && t.Type != commentToken {
+			tokens = append(tokens, t)
 		}
 	}
+	if len(tokens) < 4 || tokens[0].Type != nameToken || !tokens[1].is(operatorToken, "(") ||
+		findClosingBracket(tokens, 1) != len(tokens)-1 {
+		return 0, ""
+	}
 
-	return false
+	var action ActionFlag
+	switch tokens[0].Value {
+	case "max":
+		action = VariableMax
+	case "min":
+		action = VariableMin
+	default:
+		return 0, ""
+	}
+
+	args := tokens[2 : len(tokens)-1]
+	comma := findAtDepthZero(args, ",")
+	if comma < 1 || comma == len(args)-1 || findAtDepthZero(args[comma+1:], ",") >= 0 {
+		return 0, ""
+	}
+
+	first := code[args[0].start:args[comma-1].end]
+	second := code[args[comma+1].start:args[len(args)-1].end]
+	isTarget := func(arg string) bool {
+		return arg == v.Name || arg == fmt.Sprintf("(%s)", v.Name)
+	}
+
+	switch {
+	case isTarget(first):
+		return action, second
+	case isTarget(second):
+		return action, first
+	default:
+		return 0, ""
+	}
 }
 
-func getLRValues(code string) (lValue, rValue string) {
+// isSubscriptOf reports whether code is nothing more than a subscript of the
+// named variable, e.g. _cache_[key].
+func isSubscriptOf(code string, variableName string) bool {
 	code = strings.TrimSpace(code)
+	refs := findReferences(code, variableName)
+	return len(refs) > 0 && refs[0].kind == subscriptReference && refs[0].start == 0 && refs[0].end == len(code)
+}
+
+func getMutexLocksAndUnlocks(modules []*Module) error {
+	for _, module := range modules {
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) {
+				continue
+			}
+
+			statement := newStatement(n)
+
+			for _, variable := range module.GetVariables() {
+				if containsOnlyVariable(n.code, fmt.Sprintf("%s()", variable.Name)) {
+					if variable.Type == LockFunc || variable.Type == RLockFunc {
+						statement.Actions = MutexLock
+						statement.TargetVariable = variable
+						break
+					} else if variable.Type == UnlockFunc || variable.Type == RUnlockFunc {
+						statement.Actions = MutexUnlock
+						statement.TargetVariable = variable
+						break
+					}
+				}
+			}
+
+			if statement.Actions != 0 {
+				module.Statements = append(module.Statements, statement)
+			}
+		}
+	}
+
+	return nil
+}
+
+func getSyncs(modules []*Module) error {
+	for _, module := range modules {
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) {
+				continue
+			}
+
+			statement := newStatement(n)
+
+			for _, variable := range module.GetVariables() {
+				if startsWithVariable(n.code, fmt.Sprintf("%s(", variable.Name)) {
+					switch variable.Type {
+					case WaitGroup:
+						statement.Actions = Wait
+						statement.TargetVariable = variable
+						break
+					}
+				}
+			}
 
-	if shouldSkipLine(code) {
-		return "", ""
+			if statement.Actions != 0 {
+				module.Statements = append(module.Statements, statement)
+			}
+		}
 	}
 
-	for _, prefix := range controlStructurePrefixes {
-		if strings.HasPrefix(code, prefix) {
-			return "", code
+	return nil
+}
+
+func getSemaphoreAcquiresAndReleases(modules []*Module) error {
+	for _, module := range modules {
+		semaphores := make([]*Variable, 0)
+		for _, variable := range module.GetVariables() {
+			if variable.IsSemaphoreFunc() {
+				semaphores = append(semaphores, variable)
+			}
 		}
+
+		for _, variable := range semaphores {
+			if variable.Type != ReleaseFunc {
+				continue
+			}
+
+			acquired := false
+			for _, v := range semaphores {
+				acquired = acquired || (v.Type == AcquireFunc && v.Tag == variable.Tag)
+			}
+			if !acquired {
+				return fmt.Errorf("semaphore release %s has no matching acquire", variable.Name)
+			}
+		}
+
+		getCalls(module, semaphores, func(v *Variable) ActionFlag {
+			if v.Type == AcquireFunc {
+				return SemaphoreAcquire
+			}
+			return SemaphoreRelease
+		})
 	}
 
-	isAssignment := true
-	for _, c := range code {
-		if c == '(' {
-			isAssignment = false
-			break
-		} else if c == '=' {
-			break
+	return nil
+}
+
+func getBarrierWaits(modules []*Module) error {
+	for _, module := range modules {
+		barriers := make([]*Variable, 0)
+		for _, variable := range module.GetVariables() {
+			if variable.Type == Barrier {
+				barriers = append(barriers, variable)
+			}
 		}
+
+		getCalls(module, barriers, func(v *Variable) ActionFlag {
+			return BarrierWait
+		})
 	}
 
-	if isAssignment {
-		codeParts := strings.Split(code, "=")
-		if len(codeParts) < 2 {
-			return "", code
+	return nil
+}
+
+// getBatchesAndTransactions finds the calls to batches and transactions, e.g.
+// with _batch_main_():, which return the context manager for the block.
+func getBatchesAndTransactions(modules []*Module) error {
+	for _, module := range modules {
+		variables := make([]*Variable, 0)
+		for _, variable := range module.GetVariables() {
+			if variable.Type == Batch || variable.Type == Transaction {
+				variables = append(variables, variable)
+			}
 		}
 
-		return codeParts[0], strings.Join(codeParts[1:], "=")
+		getCalls(module, variables, func(v *Variable) ActionFlag {
+			if v.Type == Transaction {
+				return TransactionStart
+			}
+			return BatchStart
+		})
 	}
 
-	return "", code
+	return nil
 }
 
-func getDefaultValue(dataType string, rValue string) (any, error) {
+func getEventAndConditionOperations(modules []*Module) error {
+	for _, module := range modules {
+		for _, v := range module.GetVariables() {
+			if v.Type != Condition {
+				continue
+			}
+
+			lock := module.GetVariableByName(v.DefaultValue.(string))
+			if lock == nil || lock.Type != LockFunc {
+				return fmt.Errorf("condition %s must be given a lock (e.g. Condition(_lock_x_)), got %s", v.Name, v.DefaultValue)
+			}
+			v.Tag = lock.Tag
+		}
+
+		for _, n := range module.syntax.nodes {
+			if !isTranslatable(n) {
+				continue
+			}
+
+			statement := module.GetStatement(n.line, n.column)
+			isNew := statement == nil
+			if isNew {
+				statement = newStatement(n)
+			}
+
+			for _, v := range module.GetVariables() {
+				if v.Type != Event && v.Type != Condition {
+					continue
+				}
+
+				used := false
+				for _, ref := range findReferences(n.rValue, v.Name) {
+					if ref.kind != methodReference {
+						continue
+					}
+
+					switch {
+					case v.Type == Event && ref.method == "set":
+						statement.Actions |= EventSet
+					case v.Type == Event && ref.method == "clear":
+						statement.Actions |= EventClear
+					case v.Type == Event && ref.method == "is_set":
+						statement.Actions |= EventIsSet
+					case v.Type == Event && ref.method == "wait":
+						statement.Actions |= EventWait
+					case v.Type == Condition && ref.method == "wait":
+						statement.Actions |= ConditionWait
+					case v.Type == Condition && ref.method == "notify":
+						statement.Actions |= ConditionNotify
+					case v.Type == Condition && ref.method == "notify_all":
+						statement.Actions |= ConditionNotifyAll
+					default:
+						continue
+					}
+					used = true
+				}
+
+				if used && !includesVariable(statement.UsedVariables, v) {
+					statement.UsedVariables = append(statement.UsedVariables, v)
+				}
+			}
+
+			if isNew && statement.Actions != 0 {
+				module.Statements = append(module.Statements, statement)
+			}
+		}
+	}
+
+	return nil
+}
+
+// getCalls adds the action returned by action to the statements calling any of
+// the given (callable) variables, which are then included in the statement's
+// used variables.
+func getCalls(module *Module, variables []*Variable, action func(v *Variable) ActionFlag) {
+	for _, n := range module.syntax.nodes {
+		if !isTranslatable(n) || n.kind == annotatedAssignmentNode {
+			continue
+		}
+
+		statement := module.GetStatement(n.line, n.column)
+		isNew := statement == nil
+		if isNew {
+			statement = newStatement(n)
+		}
+
+		for _, variable := range variables {
+			for _, ref := range findReferences(n.code, variable.Name) {
+				if ref.kind != callReference {
+					continue
+				}
+
+				statement.Actions |= action(variable)
+				if !includesVariable(statement.UsedVariables, variable) {
+					statement.UsedVariables = append(statement.UsedVariables, variable)
+				}
+			}
+		}
+
+		if isNew && statement.Actions != 0 {
+			module.Statements = append(module.Statements, statement)
+		}
+	}
+}
+
+// isTranslatable excludes nodes Gothon never rewrites (imports, function and
+// class definitions, docstrings, etc.).
+func isTranslatable(n *node) bool {
+	return n.kind != ignoredNode
+}
+
+func newStatement(n *node) *Statement {
+	return &Statement{
+		Line:           n.line,
+		Column:         n.column,
+		EndLine:        n.endLine,
+		EndColumn:      n.endColumn,
+		Indentation:    n.indentation,
+		UsedVariables:  make([]*Variable, 0),
+		OriginalCode:   n.indentation + n.code,
+		ModifiedCode:   n.indentation + n.code,
+		OriginalLValue: n.lValue,
+		OriginalRValue: n.rValue,
+		ModifiedRValue: n.rValue,
+	}
+}
+
+func getVariableType(pythonType string, name string) VariableType {
+	switch pythonType {
+	case "bool":
+		return Bool
+	case "int":
+		return Int
+	case "float":
+		return Float
+	case "str":
+		return Str
+	case "bytes":
+		return Bytes
+	case "object", "Any":
+		return Object
+	case "Event":
+		return Event
+	case "Condition":
+		return Condition
+	case "callable":
+		if strings.HasPrefix(name, "lock_") {
+			return LockFunc
+		}
+
+		if strings.HasPrefix(name, "unlock_") {
+			return UnlockFunc
+		}
+
+		if strings.HasPrefix(name, "rlock_") {
+			return RLockFunc
+		}
+
+		if strings.HasPrefix(name, "runlock_") {
+			return RUnlockFunc
+		}
+
+		if strings.HasPrefix(name, "acquire_") {
+			return AcquireFunc
+		}
+
+		if strings.HasPrefix(name, "release_") {
+			return ReleaseFunc
+		}
+
+		if strings.HasPrefix(name, "sync_") {
+			return WaitGroup
+		}
+
+		if strings.HasPrefix(name, "barrier_") {
+			return Barrier
+		}
+
+		if strings.HasPrefix(name, "batch_") {
+			return Batch
+		}
+
+		if strings.HasPrefix(name, "transaction_") {
+			return Transaction
+		}
+	}
+
+	if strings.HasPrefix(pythonType, "LifoQueue") {
+		return LifoQueue
+	}
+
+	if strings.HasPrefix(pythonType, "PriorityQueue") {
+		return PriorityQueue
+	}
+
+	if strings.HasPrefix(pythonType, "Topic") {
+		return Topic
+	}
+
+	if strings.HasPrefix(pythonType, "Chan") {
+		return Chan
+	}
+
+	if strings.HasPrefix(pythonType, "dict[") {
+		return Dict
+	}
+
+	if strings.HasPrefix(pythonType, "list[") {
+		return List
+	}
+
+	if strings.HasPrefix(pythonType, "set[") {
+		return Set
+	}
+
+	if strings.HasPrefix(pythonType, "Queue") {
+		return Queue
+	}
+
+	return 0
+}
+
+func getVariableSubType(pythonType string) VariableType {
+	if (strings.HasPrefix(pythonType, "LifoQueue[") || strings.HasPrefix(pythonType, "Queue[") ||
+		strings.HasPrefix(pythonType, "PriorityQueue[") || strings.HasPrefix(pythonType, "Topic[") ||
+		strings.HasPrefix(pythonType, "Chan[") || strings.HasPrefix(pythonType, "list[")) &&
+		strings.HasSuffix(pythonType, "]") {
+		varType := pythonType[strings.Index(pythonType, "[")+1 : strings.LastIndex(pythonType, "]")]
+		return getVariableType(varType, "")
+	}
+
+	if strings.HasPrefix(pythonType, "dict[") && strings.HasSuffix(pythonType, "]") {
+		varType := pythonType[strings.Index(pythonType, ",")+1 : strings.LastIndex(pythonType, "]")]
+		return getVariableType(varType, "")
+	}
+	return 0
+}
+
+func getVariableKeyType(pythonType string) VariableType {
+	if strings.HasPrefix(pythonType, "dict[") && strings.Contains(pythonType, ",") {
+		varType := pythonType[strings.Index(pythonType, "[")+1 : strings.Index(pythonType, ",")]
+		return getVariableType(varType, "")
+	}
+
+	if strings.HasPrefix(pythonType, "set[") && strings.HasSuffix(pythonType, "]") {
+		varType := pythonType[strings.Index(pythonType, "[")+1 : strings.LastIndex(pythonType, "]")]
+		return getVariableType(varType, "")
+	}
+	return 0
+}
+
+func getDefaultValue(dataType string, rValue string, tokens []token) (any, error) {
 	rValue = strings.TrimSpace(rValue)
 
 	switch dataType {
 	case "callable":
-		valParts := strings.Split(rValue, "=")
-		if len(valParts) < 2 {
-			return rValue, nil
-		}
-		valParts = strings.Split(valParts[1], ":")
-		val := strings.TrimSpace(valParts[0])
-		return val, nil
+		return getLambdaDefault(rValue, tokens), nil
 	case "bool":
 		return strconv.ParseBool(rValue)
 	case "int":
@@ -839,12 +1447,11 @@ func getDefaultValue(dataType string, rValue string) (any, error) {
 	case "float":
 		return strconv.ParseFloat(rValue, 64)
 	case "str":
-		if strings.HasPrefix(rValue, "'") && strings.HasSuffix(rValue, "'") {
-			rValue = strings.Trim(rValue, "'")
-		} else if strings.HasPrefix(rValue, "\"") && strings.HasSuffix(rValue, "\"") {
-			rValue = strings.Trim(rValue, "\"")
-		}
-		return rValue, nil
+		return parseStringLiteral(rValue, tokens)
+	case "bytes":
+		return parseBytesLiteral(rValue)
+	case "object", "Any":
+		return nil, nil
 	}
 
 	if strings.HasPrefix(dataType, "LifoQueue") {
@@ -856,6 +1463,15 @@ func getDefaultValue(dataType string, rValue string) (any, error) {
 		return strconv.ParseInt(rValue, 10, 64)
 	}
 
+	if strings.HasPrefix(dataType, "PriorityQueue") {
+		rValue = strings.TrimSpace(rValue)
+		rValue = strings.TrimPrefix(rValue, "PriorityQueue(")
+		rValue = strings.TrimSuffix(rValue, ")")
+		rValue = strings.TrimSpace(rValue)
+		rValue = strings.ReplaceAll(rValue, "_", "")
+		return strconv.ParseInt(rValue, 10, 64)
+	}
+
 	if strings.HasPrefix(dataType, "Queue") {
 		rValue = strings.TrimSpace(rValue)
 		rValue = strings.TrimPrefix(rValue, "Queue(")
@@ -865,9 +1481,249 @@ func getDefaultValue(dataType string, rValue string) (any, error) {
 		return strconv.ParseInt(rValue, 10, 64)
 	}
 
+	if strings.HasPrefix(dataType, "Topic") {
+		if strings.ReplaceAll(rValue, " ", "") != "Topic()" {
+			return nil, fmt.Errorf("unsupported default value for %s: %s (shared topics must start empty)", dataType, rValue)
+		}
+		return nil, nil
+	}
+
+	if strings.HasPrefix(dataType, "Chan") {
+		rValue = strings.TrimPrefix(rValue, "Chan(")
+		rValue = strings.TrimSuffix(rValue, ")")
+		rValue = strings.TrimSpace(rValue)
+		rValue = strings.ReplaceAll(rValue, "_", "")
+		if rValue == "" {
+			return int64(0), nil
+		}
+
+		size, err := strconv.ParseInt(rValue, 10, 64)
+		if err == nil && size < 0 {
+			err = fmt.Errorf("unsupported default value for %s: %d (channel capacity cannot be negative)", dataType, size)
+		}
+		return size, err
+	}
+
+	if dataType == "Event" {
+		if rValue != "Event()" {
+			return nil, fmt.Errorf("unsupported default value for %s: %s (shared events must start cleared)", dataType, rValue)
+		}
+		return nil, nil
+	}
+
+	if dataType == "Condition" {
+		lock := strings.TrimSuffix(strings.TrimPrefix(rValue, "Condition("), ")")
+		if lock == rValue || strings.TrimSpace(lock) == "" {
+			return nil, fmt.Errorf("unsupported default value for %s: %s (shared conditions must be given a lock)", dataType, rValue)
+		}
+		return strings.TrimSpace(lock), nil
+	}
+
+	if strings.HasPrefix(dataType, "dict[") {
+		if rValue != "{}" && rValue != "dict()" {
+			return nil, fmt.Errorf("unsupported default value for %s: %s (shared dicts must start empty)", dataType, rValue)
+		}
+		return nil, nil
+	}
+
+	if strings.HasPrefix(dataType, "set[") {
+		if rValue != "set()" {
+			return nil, fmt.Errorf("unsupported default value for %s: %s (shared sets must start empty)", dataType, rValue)
+		}
+		return nil, nil
+	}
+
+	if strings.HasPrefix(dataType, "list[") {
+		if rValue != "[]" && rValue != "list()" {
+			return nil, fmt.Errorf("unsupported default value for %s: %s (shared lists must start empty)", dataType, rValue)
+		}
+		return nil, nil
+	}
+
 	return nil, errors.New("unsupported datatype")
 }
 
+// getLambdaDefault returns the default value of the first parameter of the
+// lambda that has one, e.g. 8 for lambda n=8: (), or the lambda itself if none
+// of its parameters do.
+func getLambdaDefault(rValue string, tokens []token) string {
+	if len(tokens) == 0 || !tokens[0].is(nameToken, "lambda") {
+		return rValue
+	}
+
+	end := findAtDepthZero(tokens, ":")
+	if end < 0 {
+		return rValue
+	}
+
+	params := tokens[1:end]
+	for len(params) > 0 {
+		next := findAtDepthZero(params, ",")
+		if next < 0 {
+			next = len(params)
+		}
+
+		param := params[:next]
+		if eq := findAtDepthZero(param, "="); eq >= 0 && eq+1 < len(param) {
+			return joinTokens(param[eq+1:])
+		}
+
+		if next == len(params) {
+			break
+		}
+		params = params[next+1:]
+	}
+
+	return rValue
+}
+
+// parseStringLiteral returns the value of the str literal, or of the adjacent
+// literals Python would concatenate, decoding their escape sequences.
+func parseStringLiteral(literal string, tokens []token) (string, error) {
+	if literal == "str()" {
+		return "", nil
+	}
+
+	unsupportedErr := fmt.Errorf("unsupported default value for str: %s", literal)
+	if len(tokens) == 0 {
+		return "", unsupportedErr
+	}
+
+	sb := strings.Builder{}
+	for _, t := range tokens {
+		if t.Type != stringToken {
+			return "", unsupportedErr
+		}
+
+		prefix, content, ok := splitStringLiteral(t.Value)
+		if !ok || strings.ContainsAny(prefix, "bf") {
+			return "", unsupportedErr
+		}
+
+		if strings.Contains(prefix, "r") {
+			sb.WriteString(content)
+			continue
+		}
+
+		val, err := unescapeLiteral(content, false)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in str literal: %s", literal)
+		}
+		sb.Write(val)
+	}
+
+	return sb.String(), nil
+}
+
+// parseBytesLiteral returns the value of a Python bytes literal (b'...'), or
+// of bytes() which is the empty value.
+func parseBytesLiteral(literal string) ([]byte, error) {
+	if literal == "bytes()" {
+		return []byte{}, nil
+	}
+
+	prefix, content, ok := splitStringLiteral(literal)
+	if !ok || (prefix != "b" && prefix != "br" && prefix != "rb") {
+		return nil, fmt.Errorf("unsupported default value for bytes: %s", literal)
+	}
+
+	if strings.Contains(prefix, "r") {
+		return []byte(content), nil
+	}
+
+	val, err := unescapeLiteral(content, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid escape in bytes literal: %s", literal)
+	}
+	return val, nil
+}
+
+// splitStringLiteral returns the lowercased prefix (e.g. rb) of the string or
+// bytes literal and its content between the quotes.
+func splitStringLiteral(literal string) (prefix string, content string, ok bool) {
+	prefix = strings.ToLower(literal[:strings.IndexAny(literal+"'", "'\"")])
+	quoted := literal[len(prefix):]
+	if len(quoted) < 2 {
+		return "", "", false
+	}
+
+	quote := quoted[:1]
+	if strings.HasPrefix(quoted, strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	if len(quoted) < 2*len(quote) || !strings.HasSuffix(quoted, quote) {
+		return "", "", false
+	}
+
+	return prefix, quoted[len(quote) : len(quoted)-len(quote)], true
+}
+
+// unescapeLiteral decodes the escape sequences in the content of a str or
+// bytes literal, where \x and octal escapes are code points in a str but bytes
+// in a bytes literal, and \u and \U are only escapes in a str.
+func unescapeLiteral(content string, isBytes bool) ([]byte, error) {
+	val := make([]byte, 0, len(content))
+	appendCode := func(code uint64) {
+		if isBytes {
+			val = append(val, byte(code))
+		} else {
+			val = utf8.AppendRune(val, rune(code))
+		}
+	}
+
+	for i := 0; i < len(content); i++ {
+		if content[i] != '\\' || i+1 == len(content) {
+			val = append(val, content[i])
+			continue
+		}
+
+		i++
+		switch c := content[i]; {
+		case c == '\n':
+			continue
+		case c == 'a':
+			val = append(val, '\a')
+		case c == 'b':
+			val = append(val, '\b')
+		case c == 'f':
+			val = append(val, '\f')
+		case c == 'n':
+			val = append(val, '\n')
+		case c == 'r':
+			val = append(val, '\r')
+		case c == 't':
+			val = append(val, '\t')
+		case c == 'v':
+			val = append(val, '\v')
+		case c == 'x', !isBytes && c == 'u', !isBytes && c == 'U':
+			digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+			if i+digits >= len(content) {
+				return nil, fmt.Errorf("truncated \\%c escape", c)
+			}
+			code, err := strconv.ParseUint(content[i+1:i+1+digits], 16, 32)
+			if err != nil || code > unicode.MaxRune {
+				return nil, fmt.Errorf("invalid \\%c escape", c)
+			}
+			appendCode(code)
+			i += digits
+		case c == '\\' || c == '\'' || c == '"':
+			val = append(val, c)
+		case c >= '0' && c <= '7':
+			end := i + 1
+			for end < len(content) && end < i+3 && content[end] >= '0' && content[end] <= '7' {
+				end++
+			}
+			code, _ := strconv.ParseUint(content[i:end], 8, 16)
+			appendCode(code)
+			i = end - 1
+		default:
+			val = append(val, '\\', c)
+		}
+	}
+
+	return val, nil
+}
+
 func getIndentation(code string) string {
 	if strings.TrimSpace(code) == "" {
 		return ""
@@ -912,15 +1768,29 @@ func startsWithVariable(code string, variableName string) bool {
 	return regex.MatchString(code)
 }
 
-func containsVariable(code string, variableName string) bool {
-	regex, err := regexp.Compile("([_A-Za-z][_A-Za-z0-9]*\\.)?(" + variableName + ")")
-	if err != nil {
-		panic(err)
+// containsVariable reports whether code uses the variable (see findVariable),
+// in parentheses if requireParens is set, as in (_x_).
+func containsVariable(code string, variableName string, requireParens bool) bool {
+	refs, ok := findVariable(code, variableName)
+	if !ok {
+		if requireParens {
+			return strings.Contains(code, fmt.Sprintf("(%s)", variableName))
+		}
+		return variableRegex(variableName).MatchString(code)
 	}
-	return regex.MatchString(code)
+
+	for _, ref := range refs {
+		if !requireParens || (ref.start > 0 && code[ref.start-1] == '(' && code[ref.start:ref.end] == variableName &&
+			ref.end < len(code) && code[ref.end] == ')') {
+			return true
+		}
+	}
+	return false
 }
 
 func containsOnlyVariable(code string, variableName string) bool {
+	variableName = strings.ReplaceAll(variableName, "(", "\\(")
+	variableName = strings.ReplaceAll(variableName, ")", "\\)")
 	regex, err := regexp.Compile("^([_A-Za-z][_A-Za-z0-9]*\\.)?(" + variableName + ")$")
 	if err != nil {
 		panic(err)
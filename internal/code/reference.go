@@ -0,0 +1,320 @@
+package code
+
+import (
+	"regexp"
+	"strings"
+)
+
+type referenceKind byte
+
+const (
+	bareReference referenceKind = iota
+	subscriptReference
+	methodReference
+	lengthReference
+	membershipReference
+	iterationReference
+	callReference
+)
+
+var (
+	operandBoundaryOps      = []string{",", "=", ":", ";", ":=", "->", "==", "!=", "<", ">", "<=", ">="}
+	operandBoundaryKeywords = []string{"and", "or", "not", "if", "else", "elif", "while", "for", "in", "is",
+		"return", "assert", "yield", "lambda", "del", "await"}
+)
+
+// reference is a single use of a variable within an expression, where start
+// and end are the byte offsets of the code to replace when translating it.
+type reference struct {
+	kind    referenceKind
+	start   int
+	end     int
+	method  string
+	args    string
+	operand string
+	negated bool
+}
+
+// findReferences returns the uses of the named variable found within code,
+// classified by how the variable is being used (subscripts, method calls,
+// len(), membership tests, for-loop iteration and calls).
+func findReferences(code string, name string) []reference {
+	tokens, err := tokenize(code)
+	if err != nil {
+		return nil
+	}
+
+	refs := make([]reference, 0)
+	for i, t := range tokens {
+		if t.Type != nameToken || t.Value != name {
+			continue
+		}
+
+		start := i
+		for start >= 2 && tokens[start-1].is(operatorToken, ".") && tokens[start-2].Type == nameToken {
+			start -= 2
+		}
+		if start > 0 && tokens[start-1].is(operatorToken, ".") {
+			continue
+		}
+
+		ref := reference{kind: bareReference, start: tokens[start].start, end: t.end}
+		next := i + 1
+
+		switch {
+		case next < len(tokens) && tokens[next].is(operatorToken, "["):
+			end := findClosingBracket(tokens, next)
+			if end < 0 {
+				continue
+			}
+			ref.kind = subscriptReference
+			ref.args = code[tokens[next].end:tokens[end].start]
+			ref.end = tokens[end].end
+		case next+2 < len(tokens) && tokens[next].is(operatorToken, ".") && tokens[next+1].Type == nameToken &&
+			tokens[next+2].is(operatorToken, "("):
+			end := findClosingBracket(tokens, next+2)
+			if end < 0 {
+				continue
+			}
+			ref.kind = methodReference
+			ref.method = tokens[next+1].Value
+			ref.args = code[tokens[next+2].end:tokens[end].start]
+			ref.end = tokens[end].end
+		case next < len(tokens) && tokens[next].is(operatorToken, "("):
+			end := findClosingBracket(tokens, next)
+			if end < 0 {
+				continue
+			}
+			ref.kind = callReference
+			ref.args = code[tokens[next].end:tokens[end].start]
+			ref.end = tokens[end].end
+		case start >= 2 && tokens[start-1].is(operatorToken, "(") && tokens[start-2].is(nameToken, "len") &&
+			next < len(tokens) && tokens[next].is(operatorToken, ")"):
+			ref.kind = lengthReference
+			ref.start = tokens[start-2].start
+			ref.end = tokens[next].end
+		case start >= 2 && tokens[start-1].is(nameToken, "in"):
+			op := start - 1
+			if tokens[op-1].is(nameToken, "not") {
+				ref.negated = true
+				op--
+			}
+
+			operand, boundary := findOperandStart(tokens, op)
+			if boundary == "for" {
+				ref.kind = iterationReference
+				break
+			}
+			if operand >= op {
+				continue
+			}
+			ref.kind = membershipReference
+			ref.start = tokens[operand].start
+			ref.operand = code[tokens[operand].start:tokens[op-1].end]
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return refs
+}
+
+// translateReferences rewrites each use of the variable within code, one at a
+// time (re-scanning after each so nested uses are translated as well), using
+// the replacement returned by translate.  Uses for which translate returns
+// false are left as they are.
+func translateReferences(code string, name string, translate func(ref reference) (string, bool)) string {
+	for {
+		translated := false
+		for _, ref := range findReferences(code, name) {
+			replacement, ok := translate(ref)
+			if !ok {
+				continue
+			}
+			code = code[:ref.start] + replacement + code[ref.end:]
+			translated = true
+			break
+		}
+
+		if !translated {
+			return code
+		}
+	}
+}
+
+// findVariable returns the uses of the named variable within code, each
+// spanning the objects it's an attribute of too (as in self._x_), if any.  Only
+// name tokens are uses, so strings and comments are skipped, except for the
+// replacement fields of f-strings, which are code.  ok is false if code can't
+// be tokenized.
+func findVariable(code string, name string) (refs []reference, ok bool) {
+	tokens, err := tokenize(code)
+	if err != nil {
+		return nil, false
+	}
+
+	for i, t := range tokens {
+		switch {
+		case t.Type == nameToken && t.Value == name:
+			start := i
+			for start >= 2 && tokens[start-1].is(operatorToken, ".") && tokens[start-2].Type == nameToken {
+				start -= 2
+			}
+			refs = append(refs, reference{kind: bareReference, start: tokens[start].start, end: t.end})
+		case t.Type == stringToken && isFString(t.Value):
+			for _, field := range findReplacementFields(t.Value) {
+				fieldRefs, _ := findVariable(t.Value[field[0]:field[1]], name)
+				for _, ref := range fieldRefs {
+					ref.start += t.start + field[0]
+					ref.end += t.start + field[0]
+					refs = append(refs, ref)
+				}
+			}
+		}
+	}
+
+	return refs, true
+}
+
+// replaceVariable replaces each use of the named variable within code (see
+// findVariable) with replacement.
+func replaceVariable(code string, name string, replacement string) string {
+	refs, ok := findVariable(code, name)
+	if !ok {
+		// not code the tokenizer understands, so match the name textually
+		return variableRegex(name).ReplaceAllString(code, replacement)
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, ref := range refs {
+		if ref.start < last {
+			continue
+		}
+		sb.WriteString(code[last:ref.start])
+		sb.WriteString(replacement)
+		last = ref.end
+	}
+	sb.WriteString(code[last:])
+	return sb.String()
+}
+
+func variableRegex(name string) *regexp.Regexp {
+	regex, err := regexp.Compile("\\b([_A-Za-z][_A-Za-z0-9]*\\.)?(" + name + ")\\b")
+	if err != nil {
+		panic(err)
+	}
+	return regex
+}
+
+func isFString(literal string) bool {
+	prefix := literal[:strings.IndexAny(literal, `'"`)]
+	return strings.ContainsAny(prefix, "fF")
+}
+
+// findReplacementFields returns the start and end offsets of the expressions
+// in the replacement fields of an f-string literal, e.g. x for {x!r:>8}.
+func findReplacementFields(literal string) [][2]int {
+	body := strings.IndexAny(literal, `'"`)
+	quote := 1
+	if strings.HasPrefix(literal[body:], strings.Repeat(literal[body:body+1], 3)) && len(literal)-body >= 6 {
+		quote = 3
+	}
+	end := len(literal) - quote
+
+	fields := make([][2]int, 0)
+	for i := body + quote; i < end; i++ {
+		switch {
+		case strings.HasPrefix(literal[i:end], "{{") || strings.HasPrefix(literal[i:end], "}}"):
+			i++
+		case literal[i] == '{':
+			exprEnd, fieldEnd := scanReplacementField(literal[:end], i+1)
+			fields = append(fields, [2]int{i + 1, exprEnd})
+			i = fieldEnd
+		}
+	}
+	return fields
+}
+
+// scanReplacementField returns the end of the expression of the replacement
+// field starting at start (where its conversion or format spec begins, if it
+// has one) and the offset of the brace closing the field.
+func scanReplacementField(literal string, start int) (int, int) {
+	depth := 0
+	exprEnd := -1
+	for i := start; i < len(literal); i++ {
+		c := literal[i]
+		switch {
+		case c == '\'' || c == '"':
+			closing := strings.IndexByte(literal[i+1:], c)
+			if closing < 0 {
+				i = len(literal)
+				break
+			}
+			i += closing + 1
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == '}':
+			if depth == 0 {
+				if exprEnd < 0 {
+					exprEnd = i
+				}
+				return exprEnd, i
+			}
+			depth--
+		case depth == 0 && exprEnd < 0 && (c == ':' || (c == '!' && i+1 < len(literal) && literal[i+1] != '=')):
+			exprEnd = i
+		}
+	}
+
+	if exprEnd < 0 {
+		exprEnd = len(literal)
+	}
+	return exprEnd, len(literal)
+}
+
+func findClosingBracket(tokens []token, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		if tokens[i].Type != operatorToken {
+			continue
+		}
+		switch tokens[i].Value {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// findOperandStart returns the index of the first token of the left operand
+// of the binary operator at index op, along with the token bounding it.
+func findOperandStart(tokens []token, op int) (int, string) {
+	depth := 0
+	for i := op - 1; i >= 0; i-- {
+		t := tokens[i]
+		switch {
+		case t.Type == operatorToken && (t.Value == ")" || t.Value == "]" || t.Value == "}"):
+			depth++
+		case t.Type == operatorToken && (t.Value == "(" || t.Value == "[" || t.Value == "{"):
+			if depth == 0 {
+				return i + 1, t.Value
+			}
+			depth--
+		case depth != 0:
+			continue
+		case t.Type == operatorToken && (containsString(operandBoundaryOps, t.Value) || containsString(augmentedOps, t.Value)):
+			return i + 1, t.Value
+		case t.Type == nameToken && containsString(operandBoundaryKeywords, t.Value):
+			return i + 1, t.Value
+		}
+	}
+	return 0, ""
+}
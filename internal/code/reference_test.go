@@ -0,0 +1,30 @@
+package code
+
+import "testing"
+
+func TestReplaceVariable(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"bare", "_x_ + 1", "get() + 1"},
+		{"attribute", "self._x_ * s._x_", "get() * get()"},
+		{"longer name", "_x_y_ + a_x_", "_x_y_ + a_x_"},
+		{"string", `"_x_ is here"`, `"_x_ is here"`},
+		{"string next to use", `_x_ + '_x_'`, `get() + '_x_'`},
+		{"comment", "_x_  # _x_", "get()  # _x_"},
+		{"f-string field", `f"_x_ is {_x_}"`, `f"_x_ is {get()}"`},
+		{"f-string conversion and spec", `f'{_x_!r:>{_x_}} {{_x_}}'`, `f'{get()!r:>{_x_}} {{_x_}}'`},
+		{"f-string nested string", `f"{d['_x_'] + _x_}"`, `f"{d['_x_'] + get()}"`},
+		{"triple quoted f-string", `f"""{_x_}"""`, `f"""{get()}"""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceVariable(tt.src, "_x_", "get()"); got != tt.want {
+				t.Errorf("replaceVariable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
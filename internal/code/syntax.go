@@ -0,0 +1,300 @@
+package code
+
+import (
+	"strings"
+)
+
+type nodeKind byte
+
+const (
+	ignoredNode nodeKind = iota
+	expressionNode
+	headerNode
+	assignmentNode
+	annotatedAssignmentNode
+	augmentedAssignmentNode
+)
+
+func (k nodeKind) String() string {
+	switch k {
+	case ignoredNode:
+		return "ignored"
+	case expressionNode:
+		return "expression"
+	case headerNode:
+		return "header"
+	case assignmentNode:
+		return "assignment"
+	case annotatedAssignmentNode:
+		return "annotated_assignment"
+	case augmentedAssignmentNode:
+		return "augmented_assignment"
+	default:
+		return ""
+	}
+}
+
+var (
+	headerKeywords      = []string{"if", "elif", "while", "for", "with"}
+	ignoredKeywords     = []string{"def", "class", "async", "try", "except", "finally", "else"}
+	declarationKeywords = []string{"import", "from", "global", "nonlocal"}
+	augmentedOps        = []string{"+=", "-=", "*=", "/=", "//=", "%=", "**=", "&=", "|=", "^=", "<<=", ">>=", "@="}
+)
+
+// node is a single simple statement or compound statement header.  Several
+// nodes may share a physical line (semicolons, inline suites) and a single
+// node may span several physical lines (brackets, backslash continuations).
+type node struct {
+	kind        nodeKind
+	line        int
+	column      int
+	endLine     int
+	endColumn   int
+	indentation string
+	code        string
+	target      string
+	annotation  string
+	operator    string
+	value       string
+	lValue      string
+	rValue      string
+	tokens      []token
+	valueTokens []token
+}
+
+type syntaxTree struct {
+	nodes    []*node
+	comments []token
+}
+
+func parseSyntax(src string) (*syntaxTree, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &syntaxTree{
+		nodes:    make([]*node, 0),
+		comments: make([]token, 0),
+	}
+	lines := strings.Split(src, "\n")
+
+	logicalLine := make([]token, 0)
+	for _, t := range tokens {
+		switch t.Type {
+		case commentToken:
+			tree.comments = append(tree.comments, t)
+		case nlToken:
+			continue
+		case newlineToken, endMarkerToken:
+			if len(logicalLine) > 0 {
+				indentation := getIndentation(lines[logicalLine[0].Line-1])
+				tree.nodes = append(tree.nodes, parseLogicalLine(src, logicalLine, indentation)...)
+				logicalLine = make([]token, 0)
+			}
+		default:
+			logicalLine = append(logicalLine, t)
+		}
+	}
+
+	return tree, nil
+}
+
+func parseLogicalLine(src string, tokens []token, indentation string) []*node {
+	nodes := make([]*node, 0)
+
+	for len(tokens) > 0 {
+		first := tokens[0]
+
+		if first.is(operatorToken, "@") {
+			nodes = append(nodes, newNode(src, ignoredNode, tokens, indentation))
+			return nodes
+		}
+
+		if first.Type == nameToken && (containsString(headerKeywords, first.Value) || containsString(ignoredKeywords, first.Value)) {
+			end := findHeaderEnd(tokens)
+			kind := headerNode
+			if containsString(ignoredKeywords, first.Value) {
+				kind = ignoredNode
+			}
+			nodes = append(nodes, newNode(src, kind, tokens[:end+1], indentation))
+			tokens = tokens[end+1:]
+			indentation = ""
+			continue
+		}
+
+		end := findAtDepthZero(tokens, ";")
+		if end < 0 {
+			end = len(tokens)
+		}
+		if end > 0 {
+			nodes = append(nodes, parseSimpleStatement(src, tokens[:end], indentation))
+		}
+		if end >= len(tokens) {
+			break
+		}
+		tokens = tokens[end+1:]
+		indentation = ""
+	}
+
+	return nodes
+}
+
+func parseSimpleStatement(src string, tokens []token, indentation string) *node {
+	first := tokens[0]
+	if first.Type == nameToken && containsString(declarationKeywords, first.Value) {
+		return newNode(src, ignoredNode, tokens, indentation)
+	}
+
+	allStrings := true
+	for _, t := range tokens {
+		if t.Type != stringToken {
+			allStrings = false
+			break
+		}
+	}
+	if allStrings {
+		return newNode(src, ignoredNode, tokens, indentation)
+	}
+
+	depth := 0
+	colon := -1
+	for i, t := range tokens {
+		if t.Type == nameToken && t.Value == "lambda" && depth == 0 {
+			break
+		}
+
+		if t.Type != operatorToken {
+			continue
+		}
+
+		switch {
+		case t.Value == "(" || t.Value == "[" || t.Value == "{":
+			depth++
+		case t.Value == ")" || t.Value == "]" || t.Value == "}":
+			depth--
+		case depth != 0:
+			continue
+		case t.Value == ":" && colon < 0:
+			colon = i
+		case containsString(augmentedOps, t.Value):
+			n := newNode(src, augmentedAssignmentNode, tokens, indentation)
+			n.setOperator(src, tokens, i)
+			return n
+		case t.Value == "=":
+			kind := assignmentNode
+			if colon >= 0 {
+				kind = annotatedAssignmentNode
+			}
+			n := newNode(src, kind, tokens, indentation)
+			n.setOperator(src, tokens, i)
+			if colon >= 0 {
+				n.target = src[tokens[0].start:tokens[colon-1].end]
+				n.annotation = joinTokens(tokens[colon+1 : i])
+			}
+			return n
+		}
+	}
+
+	if colon > 0 {
+		n := newNode(src, annotatedAssignmentNode, tokens, indentation)
+		n.target = src[tokens[0].start:tokens[colon-1].end]
+		n.annotation = joinTokens(tokens[colon+1:])
+		return n
+	}
+
+	return newNode(src, expressionNode, tokens, indentation)
+}
+
+func newNode(src string, kind nodeKind, tokens []token, indentation string) *node {
+	first, last := tokens[0], tokens[len(tokens)-1]
+	code := src[first.start:last.end]
+
+	return &node{
+		kind:        kind,
+		line:        first.Line,
+		column:      first.Column,
+		endLine:     last.EndLine,
+		endColumn:   last.EndColumn,
+		indentation: indentation,
+		code:        code,
+		rValue:      code,
+		value:       code,
+		tokens:      tokens,
+	}
+}
+
+func (n *node) setOperator(src string, tokens []token, index int) {
+	op := tokens[index]
+	n.operator = op.Value
+	n.lValue = src[tokens[0].start : op.end-1]
+	n.rValue = src[op.end:tokens[len(tokens)-1].end]
+	n.value = strings.TrimSpace(n.rValue)
+	n.valueTokens = tokens[index+1:]
+	if index > 0 {
+		n.target = src[tokens[0].start:tokens[index-1].end]
+	}
+}
+
+// findHeaderEnd returns the index of the colon terminating a compound
+// statement header, skipping the colons belonging to lambdas.
+func findHeaderEnd(tokens []token) int {
+	depth := 0
+	lambdas := 0
+	for i, t := range tokens {
+		switch {
+		case t.Type == nameToken && t.Value == "lambda" && depth == 0:
+			lambdas++
+		case t.Type != operatorToken:
+			continue
+		case t.Value == "(" || t.Value == "[" || t.Value == "{":
+			depth++
+		case t.Value == ")" || t.Value == "]" || t.Value == "}":
+			depth--
+		case t.Value == ":" && depth == 0:
+			if lambdas > 0 {
+				lambdas--
+				continue
+			}
+			return i
+		}
+	}
+	return len(tokens) - 1
+}
+
+func findAtDepthZero(tokens []token, op string) int {
+	depth := 0
+	for i, t := range tokens {
+		if t.Type != operatorToken {
+			continue
+		}
+		switch t.Value {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case op:
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func joinTokens(tokens []token) string {
+	sb := strings.Builder{}
+	for _, t := range tokens {
+		sb.WriteString(t.Value)
+	}
+	return sb.String()
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
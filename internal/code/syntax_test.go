@@ -0,0 +1,129 @@
+package code
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSyntax(t *testing.T) {
+	type nd struct {
+		kind       nodeKind
+		line       int
+		endLine    int
+		target     string
+		annotation string
+		operator   string
+		value      string
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want []nd
+	}{
+		{
+			name: "string containing equals",
+			src:  "_x_: str = 'a=b'\n",
+			want: []nd{{annotatedAssignmentNode, 1, 1, "_x_", "str", "=", "'a=b'"}},
+		},
+		{
+			name: "statements after semicolon",
+			src:  "a = 1; b += 2; f()\n",
+			want: []nd{
+				{assignmentNode, 1, 1, "a", "", "=", "1"},
+				{augmentedAssignmentNode, 1, 1, "b", "", "+=", "2"},
+				{expressionNode, 1, 1, "", "", "", "f()"},
+			},
+		},
+		{
+			name: "backslash continuation",
+			src:  "_x_: int = 1 + \\\n    2\n",
+			want: []nd{{annotatedAssignmentNode, 1, 2, "_x_", "int", "=", "1 + \\\n    2"}},
+		},
+		{
+			name: "multi-line brackets",
+			src:  "_d_: dict = {\n    'a': 1,\n}\nx = 2\n",
+			want: []nd{
+				{annotatedAssignmentNode, 1, 3, "_d_", "dict", "=", "{\n    'a': 1,\n}"},
+				{assignmentNode, 4, 4, "x", "", "=", "2"},
+			},
+		},
+		{
+			name: "lambda default",
+			src:  "_sync_x_: callable = lambda n=8: ()\n",
+			want: []nd{{annotatedAssignmentNode, 1, 1, "_sync_x_", "callable", "=", "lambda n=8: ()"}},
+		},
+		{
+			name: "inline suite",
+			src:  "if a: b = 1\n",
+			want: []nd{
+				{headerNode, 1, 1, "", "", "", "if a:"},
+				{assignmentNode, 1, 1, "b", "", "=", "1"},
+			},
+		},
+		{
+			name: "keyword argument",
+			src:  "f(a=1)\n",
+			want: []nd{{expressionNode, 1, 1, "", "", "", "f(a=1)"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := parseSyntax(tt.src)
+			if err != nil {
+				t.Fatalf("parseSyntax() error = %v", err)
+			}
+
+			got := make([]nd, 0, len(tree.nodes))
+			for _, n := range tree.nodes {
+				got = append(got, nd{n.kind, n.line, n.endLine, n.target, n.annotation, n.operator, n.value})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSyntax() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDefaultValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		dataType string
+		src      string
+		want     any
+		wantErr  bool
+	}{
+		{"callable without default", "callable", "lambda: ()", "lambda: ()", false},
+		{"callable default", "callable", "lambda n=8: ()", "8", false},
+		{"callable default after self", "callable", "lambda self, n=_node_count_: ()", "_node_count_", false},
+		{"callable default containing equals", "callable", "lambda n=f(a=1): ()", "f(a=1)", false},
+		{"str containing equals", "str", "'a=b'", "a=b", false},
+		{"str with escaped quote", "str", `'it\'s'`, "it's", false},
+		{"str with escapes", "str", `"a\tb\n\x41é\101"`, "a\tb\nAéA", false},
+		{"raw str", "str", `r'a\nb'`, `a\nb`, false},
+		{"concatenated str", "str", `'a' "b"`, "ab", false},
+		{"empty str call", "str", "str()", "", false},
+		{"str expression", "str", "'a' * 3", nil, true},
+		{"bytes with escapes", "bytes", `b'\x00\'a'`, []byte{0, '\'', 'a'}, false},
+		{"bytes without unicode escapes", "bytes", `b'\u00e9'`, []byte(`\u00e9`), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := parseSyntax("_x_ = " + tt.src + "\n")
+			if err != nil {
+				t.Fatalf("parseSyntax() error = %v", err)
+			}
+
+			n := tree.nodes[0]
+			got, err := getDefaultValue(tt.dataType, n.rValue, n.valueTokens)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getDefaultValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getDefaultValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
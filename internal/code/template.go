@@ -6,17 +6,12 @@ package code
 
 const boolSetFuncTemplate = `
 def gothon_{{var_id}}_set(val: bool) -> bool:
-    if val:
-        _sock_{{var_id}}_set_in.send((1).to_bytes(1, 'big'))
-    else:
-        _sock_{{var_id}}_set_in.send((0).to_bytes(1, 'big'))
-    _sock_{{var_id}}_set_out.recvfrom(1)
+    _gothon_connection.request({{request}}, (1 if val else 0).to_bytes(1, 'big'))
     return val`
 
 const boolGetFuncTemplate = `
 def gothon_{{var_id}}_get() -> bool:
-    _sock_{{var_id}}_get_in.send((22).to_bytes(1, 'big'))
-    val_bytes, _ = _sock_{{var_id}}_get_out.recvfrom(1)
+    val_bytes = _gothon_connection.request({{request}}, b'')
     return val_bytes[0] != 0`
 
 /*******************************************************************************
@@ -25,78 +20,73 @@ def gothon_{{var_id}}_get() -> bool:
 
 const intSetFuncTemplate = `
 def gothon_{{var_id}}_set(val: int) -> int:
-    _sock_{{var_id}}_set_in.send(val.to_bytes(8, 'big', signed=True))
-    _sock_{{var_id}}_set_out.recvfrom(1)
+    _gothon_connection.request({{request}}, val.to_bytes(8, 'big', signed=True))
     return val`
 
 const intGetFuncTemplate = `
 def gothon_{{var_id}}_get() -> int:
-    _sock_{{var_id}}_get_in.send((22).to_bytes(1, 'big'))
-    val_bytes, _ = _sock_{{var_id}}_get_out.recvfrom(8)
+    val_bytes = _gothon_connection.request({{request}}, b'')
     return int.from_bytes(val_bytes, 'big', signed=True)`
 
 const intAddFuncTemplate = `
 def gothon_{{var_id}}_add(delta: int):
-    _sock_{{var_id}}_add_in.send(delta.to_bytes(8, 'big', signed=True))
-    _sock_{{var_id}}_add_out.recvfrom(1)
+    _gothon_connection.request({{request}}, delta.to_bytes(8, 'big', signed=True))
     return delta`
 
 const intSubFuncTemplate = `
 def gothon_{{var_id}}_sub(delta: int):
-    _sock_{{var_id}}_sub_in.send(delta.to_bytes(8, 'big', signed=True))
-    _sock_{{var_id}}_sub_out.recvfrom(1)
+    _gothon_connection.request({{request}}, delta.to_bytes(8, 'big', signed=True))
     return delta`
 
 const intMulFuncTemplate = `
 def gothon_{{var_id}}_mul(multiplier: int):
-    _sock_{{var_id}}_mul_in.send(multiplier.to_bytes(8, 'big', signed=True))
-    _sock_{{var_id}}_mul_out.recvfrom(1)
+    _gothon_connection.request({{request}}, multiplier.to_bytes(8, 'big', signed=True))
     return multiplier`
 
 const intDivFuncTemplate = `
 def gothon_{{var_id}}_div(divisor: int):
-    _sock_{{var_id}}_div_in.send(divisor.to_bytes(8, 'big', signed=True))
-    _sock_{{var_id}}_div_out.recvfrom(1)
+    _gothon_connection.request({{request}}, divisor.to_bytes(8, 'big', signed=True))
     return divisor`
 
+const intOperatorFuncTemplate = `
+def gothon_{{var_id}}_{{action}}(operand: int):
+    status, _ = _gothon_connection.request_status({{request}}, operand.to_bytes(8, 'big', signed=True))
+    if status != 0:
+        raise ArithmeticError(f'invalid operand for {{action}}: {operand}')
+    return operand`
+
 /*******************************************************************************
  float
 *******************************************************************************/
 
 const floatSetFuncTemplate = `
 def gothon_{{var_id}}_set(val: float) -> float:
-    _sock_{{var_id}}_set_in.send(struct.pack('<d', val))
-    _sock_{{var_id}}_set_out.recvfrom(1)
+    _gothon_connection.request({{request}}, struct.pack('>d', val))
     return val`
 
 const floatGetFuncTemplate = `
 def gothon_{{var_id}}_get() -> float:
-    _sock_{{var_id}}_get_in.send((22).to_bytes(1, 'little'))
-    val_bytes, _ = _sock_{{var_id}}_get_out.recvfrom(8)
-    return struct.unpack_from('<d', val_bytes, 0)[0]`
+    val_bytes = _gothon_connection.request({{request}}, b'')
+    return struct.unpack_from('>d', val_bytes, 0)[0]`
 
 const floatAddFuncTemplate = `
 def gothon_{{var_id}}_add(delta: float):
-    _sock_{{var_id}}_add_in.send(bytearray(struct.pack('<d', delta)))
-    _sock_{{var_id}}_add_out.recvfrom(1)
+    _gothon_connection.request({{request}}, bytearray(struct.pack('>d', delta)))
     return delta`
 
 const floatSubFuncTemplate = `
 def gothon_{{var_id}}_sub(delta: float):
-    _sock_{{var_id}}_sub_in.send(bytearray(struct.pack('<d', delta)))
-    _sock_{{var_id}}_sub_out.recvfrom(1)
+    _gothon_connection.request({{request}}, bytearray(struct.pack('>d', delta)))
     return delta`
 
 const floatMulFuncTemplate = `
 def gothon_{{var_id}}_mul(multiplier: float):
-    _sock_{{var_id}}_mul_in.send(bytearray(struct.pack('<d', multiplier)))
-    _sock_{{var_id}}_mul_out.recvfrom(1)
+    _gothon_connection.request({{request}}, bytearray(struct.pack('>d', multiplier)))
     return multiplier`
 
 const floatDivFuncTemplate = `
 def gothon_{{var_id}}_div(divisor: float):
-    _sock_{{var_id}}_div_in.send(bytearray(struct.pack('<d', divisor)))
-    _sock_{{var_id}}_div_out.recvfrom(1)
+    _gothon_connection.request({{request}}, bytearray(struct.pack('>d', divisor)))
     return divisor`
 
 /*******************************************************************************
@@ -105,36 +95,109 @@ def gothon_{{var_id}}_div(divisor: float):
 
 const stringSetFuncTemplate = `
 def gothon_{{var_id}}_set(val: str) -> str:
-    _sock_{{var_id}}_set_in.send(bytes(val, 'utf-8'))
-    _sock_{{var_id}}_set_out.recvfrom(1)
+    _gothon_connection.request({{request}}, bytes(val, 'utf-8'))
     return val`
 
 const stringGetFuncTemplate = `
 def gothon_{{var_id}}_get() -> str:
-    _sock_{{var_id}}_get_in.send((22).to_bytes(1, 'big'))
-    val_bytes, _ = _sock_{{var_id}}_get_out.recvfrom({{str_max_size}})
+    val_bytes = _gothon_connection.request({{request}}, b'')
     return str(val_bytes, 'utf-8')`
 
 const stringAddFuncTemplate = `
 def gothon_{{var_id}}_add(suffix: str):
-    _sock_{{var_id}}_add_in.send(bytes(suffix, 'utf-8'))
-    _sock_{{var_id}}_add_out.recvfrom(1)
+    _gothon_connection.request({{request}}, bytes(suffix, 'utf-8'))
     return suffix`
 
 const stringSubFuncTemplate = `
 def gothon_{{var_id}}_sub(suffix: str):
-    _sock_{{var_id}}_sub_in.send(bytes(suffix, 'utf-8'))
-    _sock_{{var_id}}_sub_out.recvfrom(1)
+    _gothon_connection.request({{request}}, bytes(suffix, 'utf-8'))
+    return suffix`
+
+/*******************************************************************************
+ bytes
+*******************************************************************************/
+
+const bytesSetFuncTemplate = `
+def gothon_{{var_id}}_set(val: bytes) -> bytes:
+    status, _ = _gothon_connection.request_status({{request}}, len(val).to_bytes(4, 'big') + val)
+    if status != 0:
+        raise BufferError('bytes value exceeds the maximum size')
+    return val`
+
+const bytesGetFuncTemplate = `
+def gothon_{{var_id}}_get() -> bytes:
+    val_bytes = _gothon_connection.request({{request}}, b'')
+    return bytes(val_bytes[4:4 + int.from_bytes(val_bytes[:4], 'big')])`
+
+const bytesAddFuncTemplate = `
+def gothon_{{var_id}}_add(suffix: bytes):
+    status, _ = _gothon_connection.request_status({{request}}, len(suffix).to_bytes(4, 'big') + suffix)
+    if status != 0:
+        raise BufferError('bytes value exceeds the maximum size')
     return suffix`
 
+/*******************************************************************************
+ object
+*******************************************************************************/
+
+const objectSetFuncTemplate = `
+def gothon_{{var_id}}_set(val: object, init: bool = False) -> object:
+    val_bytes = pickle.dumps(val)
+    status, _ = _gothon_connection.request_status({{request}}, int(init).to_bytes(1, 'big') + len(val_bytes).to_bytes(4, 'big') + val_bytes)
+    if status != 0:
+        raise BufferError('pickled object exceeds the maximum size')
+    return val`
+
+const objectGetFuncTemplate = `
+def gothon_{{var_id}}_get() -> object:
+    val_bytes = _gothon_connection.request({{request}}, b'')
+    length = int.from_bytes(val_bytes[:4], 'big')
+    if length == 0:
+        return None
+    return pickle.loads(val_bytes[4:4 + length])`
+
+/*******************************************************************************
+ atomic
+*******************************************************************************/
+
+const atomicExchangeFuncTemplate = `
+def gothon_{{var_id}}_{{action}}(val: {{value_type}}) -> {{value_type}}:
+    val_bytes = _gothon_connection.request({{request}}, {{value_encode}})
+    return {{value_decode}}`
+
+const atomicExtremumFuncTemplate = `
+def gothon_{{var_id}}_{{action}}(val: {{value_type}}) -> {{value_type}}:
+    val_bytes = _gothon_connection.request({{request}}, {{value_encode}})
+    return {{action}}({{value_decode}}, val)`
+
+const atomicCompareAndSwapFuncTemplate = `
+def gothon_{{var_id}}_cas(expected: {{value_type}}, val: {{value_type}}) -> bool:
+    expected_bytes = {{expected_encode}}
+    swapped = _gothon_connection.request({{request}}, len(expected_bytes).to_bytes(4, 'big') + expected_bytes + {{value_encode}})
+    return swapped[0] != 0`
+
 /*******************************************************************************
  mutex
 *******************************************************************************/
 
 const mutexFuncTemplate = `
 def gothon_{{var_id}}():
-    _sock_{{var_id}}_in.send((22).to_bytes(1, 'big'))
-    _sock_{{var_id}}_out.recvfrom(1)`
+    _gothon_connection.request({{request}}, b'')`
+
+/*******************************************************************************
+ semaphore
+*******************************************************************************/
+
+const semaphoreAcquireFuncTemplate = `
+def gothon_{{var_id}}(blocking: bool = True) -> bool:
+    status, _ = _gothon_connection.request_status({{request}}, (1 if blocking else 0).to_bytes(1, 'big'))
+    return status == 0`
+
+const semaphoreReleaseFuncTemplate = `
+def gothon_{{var_id}}():
+    status, _ = _gothon_connection.request_status({{request}}, b'')
+    if status != 0:
+        raise ValueError('semaphore released too many times')`
 
 /*******************************************************************************
  sync
@@ -142,8 +205,67 @@ def gothon_{{var_id}}():
 
 const syncFuncTemplate = `
 def gothon_{{var_id}}(n: int = 0):
-    _sock_{{var_id}}_in.send(n.to_bytes(4, 'big'))
-    _sock_{{var_id}}_out.recvfrom(1)`
+    _gothon_connection.request({{request}}, n.to_bytes(4, 'big'))`
+
+/*******************************************************************************
+ barrier
+*******************************************************************************/
+
+const barrierFuncTemplate = `
+def gothon_{{var_id}}() -> int:
+    index = _gothon_connection.request({{request}}, b'')
+    return int.from_bytes(index, 'big')`
+
+/*******************************************************************************
+ batch
+*******************************************************************************/
+
+const batchFuncTemplate = `
+def gothon_{{var_id}}() -> _GothonBatch:
+    return _GothonBatch({{request}})`
+
+/*******************************************************************************
+ transaction
+*******************************************************************************/
+
+const transactionFuncTemplate = `
+def gothon_{{var_id}}() -> _GothonTransaction:
+    return _GothonTransaction({{request}})`
+
+/*******************************************************************************
+ event
+*******************************************************************************/
+
+const eventSetFuncTemplate = `
+def gothon_{{var_id}}_{{action}}():
+    _gothon_connection.request({{request}}, b'')`
+
+const eventIsSetFuncTemplate = `
+def gothon_{{var_id}}_isset() -> bool:
+    val_bytes = _gothon_connection.request({{request}}, b'')
+    return val_bytes[0] != 0`
+
+const eventWaitFuncTemplate = `
+def gothon_{{var_id}}_wait(timeout: float = None) -> bool:
+    status, _ = _gothon_connection.request_status({{request}}, struct.pack('>d', -1.0 if timeout is None else timeout))
+    return status == 0`
+
+/*******************************************************************************
+ condition
+*******************************************************************************/
+
+const conditionWaitFuncTemplate = `
+def gothon_{{var_id}}_wait(timeout: float = None) -> bool:
+    status, _ = _gothon_connection.request_status({{request}}, struct.pack('>d', -1.0 if timeout is None else timeout))
+    return status == 0`
+
+const conditionNotifyFuncTemplate = `
+def gothon_{{var_id}}_notify(n: int = 1):
+    _gothon_connection.request({{request}}, n.to_bytes(4, 'big'))`
+
+const conditionNotifyAllFuncTemplate = `
+def gothon_{{var_id}}_notifyall():
+    _gothon_connection.request({{request}}, b'')`
 
 /*******************************************************************************
  queue
@@ -151,41 +273,48 @@ def gothon_{{var_id}}(n: int = 0):
 
 const queueSizeFuncTemplate = `
 def gothon_{{var_id}}_size() -> int:
-    _sock_{{var_id}}_size_in.send((22).to_bytes(1, 'big'))
-    val_bytes, _ = _sock_{{var_id}}_size_out.recvfrom(8)
+    val_bytes = _gothon_connection.request({{request}}, b'')
     return int.from_bytes(val_bytes, 'big', signed=False)`
 
 const queueEmptyFuncTemplate = `
 def gothon_{{var_id}}_empty() -> bool:
-    _sock_{{var_id}}_empty_in.send((22).to_bytes(1, 'big'))
-    val_bytes, _ = _sock_{{var_id}}_empty_out.recvfrom(1)
+    val_bytes = _gothon_connection.request({{request}}, b'')
     return val_bytes[0] != 0`
 
 const queueFullFuncTemplate = `
 def gothon_{{var_id}}_full() -> bool:
-    _sock_{{var_id}}_full_in.send((22).to_bytes(1, 'big'))
-    val_bytes, _ = _sock_{{var_id}}_full_out.recvfrom(1)
+    val_bytes = _gothon_connection.request({{request}}, b'')
     return val_bytes[0] != 0`
 
+const queueTaskDoneFuncTemplate = `
+def gothon_{{var_id}}_taskdone():
+    status, _ = _gothon_connection.request_status({{request}}, b'')
+    if status != 0:
+        raise ValueError('task_done() called too many times')`
+
+const queueJoinFuncTemplate = `
+def gothon_{{var_id}}_join():
+    _gothon_connection.request({{request}}, b'')`
+
 /*******************************************************************************
  bool queue
 *******************************************************************************/
 
 const boolQueueSetFuncTemplate = `
-def gothon_{{var_id}}_set(val: bool) -> (bool, bool):
-    if val:
-        _sock_{{var_id}}_set_in.send((1).to_bytes(1, 'big'))
-    else:
-        _sock_{{var_id}}_set_in.send((0).to_bytes(1, 'big'))
-    ok, _ = _sock_{{var_id}}_set_out.recvfrom(1)
-    return val, ok[0] == 22`
+def gothon_{{var_id}}_set(val: bool, block: bool = False, timeout: float = None) -> (bool, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    status, _ = _gothon_connection.request_status({{request}}, request + (1 if val else 0).to_bytes(1, 'big'))
+    return val, status == 0`
 
 const boolQueueGetFuncTemplate = `
-def gothon_{{var_id}}_get() -> (bool, bool):
-    _sock_{{var_id}}_get_in.send((22).to_bytes(1, 'big'))
-    ok, _ = _sock_{{var_id}}_get_ok.recvfrom(1)
-    if ok[0] == 22:
-        val_bytes, _ = _sock_{{var_id}}_get_out.recvfrom(1)
+def gothon_{{var_id}}_get(block: bool = False, timeout: float = None) -> (bool, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    status, val_bytes = _gothon_connection.request_status({{request}}, request)
+    if status == 0:
         return val_bytes[0] != 0, True
     else:
         return False, False`
@@ -195,17 +324,20 @@ def gothon_{{var_id}}_get() -> (bool, bool):
 *******************************************************************************/
 
 const intQueueSetFuncTemplate = `
-def gothon_{{var_id}}_set(val: int) -> (int, bool):
-    _sock_{{var_id}}_set_in.send(val.to_bytes(8, 'big', signed=True))
-    ok, _ = _sock_{{var_id}}_set_out.recvfrom(1)
-    return val, ok[0] == 22`
+def gothon_{{var_id}}_set(val: int, block: bool = False, timeout: float = None) -> (int, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    status, _ = _gothon_connection.request_status({{request}}, request + val.to_bytes(8, 'big', signed=True))
+    return val, status == 0`
 
 const intQueueGetFuncTemplate = `
-def gothon_{{var_id}}_get() -> (int, bool):
-    _sock_{{var_id}}_get_in.send((22).to_bytes(1, 'big'))
-    ok, _ = _sock_{{var_id}}_get_ok.recvfrom(1)
-    if ok[0] == 22:
-        val_bytes, _ = _sock_{{var_id}}_get_out.recvfrom(8)
+def gothon_{{var_id}}_get(block: bool = False, timeout: float = None) -> (int, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    status, val_bytes = _gothon_connection.request_status({{request}}, request)
+    if status == 0:
         return int.from_bytes(val_bytes, 'big', signed=True), True
     else:
         return 0, False`
@@ -215,18 +347,21 @@ def gothon_{{var_id}}_get() -> (int, bool):
 *******************************************************************************/
 
 const floatQueueSetFuncTemplate = `
-def gothon_{{var_id}}_set(val: float) -> (float, bool):
-    _sock_{{var_id}}_set_in.send(struct.pack('<d', val))
-    ok, _ = _sock_{{var_id}}_set_out.recvfrom(1)
-    return val, ok[0] == 22`
+def gothon_{{var_id}}_set(val: float, block: bool = False, timeout: float = None) -> (float, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    status, _ = _gothon_connection.request_status({{request}}, request + struct.pack('>d', val))
+    return val, status == 0`
 
 const floatQueueGetFuncTemplate = `
-def gothon_{{var_id}}_get() -> (float, bool):
-    _sock_{{var_id}}_get_in.send((22).to_bytes(1, 'little'))
-    ok, _ = _sock_{{var_id}}_get_ok.recvfrom(1)
-    if ok[0] == 22:
-        val_bytes, _ = _sock_{{var_id}}_get_out.recvfrom(8)
-        return struct.unpack_from('<d', val_bytes, 0)[0], True
+def gothon_{{var_id}}_get(block: bool = False, timeout: float = None) -> (float, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    status, val_bytes = _gothon_connection.request_status({{request}}, request)
+    if status == 0:
+        return struct.unpack_from('>d', val_bytes, 0)[0], True
     else:
         return 0, False`
 
@@ -235,74 +370,813 @@ def gothon_{{var_id}}_get() -> (float, bool):
 *******************************************************************************/
 
 const stringQueueSetFuncTemplate = `
-def gothon_{{var_id}}_set(val: str) -> (str, bool):
-    _sock_{{var_id}}_set_in.send(bytes(val, 'utf-8'))
-    ok, _ = _sock_{{var_id}}_set_out.recvfrom(1)
-    return val, ok[0] == 22`
+def gothon_{{var_id}}_set(val: str, block: bool = False, timeout: float = None) -> (str, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    status, _ = _gothon_connection.request_status({{request}}, request + bytes(val, 'utf-8'))
+    return val, status == 0`
 
 const stringQueueGetFuncTemplate = `
-def gothon_{{var_id}}_get() -> (str, bool):
-    _sock_{{var_id}}_get_in.send((22).to_bytes(1, 'big'))
-    ok, _ = _sock_{{var_id}}_get_ok.recvfrom(1)
-    if ok[0] == 22:
-        val_bytes, _ = _sock_{{var_id}}_get_out.recvfrom({{str_max_size}})
+def gothon_{{var_id}}_get(block: bool = False, timeout: float = None) -> (str, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    status, val_bytes = _gothon_connection.request_status({{request}}, request)
+    if status == 0:
         return str(val_bytes, 'utf-8'), True
     else:
-        "", False`
+        return "", False`
+
+/*******************************************************************************
+ bytes queue
+*******************************************************************************/
+
+const bytesQueueSetFuncTemplate = `
+def gothon_{{var_id}}_set(val: bytes, block: bool = False, timeout: float = None) -> (bytes, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    status, _ = _gothon_connection.request_status({{request}}, request + len(val).to_bytes(4, 'big') + val)
+    return val, status == 0`
+
+const bytesQueueGetFuncTemplate = `
+def gothon_{{var_id}}_get(block: bool = False, timeout: float = None) -> (bytes, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    status, val_bytes = _gothon_connection.request_status({{request}}, request)
+    if status == 0:
+        return bytes(val_bytes[4:4 + int.from_bytes(val_bytes[:4], 'big')]), True
+    else:
+        return b'', False`
+
+/*******************************************************************************
+ object queue
+*******************************************************************************/
+
+const objectQueueSetFuncTemplate = `
+def gothon_{{var_id}}_set(val: object, block: bool = False, timeout: float = None) -> (object, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    val_bytes = pickle.dumps(val)
+    status, _ = _gothon_connection.request_status({{request}}, request + len(val_bytes).to_bytes(4, 'big') + val_bytes)
+    return val, status == 0`
+
+const objectQueueGetFuncTemplate = `
+def gothon_{{var_id}}_get(block: bool = False, timeout: float = None) -> (object, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    request = (1 if block else 0).to_bytes(1, 'big') + struct.pack('>d', -1 if timeout is None else timeout)
+    status, val_bytes = _gothon_connection.request_status({{request}}, request)
+    if status == 0:
+        return pickle.loads(val_bytes[4:4 + int.from_bytes(val_bytes[:4], 'big')]), True
+    else:
+        return None, False`
+
+/*******************************************************************************
+ chan
+*******************************************************************************/
+
+const chanSendFuncTemplate = `
+def gothon_{{var_id}}_send(val: {{value_type}}, timeout: float = None) -> bool:
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    val_bytes = {{value_encode}}
+    if len(val_bytes) > {{str_max_size}}:
+        raise BufferError('value exceeds the maximum size')
+    status, _ = _gothon_connection.request_status({{request}}, struct.pack('>d', -1 if timeout is None else timeout) + val_bytes)
+    if status == _GOTHON_STATUS_CLOSED:
+        raise ValueError('send on closed channel')
+    return status == 0`
+
+const chanRecvFuncTemplate = `
+def gothon_{{var_id}}_recv(timeout: float = None) -> ({{value_type}}, bool):
+    if timeout is not None and timeout < 0:
+        raise ValueError("'timeout' must be a non-negative number")
+    status, val_bytes = _gothon_connection.request_status({{request}}, struct.pack('>d', -1 if timeout is None else timeout))
+    if status != 0:
+        return {{value_zero}}, False
+    return {{value_decode}}, True`
+
+const chanCloseFuncTemplate = `
+def gothon_{{var_id}}_close():
+    status, _ = _gothon_connection.request_status({{request}}, b'')
+    if status != 0:
+        raise ValueError('close of closed channel')`
+
+const chanIterFuncTemplate = `
+def gothon_{{var_id}}_iter():
+    while True:
+        status, val_bytes = _gothon_connection.request_status({{request}}, struct.pack('>d', -1))
+        if status != 0:
+            return
+        yield {{value_decode}}`
+
+/*******************************************************************************
+ dict
+*******************************************************************************/
+
+const dictSetFuncTemplate = `
+def gothon_{{var_id}}_set(key: {{key_type}}, val: {{value_type}}) -> {{value_type}}:
+    key_bytes = {{key_encode}}
+    _gothon_connection.request({{request}}, len(key_bytes).to_bytes(4, 'big') + key_bytes + {{value_encode}})
+    return val`
+
+const dictGetFuncTemplate = `
+def gothon_{{var_id}}_get(key: {{key_type}}, *default) -> {{value_type}}:
+    status, val_bytes = _gothon_connection.request_status({{request}}, {{key_encode}})
+    if status == 0:
+        return {{value_decode}}
+    if len(default) > 0:
+        return default[0]
+    raise KeyError(key)`
+
+const dictAddFuncTemplate = `
+def gothon_{{var_id}}_add(key: {{key_type}}, val: {{value_type}}):
+    key_bytes = {{key_encode}}
+    status, _ = _gothon_connection.request_status({{request}}, len(key_bytes).to_bytes(4, 'big') + key_bytes + {{value_encode}})
+    if status != 0:
+        raise TypeError('unsupported operand type(s) for +=')
+    return val`
+
+const dictDelFuncTemplate = `
+def gothon_{{var_id}}_del(key: {{key_type}}):
+    status, _ = _gothon_connection.request_status({{request}}, {{key_encode}})
+    if status != 0:
+        raise KeyError(key)`
+
+const dictContainsFuncTemplate = `
+def gothon_{{var_id}}_contains(key: {{key_type}}) -> bool:
+    val_bytes = _gothon_connection.request({{request}}, {{key_encode}})
+    return val_bytes[0] != 0`
+
+const dictKeysFuncTemplate = `
+def gothon_{{var_id}}_keys() -> list:
+    keys_bytes = _gothon_connection.request({{request}}, b'')
+    keys = []
+    i = 0
+    while i < len(keys_bytes):
+        size = int.from_bytes(keys_bytes[i:i + 4], 'big')
+        val_bytes = keys_bytes[i + 4:i + 4 + size]
+        keys.append({{key_decode}})
+        i += 4 + size
+    return keys`
+
+/*******************************************************************************
+ list
+*******************************************************************************/
+
+const listSetFuncTemplate = `
+def gothon_{{var_id}}_set(index: int, val: {{value_type}}) -> {{value_type}}:
+    status, _ = _gothon_connection.request_status({{request}}, index.to_bytes(8, 'big', signed=True) + {{value_encode}})
+    if status != 0:
+        raise IndexError('list assignment index out of range')
+    return val`
+
+const listGetFuncTemplate = `
+def gothon_{{var_id}}_get(index: int) -> {{value_type}}:
+    status, val_bytes = _gothon_connection.request_status({{request}}, index.to_bytes(8, 'big', signed=True))
+    if status != 0:
+        raise IndexError('list index out of range')
+    return {{value_decode}}`
+
+const listAppendFuncTemplate = `
+def gothon_{{var_id}}_append(val: {{value_type}}):
+    _gothon_connection.request({{request}}, {{value_encode}})`
+
+const listSliceFuncTemplate = `
+def gothon_{{var_id}}_slice(start: int = None, stop: int = None, step: int = None) -> list:
+    if step is not None and step != 1:
+        return gothon_{{var_id}}_slice()[start:stop:step]
+    bounds = b''
+    for bound in (start, stop):
+        if bound is None:
+            bounds += (0).to_bytes(9, 'big')
+        else:
+            bounds += (1).to_bytes(1, 'big') + bound.to_bytes(8, 'big', signed=True)
+    items_bytes = _gothon_connection.request({{request}}, bounds)
+    items = []
+    i = 0
+    while i < len(items_bytes):
+        size = int.from_bytes(items_bytes[i:i + 4], 'big')
+        val_bytes = items_bytes[i + 4:i + 4 + size]
+        items.append({{value_decode}})
+        i += 4 + size
+    return items`
+
+/*******************************************************************************
+ set
+*******************************************************************************/
+
+const setAddFuncTemplate = `
+def gothon_{{var_id}}_add(key: {{key_type}}) -> bool:
+    added = _gothon_connection.request({{request}}, {{key_encode}})
+    return added[0] != 0`
+
+const setDelFuncTemplate = `
+def gothon_{{var_id}}_del(key: {{key_type}}, strict: bool = False) -> bool:
+    removed = _gothon_connection.request({{request}}, {{key_encode}})
+    if removed[0] == 0 and strict:
+        raise KeyError(key)
+    return removed[0] != 0`
+
+/*******************************************************************************
+ shared memory
+*******************************************************************************/
+
+const sharedMemorySetFuncTemplate = `
+def gothon_{{var_id}}_set(val: {{value_type}}) -> {{value_type}}:
+    _shm_{{var_id}}.update(lambda _: val)
+    return val`
+
+const sharedMemoryGetFuncTemplate = `
+def gothon_{{var_id}}_get() -> {{value_type}}:
+    return _shm_{{var_id}}.get()`
+
+const sharedMemoryIntArithmeticFuncTemplate = `
+def gothon_{{var_id}}_{{action}}(operand: int):
+    _shm_{{var_id}}.update(lambda current: _gothon_int_operator('{{action}}', current, operand))
+    return operand`
+
+const sharedMemoryFloatArithmeticFuncTemplate = `
+def gothon_{{var_id}}_{{action}}(operand: float):
+    _shm_{{var_id}}.update(lambda current: _gothon_float_operator('{{action}}', current, operand))
+    return operand`
+
+const sharedMemoryFetchAddFuncTemplate = `
+def gothon_{{var_id}}_fetch_add(val: {{value_type}}) -> {{value_type}}:
+    return _shm_{{var_id}}.update(lambda current: _gothon_{{value_type}}_operator('add', current, val))[0]`
+
+const sharedMemorySwapFuncTemplate = `
+def gothon_{{var_id}}_swap(val: {{value_type}}) -> {{value_type}}:
+    return _shm_{{var_id}}.update(lambda _: val)[0]`
+
+const sharedMemoryCompareAndSwapFuncTemplate = `
+def gothon_{{var_id}}_cas(expected: {{value_type}}, val: {{value_type}}) -> bool:
+    old, _ = _shm_{{var_id}}.update(lambda current: val if current == expected else current)
+    return old == expected`
+
+const sharedMemoryExtremumFuncTemplate = `
+def gothon_{{var_id}}_{{action}}(val: {{value_type}}) -> {{value_type}}:
+    return _shm_{{var_id}}.update(lambda current: {{action}}(current, val))[1]`
+
+// sharedMemoryTemplate is the part of the socket module that maps the shared
+// memory file, written only if there are variables kept in it.  Each slot holds
+// a sequence number followed by the value (both big-endian), where the sequence
+// number is odd while the value is being written so that reading it doesn't
+// need a lock: the value read is only used if the sequence number was even and
+// unchanged after reading it.  Writes are serialized by a lock on the slot's
+// byte range in the file (as Python has no atomic instructions to build a futex
+// on), which the kernel releases should the node die while holding it.
+const sharedMemoryTemplate = `
+_GOTHON_SHARED_MEMORY_SLOT_SIZE = {{slot_size}}
+_GOTHON_SHARED_MEMORY_SEQUENCE = struct.Struct('>Q')
+
+_gothon_shared_memory_file = open('{{gothon_dir}}/shm', 'r+b')
+_gothon_shared_memory = mmap.mmap(_gothon_shared_memory_file.fileno(), 0)
+
+
+class _GothonSharedValue:
+    def __init__(self, variable: str, slot: int, kind: type):
+        self.variable = variable
+        self.offset = slot * _GOTHON_SHARED_MEMORY_SLOT_SIZE
+        self.kind = kind
+        self.format = struct.Struct('>d' if kind is float else '>q')
+        self.lock = threading.Lock()
+
+    def check_transaction(self):
+        if getattr(_gothon_transaction, 'sock', None) is not None:
+            raise RuntimeError(f"gothon: '{self.variable}' is kept in shared memory, "
+                               f"so it can't be used within a transaction")
+
+    def get(self):
+        self.check_transaction()
+        while True:
+            sequence = _GOTHON_SHARED_MEMORY_SEQUENCE.unpack_from(_gothon_shared_memory, self.offset)[0]
+            if sequence % 2 == 0:
+                val = self.format.unpack_from(_gothon_shared_memory, self.offset + 8)[0]
+                if _GOTHON_SHARED_MEMORY_SEQUENCE.unpack_from(_gothon_shared_memory, self.offset)[0] == sequence:
+                    return self.kind(val)
+            time.sleep(0)
+
+    def update(self, update) -> tuple:
+        self.check_transaction()
+        with self.lock:
+            fcntl.lockf(_gothon_shared_memory_file, fcntl.LOCK_EX, _GOTHON_SHARED_MEMORY_SLOT_SIZE, self.offset)
+            try:
+                sequence = _GOTHON_SHARED_MEMORY_SEQUENCE.unpack_from(_gothon_shared_memory, self.offset)[0]
+                old = self.kind(self.format.unpack_from(_gothon_shared_memory, self.offset + 8)[0])
+                new = update(old)
+                try:
+                    val_bytes = self.format.pack(float(new) if self.kind is float else int(new))
+                except struct.error:
+                    raise OverflowError(f'{new} is out of range for {self.kind.__name__}') from None
+                _GOTHON_SHARED_MEMORY_SEQUENCE.pack_into(_gothon_shared_memory, self.offset, sequence + 1)
+                _gothon_shared_memory[self.offset + 8:self.offset + 16] = val_bytes
+                _GOTHON_SHARED_MEMORY_SEQUENCE.pack_into(_gothon_shared_memory, self.offset, sequence + 2)
+            finally:
+                fcntl.lockf(_gothon_shared_memory_file, fcntl.LOCK_UN, _GOTHON_SHARED_MEMORY_SLOT_SIZE, self.offset)
+        return old, new
+
+
+def _gothon_int_operator(operator: str, val: int, operand: int) -> int:
+    if (operator in ('floordiv', 'mod') and operand == 0) or (operator in ('pow', 'lshift', 'rshift') and operand < 0):
+        raise ArithmeticError(f'invalid operand for {operator}: {operand}')
+    if operator == 'div':
+        quotient = abs(val) // abs(operand)
+        result = quotient if (val < 0) == (operand < 0) else -quotient
+    elif operator == 'pow':
+        result = pow(val, operand, 2 ** 64)
+    elif operator == 'lshift':
+        result = val << min(operand, 64)
+    else:
+        result = _GOTHON_OPERATORS[operator](val, operand)
+    return (result + 2 ** 63) % 2 ** 64 - 2 ** 63
+
+
+def _gothon_float_operator(operator: str, val: float, operand: float) -> float:
+    if operator == 'div' and operand == 0:
+        if val == 0 or math.isnan(val):
+            return math.nan
+        return math.copysign(math.inf, val) * math.copysign(1, operand)
+    return _GOTHON_OPERATORS[operator](val, operand)
+
+
+_GOTHON_OPERATORS = {
+    'add': lambda a, b: a + b,
+    'sub': lambda a, b: a - b,
+    'mul': lambda a, b: a * b,
+    'div': lambda a, b: a / b,
+    'floordiv': lambda a, b: a // b,
+    'mod': lambda a, b: a % b,
+    'and': lambda a, b: a & b,
+    'or': lambda a, b: a | b,
+    'xor': lambda a, b: a ^ b,
+    'rshift': lambda a, b: a >> b,
+}
+`
+
+/*******************************************************************************
+ codec
+*******************************************************************************/
+
+var encodeTemplates = map[string]string{
+	"bool":   "(1 if {{val}} else 0).to_bytes(1, 'big')",
+	"int":    "{{val}}.to_bytes(8, 'big', signed=True)",
+	"float":  "struct.pack('>d', {{val}})",
+	"str":    "bytes({{val}}, 'utf-8')",
+	"bytes":  "bytes({{val}})",
+	"object": "pickle.dumps({{val}})",
+}
+
+var decodeTemplates = map[string]string{
+	"bool":   "val_bytes[0] != 0",
+	"int":    "int.from_bytes(val_bytes, 'big', signed=True)",
+	"float":  "struct.unpack_from('>d', val_bytes, 0)[0]",
+	"str":    "str(val_bytes, 'utf-8')",
+	"bytes":  "bytes(val_bytes)",
+	"object": "pickle.loads(val_bytes)",
+}
+
+// zeroTemplates are the values returned in place of those that couldn't be
+// read, as with receiving from a closed channel.
+var zeroTemplates = map[string]string{
+	"bool":   "False",
+	"int":    "0",
+	"float":  "0.0",
+	"str":    "''",
+	"bytes":  "b''",
+	"object": "None",
+}
 
 /*******************************************************************************
  socket
 *******************************************************************************/
 
-const socketInitTemplate = `
-    _sock_{{var_id}}_{{action}}_in.connect(_addr_{{var_id}}_{{action}}_in)
-    _sock_{{var_id}}_{{action}}_out.bind(_addr_{{var_id}}_{{action}}_out)`
+const socketTemplate = `
+class GothonTimeoutError(TimeoutError):
+    def __init__(self, variable: str, operation: str, timeout: float):
+        super().__init__(f"gothon: '{operation}' on '{variable}' timed out after {timeout} seconds")
+        self.variable = variable
+        self.operation = operation
+        self.timeout = timeout
+
+
+class Topic(list):
+    pass
+
+
+class Chan:
+    def __init__(self, size: int = 0):
+        self.size = size
+
+    def __class_getitem__(cls, item):
+        return cls
+
+
+class GothonError(Exception):
+    def __init__(self, variable: str, operation: str, status: int, message: str):
+        super().__init__(f"gothon: '{operation}' on '{variable}' failed: {message}")
+        self.variable = variable
+        self.operation = operation
+        self.status = status
+
+
+class GothonConflictError(GothonError):
+    pass
+
+
+_GOTHON_PROTOCOL_VERSION = {{protocol_version}}
+_GOTHON_OPCODE_CANCEL = {{cancel_opcode}}
+_GOTHON_FRAME_HEADER = struct.Struct('>IBBIBH')
+_GOTHON_OPERATION_HEADER = struct.Struct('>BH')
+_GOTHON_TRANSACTION_BEGIN = b'\x01'
+_GOTHON_TRANSACTION_OPERATION = b'\x02'
+_GOTHON_TRANSACTION_COMMIT = b'\x03'
+_GOTHON_TRANSACTION_ABORT = b'\x04'
+_GOTHON_STATUS_CONFLICT = 4
+_GOTHON_STATUS_REFUSED = 5
+_GOTHON_STATUS_CLOSED = 6
+_GOTHON_STATUS_TIMEOUT = 7
+_GOTHON_STATUS_CANCELED = 8
+
+_gothon_batch = threading.local()
+_gothon_transaction = threading.local()
+_gothon_transaction_lock = threading.Lock()
+
+
+class _GothonConnection:
+    def __init__(self):
+        self.sock = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+        self.send_lock = threading.Lock()
+        self.recv_cond = threading.Condition()
+        self.receiving = False
+        self.request_id = 0
+        self.replies = collections.defaultdict(collections.deque)
+        self.abandoned = set()
+
+    def connect(self, addr):
+        self.sock.connect(addr)
+
+    def send(self, opcode: int, id: bytes, data: bytes) -> int:
+        with self.send_lock:
+            self.request_id = (self.request_id + 1) & 0xFFFFFFFF
+            request_id = self.request_id
+            length = _GOTHON_FRAME_HEADER.size - 4 + len(id) + len(data)
+            header = _GOTHON_FRAME_HEADER.pack(length, _GOTHON_PROTOCOL_VERSION, opcode, request_id, 0, len(id))
+            self.sock.sendall(header + id + data)
+        return request_id
+
+    def recv(self, timeout: float, request_id: int) -> (int, bytes):
+        deadline = None if timeout is None else time.monotonic() + timeout
+        with self.recv_cond:
+            while True:
+                replies = self.replies.get(request_id)
+                if replies:
+                    reply = replies.popleft()
+                    if not replies:
+                        del self.replies[request_id]
+                    return reply
+                remaining = None if deadline is None else deadline - time.monotonic()
+                if remaining is not None and remaining <= 0:
+                    raise socket.timeout()
+                if self.receiving:
+                    self.recv_cond.wait(remaining)
+                    continue
+                self.receiving = True
+                self.recv_cond.release()
+                try:
+                    frame = self._read_frame(remaining)
+                finally:
+                    self.recv_cond.acquire()
+                    self.receiving = False
+                    self.recv_cond.notify_all()
+                if frame is not None and frame[0] not in self.abandoned:
+                    self.replies[frame[0]].append(frame[1:])
+
+    def request(self, opcode: int, id: str, variable: str, operation: str, timeout: float, access: str,
+                data: bytes) -> bytes:
+        """Sends the request for the operation on the variable with the given ID,
+        returning the payload of its reply and raising GothonError if it failed."""
+        status, payload = self.request_status(opcode, id, variable, operation, timeout, access, data)
+        if status != 0:
+            raise GothonError(variable, operation, status, payload.decode('utf-8', 'replace'))
+        return payload
+
+    def request_status(self, opcode: int, id: str, variable: str, operation: str, timeout: float, access: str,
+                       data: bytes) -> (int, bytes):
+        """Sends the request for the operation on the variable with the given ID,
+        returning the status of its reply along with its payload, and raising
+        unless the request was carried out or refused, or its channel was closed
+        or its timeout elapsed, which are left to the caller.  Within a
+        transaction, the request is sent as part of it, while within a batch,
+        writes are buffered (see _GothonBatch) and taken as carried out."""
+        id = id.encode('utf-8')
+        transaction = getattr(_gothon_transaction, 'request', None)
+        if access is not None and transaction is not None:
+            if access in ('atomic', 'none'):
+                raise RuntimeError(f"gothon: '{operation}' on '{variable}' can't be used within a transaction")
+            data = _GOTHON_TRANSACTION_OPERATION + _gothon_operation(opcode, id, data)
+            opcode, id = transaction[0], transaction[1].encode('utf-8')
+        elif access == 'write' and getattr(_gothon_batch, 'requests', None) is not None:
+            _gothon_batch.requests.append(_gothon_operation(opcode, id, data))
+            return 0, b''
+
+        request_id = self.send(opcode, id, data)
+        try:
+            status, payload = self.recv(timeout, request_id)
+        except socket.timeout:
+            # Gothon replies to the request canceled with its outcome if it
+            # carried it out in the meantime
+            self.cancel(id, request_id)
+            try:
+                status, payload = self.recv(timeout, request_id)
+            except socket.timeout:
+                self.abandon(request_id)
+                status, payload = _GOTHON_STATUS_CANCELED, b''
+            if status == _GOTHON_STATUS_CANCELED:
+                raise GothonTimeoutError(variable, operation, timeout) from None
+        if status == _GOTHON_STATUS_CONFLICT:
+            raise GothonConflictError(variable, operation, status, payload.decode('utf-8', 'replace'))
+        if status not in (0, _GOTHON_STATUS_REFUSED, _GOTHON_STATUS_CLOSED, _GOTHON_STATUS_TIMEOUT):
+            raise GothonError(variable, operation, status, payload.decode('utf-8', 'replace'))
+        return status, payload
+
+    def cancel(self, id: bytes, request_id: int):
+        """Asks Gothon to cancel the request, which it replies to either way:
+        with its outcome if it was already carried out, and otherwise with
+        _GOTHON_STATUS_CANCELED, undoing it if it's carried out later on."""
+        with self.send_lock:
+            length = _GOTHON_FRAME_HEADER.size - 4 + len(id)
+            header = _GOTHON_FRAME_HEADER.pack(length, _GOTHON_PROTOCOL_VERSION, _GOTHON_OPCODE_CANCEL, request_id, 0,
+                                               len(id))
+            self.sock.sendall(header + id)
 
-const socketInitTemplateForMutex = `
-    _sock_{{var_id}}_in.connect(_addr_{{var_id}}_in)
-    _sock_{{var_id}}_out.bind(_addr_{{var_id}}_out)`
+    def abandon(self, request_id: int):
+        with self.recv_cond:
+            self.abandoned.add(request_id)
+            self.replies.pop(request_id, None)
 
-const socketInitTemplateForSync = `
-    _sock_{{var_id}}_in.connect(_addr_{{var_id}}_in)
-    _sock_{{var_id}}_out.bind(_addr_{{var_id}}_out)`
+    def _read_frame(self, timeout: float):
+        readable, _, _ = select.select([self.sock], [], [], timeout)
+        if not readable:
+            return None
+        header = self._read(_GOTHON_FRAME_HEADER.size)
+        length, version, _, request_id, status, id_length = _GOTHON_FRAME_HEADER.unpack(header)
+        if version != _GOTHON_PROTOCOL_VERSION:
+            raise ConnectionError(f'gothon: unsupported protocol version {version}')
+        body = self._read(length - _GOTHON_FRAME_HEADER.size + 4)
+        return request_id, status, body[id_length:]
 
-const socketInitTemplateForQueueGet = `
-    _sock_{{var_id}}_get_ok.bind(_addr_{{var_id}}_get_ok)`
+    def _read(self, n: int) -> bytes:
+        buf = bytearray()
+        while len(buf) < n:
+            chunk = self.sock.recv(n - len(buf))
+            if not chunk:
+                raise ConnectionResetError('gothon: connection closed')
+            buf += chunk
+        return bytes(buf)
+
+
+_gothon_connection = _GothonConnection()
+_gothon_addr = '{{gothon_dir}}/sock/{{node_id}}.sock'
+
+
+def _gothon_operation(opcode: int, id: bytes, data: bytes) -> bytes:
+    """Packs the request as one of the operations of a batch or transaction."""
+    header = _GOTHON_OPERATION_HEADER.pack(opcode, len(id))
+    return header + id + len(data).to_bytes(4, 'big') + bytes(data)
+
+
+class _GothonBatch:
+    """Buffers the writes the thread makes to managed variables within the block,
+    sending them as one request on leaving it, which Gothon applies all at once
+    (or, if any of them fails, not at all).  Nothing is sent if the block raises,
+    and nested batches are part of the outermost."""
+
+    def __init__(self, *request):
+        self.request = request
+        self.outermost = False
+
+    def __enter__(self):
+        if getattr(_gothon_batch, 'requests', None) is None:
+            _gothon_batch.requests = []
+            self.outermost = True
+        return self
+
+    def __exit__(self, exc_type, exc_value, traceback):
+        if not self.outermost:
+            return False
+        requests = _gothon_batch.requests
+        _gothon_batch.requests = None
+        if exc_type is None and requests:
+            _gothon_connection.request(*self.request, b''.join(requests))
+        return False
+
+
+class _GothonTransaction:
+    """Makes the reads and writes the thread makes to managed variables within
+    the block as one, sending each as part of the transaction rather than on its
+    own.  Nothing is locked while the block runs: the writes are committed when
+    it exits (or, if it raises, discarded), unless a variable it used has been
+    changed by another node in the meantime, in which case none are and a
+    GothonConflictError is raised, either on exit or by the operation noticing
+    it within the block.  Only one transaction per node is open at a
+    time, and nested transactions are part of the outermost."""
+
+    def __init__(self, *request):
+        self.request = request
+        self.outermost = False
+
+    def __enter__(self):
+        if getattr(_gothon_transaction, 'request', None) is not None:
+            return self
+        _gothon_transaction_lock.acquire()
+        try:
+            _gothon_connection.request(*self.request, _GOTHON_TRANSACTION_BEGIN)
+        except BaseException:
+            _gothon_transaction_lock.release()
+            raise
+        _gothon_transaction.request = self.request
+        self.outermost = True
+        return self
+
+    def __exit__(self, exc_type, exc_value, traceback):
+        if not self.outermost:
+            return False
+        _gothon_transaction.request = None
+        try:
+            _gothon_connection.request(*self.request,
+                                       _GOTHON_TRANSACTION_COMMIT if exc_type is None else _GOTHON_TRANSACTION_ABORT)
+        finally:
+            _gothon_transaction_lock.release()
+        return False
+
+
+def _gothon_get_timeout() -> float:
+    timeout = os.environ.get('GOTHON_TIMEOUT', '')
+    if timeout == '' or float(timeout) <= 0:
+        return None
+    return float(timeout)
+
+
+_gothon_timeout = _gothon_get_timeout()
+`
+
+const socketInitTemplate = `
+try:
+    _gothon_connection.connect(_gothon_addr)
+except socket.error as msg:
+    print(msg, file=sys.stderr)
+    sys.exit(1)
+`
 
 /*******************************************************************************
  template map
 *******************************************************************************/
 
+// sharedMemoryTemplates replace the templates of the same name for variables
+// kept in the shared memory file.
+var sharedMemoryTemplates = map[string]string{
+	"bool_set":        sharedMemorySetFuncTemplate,
+	"bool_get":        sharedMemoryGetFuncTemplate,
+	"bool_swap":       sharedMemorySwapFuncTemplate,
+	"bool_cas":        sharedMemoryCompareAndSwapFuncTemplate,
+	"int_set":         sharedMemorySetFuncTemplate,
+	"int_get":         sharedMemoryGetFuncTemplate,
+	"int_add":         sharedMemoryIntArithmeticFuncTemplate,
+	"int_sub":         sharedMemoryIntArithmeticFuncTemplate,
+	"int_mul":         sharedMemoryIntArithmeticFuncTemplate,
+	"int_div":         sharedMemoryIntArithmeticFuncTemplate,
+	"int_floordiv":    sharedMemoryIntArithmeticFuncTemplate,
+	"int_mod":         sharedMemoryIntArithmeticFuncTemplate,
+	"int_pow":         sharedMemoryIntArithmeticFuncTemplate,
+	"int_and":         sharedMemoryIntArithmeticFuncTemplate,
+	"int_or":          sharedMemoryIntArithmeticFuncTemplate,
+	"int_xor":         sharedMemoryIntArithmeticFuncTemplate,
+	"int_lshift":      sharedMemoryIntArithmeticFuncTemplate,
+	"int_rshift":      sharedMemoryIntArithmeticFuncTemplate,
+	"int_fetch_add":   sharedMemoryFetchAddFuncTemplate,
+	"int_swap":        sharedMemorySwapFuncTemplate,
+	"int_cas":         sharedMemoryCompareAndSwapFuncTemplate,
+	"int_max":         sharedMemoryExtremumFuncTemplate,
+	"int_min":         sharedMemoryExtremumFuncTemplate,
+	"float_set":       sharedMemorySetFuncTemplate,
+	"float_get":       sharedMemoryGetFuncTemplate,
+	"float_add":       sharedMemoryFloatArithmeticFuncTemplate,
+	"float_sub":       sharedMemoryFloatArithmeticFuncTemplate,
+	"float_mul":       sharedMemoryFloatArithmeticFuncTemplate,
+	"float_div":       sharedMemoryFloatArithmeticFuncTemplate,
+	"float_fetch_add": sharedMemoryFetchAddFuncTemplate,
+	"float_swap":      sharedMemorySwapFuncTemplate,
+	"float_cas":       sharedMemoryCompareAndSwapFuncTemplate,
+	"float_max":       sharedMemoryExtremumFuncTemplate,
+	"float_min":       sharedMemoryExtremumFuncTemplate,
+}
+
 var templates = map[string]string{
-	"bool_set":        boolSetFuncTemplate,
-	"bool_get":        boolGetFuncTemplate,
-	"int_set":         intSetFuncTemplate,
-	"int_get":         intGetFuncTemplate,
-	"int_add":         intAddFuncTemplate,
-	"int_sub":         intSubFuncTemplate,
-	"int_mul":         intMulFuncTemplate,
-	"int_div":         intDivFuncTemplate,
-	"float_set":       floatSetFuncTemplate,
-	"float_get":       floatGetFuncTemplate,
-	"float_add":       floatAddFuncTemplate,
-	"float_sub":       floatSubFuncTemplate,
-	"float_mul":       floatMulFuncTemplate,
-	"float_div":       floatDivFuncTemplate,
-	"str_set":         stringSetFuncTemplate,
-	"str_get":         stringGetFuncTemplate,
-	"str_add":         stringAddFuncTemplate,
-	"str_sub":         stringSubFuncTemplate,
-	"mutex":           mutexFuncTemplate,
-	"sync":            syncFuncTemplate,
-	"queue_size":      queueSizeFuncTemplate,
-	"queue_empty":     queueEmptyFuncTemplate,
-	"queue_full":      queueFullFuncTemplate,
-	"bool_queue_set":  boolQueueSetFuncTemplate,
-	"bool_queue_get":  boolQueueGetFuncTemplate,
-	"int_queue_set":   intQueueSetFuncTemplate,
-	"int_queue_get":   intQueueGetFuncTemplate,
-	"float_queue_set": floatQueueSetFuncTemplate,
-	"float_queue_get": floatQueueGetFuncTemplate,
-	"str_queue_set":   stringQueueSetFuncTemplate,
-	"str_queue_get":   stringQueueGetFuncTemplate,
+	"bool_set":                boolSetFuncTemplate,
+	"bool_get":                boolGetFuncTemplate,
+	"int_set":                 intSetFuncTemplate,
+	"int_get":                 intGetFuncTemplate,
+	"int_add":                 intAddFuncTemplate,
+	"int_sub":                 intSubFuncTemplate,
+	"int_mul":                 intMulFuncTemplate,
+	"int_div":                 intDivFuncTemplate,
+	"int_floordiv":            intOperatorFuncTemplate,
+	"int_mod":                 intOperatorFuncTemplate,
+	"int_pow":                 intOperatorFuncTemplate,
+	"int_and":                 intOperatorFuncTemplate,
+	"int_or":                  intOperatorFuncTemplate,
+	"int_xor":                 intOperatorFuncTemplate,
+	"int_lshift":              intOperatorFuncTemplate,
+	"int_rshift":              intOperatorFuncTemplate,
+	"float_set":               floatSetFuncTemplate,
+	"float_get":               floatGetFuncTemplate,
+	"float_add":               floatAddFuncTemplate,
+	"float_sub":               floatSubFuncTemplate,
+	"float_mul":               floatMulFuncTemplate,
+	"float_div":               floatDivFuncTemplate,
+	"str_set":                 stringSetFuncTemplate,
+	"str_get":                 stringGetFuncTemplate,
+	"str_add":                 stringAddFuncTemplate,
+	"str_sub":                 stringSubFuncTemplate,
+	"bytes_set":               bytesSetFuncTemplate,
+	"bytes_get":               bytesGetFuncTemplate,
+	"bytes_add":               bytesAddFuncTemplate,
+	"object_set":              objectSetFuncTemplate,
+	"object_get":              objectGetFuncTemplate,
+	"bool_swap":               atomicExchangeFuncTemplate,
+	"bool_cas":                atomicCompareAndSwapFuncTemplate,
+	"int_fetch_add":           atomicExchangeFuncTemplate,
+	"int_swap":                atomicExchangeFuncTemplate,
+	"int_cas":                 atomicCompareAndSwapFuncTemplate,
+	"float_fetch_add":         atomicExchangeFuncTemplate,
+	"float_swap":              atomicExchangeFuncTemplate,
+	"float_cas":               atomicCompareAndSwapFuncTemplate,
+	"str_fetch_add":           atomicExchangeFuncTemplate,
+	"str_swap":                atomicExchangeFuncTemplate,
+	"str_cas":                 atomicCompareAndSwapFuncTemplate,
+	"int_max":                 atomicExtremumFuncTemplate,
+	"int_min":                 atomicExtremumFuncTemplate,
+	"float_max":               atomicExtremumFuncTemplate,
+	"float_min":               atomicExtremumFuncTemplate,
+	"mutex":                   mutexFuncTemplate,
+	"sync":                    syncFuncTemplate,
+	"acquire":                 semaphoreAcquireFuncTemplate,
+	"release":                 semaphoreReleaseFuncTemplate,
+	"barrier":                 barrierFuncTemplate,
+	"batch":                   batchFuncTemplate,
+	"transaction":             transactionFuncTemplate,
+	"event_set":               eventSetFuncTemplate,
+	"event_clear":             eventSetFuncTemplate,
+	"event_isset":             eventIsSetFuncTemplate,
+	"event_wait":              eventWaitFuncTemplate,
+	"condition_wait":          conditionWaitFuncTemplate,
+	"condition_notify":        conditionNotifyFuncTemplate,
+	"condition_notifyall":     conditionNotifyAllFuncTemplate,
+	"fifo_queue_taskdone":     queueTaskDoneFuncTemplate,
+	"fifo_queue_join":         queueJoinFuncTemplate,
+	"lifo_queue_taskdone":     queueTaskDoneFuncTemplate,
+	"lifo_queue_join":         queueJoinFuncTemplate,
+	"priority_queue_taskdone": queueTaskDoneFuncTemplate,
+	"priority_queue_join":     queueJoinFuncTemplate,
+	"chan_send":               chanSendFuncTemplate,
+	"chan_recv":               chanRecvFuncTemplate,
+	"chan_close":              chanCloseFuncTemplate,
+	"chan_iter":               chanIterFuncTemplate,
+	"queue_size":              queueSizeFuncTemplate,
+	"queue_empty":             queueEmptyFuncTemplate,
+	"queue_full":              queueFullFuncTemplate,
+	"bool_queue_set":          boolQueueSetFuncTemplate,
+	"bool_queue_get":          boolQueueGetFuncTemplate,
+	"int_queue_set":           intQueueSetFuncTemplate,
+	"int_queue_get":           intQueueGetFuncTemplate,
+	"float_queue_set":         floatQueueSetFuncTemplate,
+	"float_queue_get":         floatQueueGetFuncTemplate,
+	"str_queue_set":           stringQueueSetFuncTemplate,
+	"str_queue_get":           stringQueueGetFuncTemplate,
+	"bytes_queue_set":         bytesQueueSetFuncTemplate,
+	"bytes_queue_get":         bytesQueueGetFuncTemplate,
+	"object_queue_set":        objectQueueSetFuncTemplate,
+	"object_queue_get":        objectQueueGetFuncTemplate,
+	"dict_set":                dictSetFuncTemplate,
+	"dict_get":                dictGetFuncTemplate,
+	"dict_add":                dictAddFuncTemplate,
+	"dict_del":                dictDelFuncTemplate,
+	"dict_contains":           dictContainsFuncTemplate,
+	"dict_size":               queueSizeFuncTemplate,
+	"dict_keys":               dictKeysFuncTemplate,
+	"list_set":                listSetFuncTemplate,
+	"list_get":                listGetFuncTemplate,
+	"list_append":             listAppendFuncTemplate,
+	"list_slice":              listSliceFuncTemplate,
+	"list_size":               queueSizeFuncTemplate,
+	"set_add":                 setAddFuncTemplate,
+	"set_del":                 setDelFuncTemplate,
+	"set_contains":            dictContainsFuncTemplate,
+	"set_size":                queueSizeFuncTemplate,
+	"set_keys":                dictKeysFuncTemplate,
 }
@@ -0,0 +1,318 @@
+package code
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenType byte
+
+const (
+	endMarkerToken tokenType = iota
+	nameToken
+	numberToken
+	stringToken
+	operatorToken
+	newlineToken
+	nlToken
+	commentToken
+)
+
+func (t tokenType) String() string {
+	switch t {
+	case endMarkerToken:
+		return "end_marker"
+	case nameToken:
+		return "name"
+	case numberToken:
+		return "number"
+	case stringToken:
+		return "string"
+	case operatorToken:
+		return "operator"
+	case newlineToken:
+		return "newline"
+	case nlToken:
+		return "nl"
+	case commentToken:
+		return "comment"
+	default:
+		return ""
+	}
+}
+
+// token positions: lines are 1-based, columns are 0-based byte offsets
+// within the line and start/end are byte offsets within the source.
+type token struct {
+	Type      tokenType
+	Value     string
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+	start     int
+	end       int
+}
+
+func (t token) is(tokenType tokenType, value string) bool {
+	return t.Type == tokenType && t.Value == value
+}
+
+var operators = []string{
+	"**=", "//=", ">>=", "<<=", "...",
+	"->", ":=", "**", "//", "<<", ">>", "<=", ">=", "==", "!=",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "@=",
+	"+", "-", "*", "/", "%", "@", "&", "|", "^", "~", "<", ">",
+	"(", ")", "[", "]", "{", "}", ",", ":", ";", ".", "=", "!",
+}
+
+type tokenizer struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int
+	depth     int
+	tokens    []token
+}
+
+func tokenize(src string) ([]token, error) {
+	t := &tokenizer{
+		src:    src,
+		line:   1,
+		tokens: make([]token, 0),
+	}
+
+	err := t.run()
+	if err != nil {
+		return nil, err
+	}
+
+	return t.tokens, nil
+}
+
+func (t *tokenizer) run() error {
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\f' || c == '\r':
+			t.pos++
+		case c == '\n':
+			t.newline()
+		case c == '\\':
+			if !t.continuation() {
+				return t.errorf("unexpected character after line continuation character")
+			}
+		case c == '#':
+			start := t.pos
+			for t.pos < len(t.src) && t.src[t.pos] != '\n' {
+				t.pos++
+			}
+			t.emit(commentToken, start)
+		case c == '"' || c == '\'' || t.isStringPrefix():
+			err := t.string()
+			if err != nil {
+				return err
+			}
+		case isDigit(c) || (c == '.' && t.pos+1 < len(t.src) && isDigit(t.src[t.pos+1])):
+			t.number()
+		case isNameStart(t.peekRune()):
+			start := t.pos
+			for t.pos < len(t.src) && isNameContinue(t.peekRune()) {
+				_, size := utf8.DecodeRuneInString(t.src[t.pos:])
+				t.pos += size
+			}
+			t.emit(nameToken, start)
+		default:
+			if !t.operator() {
+				return t.errorf("invalid character '%c'", t.peekRune())
+			}
+		}
+	}
+
+	if t.depth > 0 {
+		return t.errorf("unexpected EOF, unclosed bracket")
+	}
+
+	if len(t.tokens) > 0 && t.tokens[len(t.tokens)-1].Type != newlineToken && t.hasLogicalLine() {
+		t.tokens = append(t.tokens, token{
+			Type: newlineToken, Line: t.line, Column: t.pos - t.lineStart, EndLine: t.line,
+			EndColumn: t.pos - t.lineStart, start: t.pos, end: t.pos,
+		})
+	}
+
+	t.tokens = append(t.tokens, token{
+		Type: endMarkerToken, Line: t.line, Column: t.pos - t.lineStart, EndLine: t.line,
+		EndColumn: t.pos - t.lineStart, start: t.pos, end: t.pos,
+	})
+
+	return nil
+}
+
+func (t *tokenizer) newline() {
+	tokenType := nlToken
+	if t.depth == 0 && t.hasLogicalLine() {
+		tokenType = newlineToken
+	}
+
+	t.tokens = append(t.tokens, token{
+		Type: tokenType, Value: "\n", Line: t.line, Column: t.pos - t.lineStart, EndLine: t.line,
+		EndColumn: t.pos - t.lineStart + 1, start: t.pos, end: t.pos + 1,
+	})
+
+	t.pos++
+	t.line++
+	t.lineStart = t.pos
+}
+
+// hasLogicalLine reports whether any code token has been emitted since the
+// last NEWLINE, i.e. whether a NEWLINE now would terminate a statement.
+func (t *tokenizer) hasLogicalLine() bool {
+	for i := len(t.tokens) - 1; i >= 0; i-- {
+		switch t.tokens[i].Type {
+		case newlineToken:
+			return false
+		case nlToken, commentToken:
+			continue
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tokenizer) continuation() bool {
+	next := t.pos + 1
+	if next < len(t.src) && t.src[next] == '\r' {
+		next++
+	}
+	if next >= len(t.src) || t.src[next] != '\n' {
+		return false
+	}
+
+	t.pos = next + 1
+	t.line++
+	t.lineStart = t.pos
+	return true
+}
+
+func (t *tokenizer) isStringPrefix() bool {
+	i := t.pos
+	for i < len(t.src) && i-t.pos < 3 && strings.ContainsRune("rRbBuUfF", rune(t.src[i])) {
+		i++
+	}
+	return i > t.pos && i < len(t.src) && (t.src[i] == '"' || t.src[i] == '\'')
+}
+
+func (t *tokenizer) string() error {
+	start, startLine, startColumn := t.pos, t.line, t.pos-t.lineStart
+
+	for t.src[t.pos] != '"' && t.src[t.pos] != '\'' {
+		t.pos++
+	}
+
+	quote := t.src[t.pos : t.pos+1]
+	if strings.HasPrefix(t.src[t.pos:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	t.pos += len(quote)
+
+	for {
+		if t.pos >= len(t.src) {
+			return fmt.Errorf("syntax error: line %d: unterminated string literal", startLine)
+		}
+
+		c := t.src[t.pos]
+		switch {
+		case c == '\\':
+			if t.pos+1 < len(t.src) && t.src[t.pos+1] == '\n' {
+				t.line++
+				t.lineStart = t.pos + 2
+			}
+			t.pos += 2
+		case c == '\n':
+			if len(quote) == 1 {
+				return fmt.Errorf("syntax error: line %d: unterminated string literal", startLine)
+			}
+			t.pos++
+			t.line++
+			t.lineStart = t.pos
+		case strings.HasPrefix(t.src[t.pos:], quote):
+			t.pos += len(quote)
+			t.tokens = append(t.tokens, token{
+				Type: stringToken, Value: t.src[start:t.pos], Line: startLine, Column: startColumn,
+				EndLine: t.line, EndColumn: t.pos - t.lineStart, start: start, end: t.pos,
+			})
+			return nil
+		default:
+			t.pos++
+		}
+	}
+}
+
+func (t *tokenizer) number() {
+	start := t.pos
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+		if isDigit(c) || c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			t.pos++
+			if (c == 'e' || c == 'E') && t.pos < len(t.src) && (t.src[t.pos] == '+' || t.src[t.pos] == '-') &&
+				!strings.HasPrefix(strings.ToLower(t.src[start:t.pos]), "0x") {
+				t.pos++
+			}
+			continue
+		}
+		break
+	}
+	t.emit(numberToken, start)
+}
+
+func (t *tokenizer) operator() bool {
+	for _, op := range operators {
+		if strings.HasPrefix(t.src[t.pos:], op) {
+			start := t.pos
+			t.pos += len(op)
+			switch op {
+			case "(", "[", "{":
+				t.depth++
+			case ")", "]", "}":
+				if t.depth > 0 {
+					t.depth--
+				}
+			}
+			t.emit(operatorToken, start)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tokenizer) emit(tokenType tokenType, start int) {
+	t.tokens = append(t.tokens, token{
+		Type: tokenType, Value: t.src[start:t.pos], Line: t.line, Column: start - t.lineStart,
+		EndLine: t.line, EndColumn: t.pos - t.lineStart, start: start, end: t.pos,
+	})
+}
+
+func (t *tokenizer) peekRune() rune {
+	r, _ := utf8.DecodeRuneInString(t.src[t.pos:])
+	return r
+}
+
+func (t *tokenizer) errorf(msg string, args ...any) error {
+	return fmt.Errorf("syntax error: line %d: %s", t.line, fmt.Sprintf(msg, args...))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isNameContinue(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,112 @@
+package code
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	type tok struct {
+		Type  tokenType
+		Value string
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want []tok
+	}{
+		{
+			name: "string containing equals",
+			src:  "_x_: str = 'a=b'\n",
+			want: []tok{
+				{nameToken, "_x_"}, {operatorToken, ":"}, {nameToken, "str"}, {operatorToken, "="},
+				{stringToken, "'a=b'"}, {newlineToken, "\n"}, {endMarkerToken, ""},
+			},
+		},
+		{
+			name: "statements after semicolon",
+			src:  "a = 1; b += 2\n",
+			want: []tok{
+				{nameToken, "a"}, {operatorToken, "="}, {numberToken, "1"}, {operatorToken, ";"},
+				{nameToken, "b"}, {operatorToken, "+="}, {numberToken, "2"}, {newlineToken, "\n"}, {endMarkerToken, ""},
+			},
+		},
+		{
+			name: "backslash continuation",
+			src:  "a = 1 + \\\n    2\n",
+			want: []tok{
+				{nameToken, "a"}, {operatorToken, "="}, {numberToken, "1"}, {operatorToken, "+"},
+				{numberToken, "2"}, {newlineToken, "\n"}, {endMarkerToken, ""},
+			},
+		},
+		{
+			name: "multi-line brackets",
+			src:  "f(1,\n  2)\n",
+			want: []tok{
+				{nameToken, "f"}, {operatorToken, "("}, {numberToken, "1"}, {operatorToken, ","}, {nlToken, "\n"},
+				{numberToken, "2"}, {operatorToken, ")"}, {newlineToken, "\n"}, {endMarkerToken, ""},
+			},
+		},
+		{
+			name: "triple quoted string spanning lines",
+			src:  "s = '''a\n=b'''\n",
+			want: []tok{
+				{nameToken, "s"}, {operatorToken, "="}, {stringToken, "'''a\n=b'''"}, {newlineToken, "\n"},
+				{endMarkerToken, ""},
+			},
+		},
+		{
+			name: "comment and blank line",
+			src:  "# c = 1\n\nx = b'\\''  # d\n",
+			want: []tok{
+				{commentToken, "# c = 1"}, {nlToken, "\n"}, {nlToken, "\n"}, {nameToken, "x"}, {operatorToken, "="},
+				{stringToken, "b'\\''"}, {commentToken, "# d"}, {newlineToken, "\n"}, {endMarkerToken, ""},
+			},
+		},
+		{
+			name: "no trailing newline",
+			src:  "x = 1",
+			want: []tok{
+				{nameToken, "x"}, {operatorToken, "="}, {numberToken, "1"}, {newlineToken, ""}, {endMarkerToken, ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenize(tt.src)
+			if err != nil {
+				t.Fatalf("tokenize() error = %v", err)
+			}
+
+			got := make([]tok, 0, len(tokens))
+			for _, token := range tokens {
+				got = append(got, tok{token.Type, token.Value})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeError(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"unterminated string", "x = 'abc\n"},
+		{"unclosed bracket", "f(1,\n"},
+		{"character after continuation", "x = 1 \\ 2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tokenize(tt.src)
+			if err == nil {
+				t.Errorf("tokenize() error = nil, want an error")
+			}
+		})
+	}
+}
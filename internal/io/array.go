@@ -5,11 +5,11 @@ import (
 	"strconv"
 )
 
-type SocketArray []*DomainSocket
+type NodeArray []*Node
 
-func (a SocketArray) Listen() error {
-	for _, socket := range a {
-		err := socket.Init()
+func (a NodeArray) Listen() error {
+	for _, node := range a {
+		err := node.Listen()
 		if err != nil {
 			return err
 		}
@@ -17,29 +17,34 @@ func (a SocketArray) Listen() error {
 	return nil
 }
 
-func (a SocketArray) Close() {
-	for _, socket := range a {
-		socket.Close()
+func (a NodeArray) Close() {
+	for _, node := range a {
+		node.Close()
 	}
 }
 
-func (a SocketArray) Get(path string) *DomainSocket {
-	for _, socket := range a {
-		if socket.path == path {
-			return socket
-		}
+// Handle sets the handler for the action on the variable with the given ID, on
+// every node.
+func (a NodeArray) Handle(action string, id string, handler Handler) {
+	for _, node := range a {
+		node.Handle(action, id, handler)
+	}
+}
+
+// OnDisconnect sets the function called with the index of each node whose
+// connection ends, after the last of its requests has been passed to its
+// handler and those yet to be replied to have been canceled.
+func (a NodeArray) OnDisconnect(f func(node int)) {
+	for _, node := range a {
+		node.onDisconnect = f
 	}
-	return nil
 }
 
-func NewSocketArray(basePath string, socketPaths []string, nodeCount int) SocketArray {
-	socketArray := make([]*DomainSocket, 0)
+func NewNodeArray(basePath string, nodeCount int) NodeArray {
+	nodeArray := make([]*Node, 0)
 	for i := 0; i < nodeCount; i++ {
-		for _, path := range socketPaths {
-			socketPath, _ := filepath.Abs(filepath.Join(basePath, strconv.Itoa(i), path))
-			socket := NewDomainSocket(socketPath, path)
-			socketArray = append(socketArray, socket)
-		}
+		path, _ := filepath.Abs(filepath.Join(basePath, strconv.Itoa(i)+".sock"))
+		nodeArray = append(nodeArray, NewNode(path, i))
 	}
-	return socketArray
+	return nodeArray
 }
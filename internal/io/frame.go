@@ -0,0 +1,131 @@
+package io
+
+import (
+	"encoding/binary"
+	"fmt"
+	stdio "io"
+)
+
+// ProtocolVersion is the version of the frame format below, which nodes must
+// send their requests with.  It changes whenever the format does.
+const ProtocolVersion = byte(1)
+
+// frameHeaderLength is the length of the fixed part of a frame, which is made
+// up of the following (in network byte order, as are all values carried by
+// the payload):
+//
+//	length    uint32  the length of the rest of the frame
+//	version   uint8   the version of the protocol, see ProtocolVersion
+//	opcode    uint8   the action requested or replied to (see opcodes)
+//	requestID uint32  chosen by the node, then echoed back in each reply to it
+//	status    uint8   always StatusOK in requests, see the statuses below
+//	idLength  uint16  the length of the variable ID
+//	id        []byte  the ID of the variable, e.g. main/_counter_
+//	payload   []byte  the request or reply, or the error message if the status
+//	                  isn't StatusOK
+const frameHeaderLength = 13
+
+// The statuses of replies, where any but StatusOK means the request wasn't
+// carried out and the payload is a UTF-8 message explaining why.
+// StatusConflict is replied to the commit of a transaction when a variable it
+// used was changed by another node in the meantime.  The last three aren't
+// errors but outcomes the node turns into those of Python: StatusRefused when
+// the variable's state doesn't allow the request (e.g. a missing key, or a
+// queue that's empty when not blocking), StatusClosed when the channel is
+// closed and StatusTimeout when the timeout given with the request elapsed.
+// StatusCanceled is replied to a request the node canceled, see Node.cancel.
+const (
+	StatusOK byte = iota
+	StatusBadRequest
+	StatusUnknownOperation
+	StatusUnsupportedVersion
+	StatusConflict
+	StatusRefused
+	StatusClosed
+	StatusTimeout
+	StatusCanceled
+)
+
+// opcodes are the actions carried by frames, where the opcode of an action is
+// its index.  Callables (locks, semaphores, etc) share the call action, as each
+// has a single request and reply.  Opcodes are fixed, so new actions must only
+// ever be appended.
+var opcodes = []string{
+	"call", "set", "get", "add", "sub", "mul", "div", "size", "empty", "full", "del", "contains", "keys", "append",
+	"slice", "fetch_add", "swap", "cas", "floordiv", "mod", "pow", "and", "or", "xor", "lshift", "rshift", "max",
+	"min", "clear", "isset", "wait", "notify", "notifyall", "taskdone", "join", "send", "recv", "close", "iter",
+	"cancel",
+}
+
+// opcodeCancel is the opcode of the frames canceling a request, see Node.cancel.
+var opcodeCancel = GetOpcode("cancel")
+
+// GetOpcode returns the opcode of the action, panicking if there isn't one.
+func GetOpcode(action string) byte {
+	for i, a := range opcodes {
+		if a == action {
+			return byte(i)
+		}
+	}
+	panic(fmt.Errorf("no opcode for action %s", action))
+}
+
+// GetAction returns the action with the opcode, if there is one.
+func GetAction(opcode byte) (string, bool) {
+	if int(opcode) >= len(opcodes) {
+		return "", false
+	}
+	return opcodes[opcode], true
+}
+
+type frame struct {
+	version   byte
+	opcode    byte
+	requestID uint32
+	status    byte
+	id        string
+	payload   []byte
+}
+
+func writeFrame(w stdio.Writer, f frame) error {
+	buffer := make([]byte, frameHeaderLength, frameHeaderLength+len(f.id)+len(f.payload))
+	binary.BigEndian.PutUint32(buffer, uint32(frameHeaderLength-4+len(f.id)+len(f.payload)))
+	buffer[4] = f.version
+	buffer[5] = f.opcode
+	binary.BigEndian.PutUint32(buffer[6:], f.requestID)
+	buffer[10] = f.status
+	binary.BigEndian.PutUint16(buffer[11:], uint16(len(f.id)))
+	buffer = append(append(buffer, f.id...), f.payload...)
+
+	_, err := w.Write(buffer)
+	return err
+}
+
+func readFrame(r stdio.Reader) (f frame, err error) {
+	header := make([]byte, frameHeaderLength)
+	_, err = stdio.ReadFull(r, header)
+	if err != nil {
+		return f, err
+	}
+
+	length := int(binary.BigEndian.Uint32(header))
+	idLength := int(binary.BigEndian.Uint16(header[11:]))
+	if length < frameHeaderLength-4+idLength {
+		return f, fmt.Errorf("invalid frame length %d for id length %d", length, idLength)
+	}
+
+	body := make([]byte, length-(frameHeaderLength-4))
+	_, err = stdio.ReadFull(r, body)
+	if err != nil {
+		return f, err
+	}
+
+	return frame{
+		version:   header[4],
+		opcode:    header[5],
+		requestID: binary.BigEndian.Uint32(header[6:]),
+		status:    header[10],
+		id:        string(body[:idLength]),
+		payload:   body[idLength:],
+	}, nil
+}
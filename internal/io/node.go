@@ -0,0 +1,282 @@
+package io
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	stdio "io"
+	"net"
+	"os"
+	"sync"
+	"tonysoft.com/gothon/pkg/log"
+)
+
+// Node is the connection to a node, over which the requests and replies for
+// all of its variables are sent as frames (see frameHeaderLength).  Requests
+// are passed to the handler for their opcode and variable ID as they're read,
+// while replies are queued and sent in the order they're made, each with the
+// ID of the request it's for so that a node can have several outstanding at
+// once.  Neither reading nor replying ever waits on the node reading replies.
+// The requests yet to be replied to are kept until then, so that the node can
+// cancel them.
+type Node struct {
+	path         string
+	index        int
+	listener     *net.UnixListener
+	handlers     map[handlerKey]Handler
+	onDisconnect func(node int)
+	mut          sync.Mutex
+	replied      *sync.Cond
+	replies      []frame
+	pending      map[uint32]*Request
+	conn         net.Conn
+	closed       bool
+}
+
+func (n *Node) Listen() error {
+	_ = os.Remove(n.path)
+
+	addr, err := net.ResolveUnixAddr("unix", n.path)
+	if err != nil {
+		return err
+	}
+
+	n.listener, err = net.ListenUnix("unix", addr)
+	if err != nil {
+		return err
+	}
+
+	go n.accept()
+	return nil
+}
+
+func (n *Node) Close() {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+
+	if n.closed {
+		return
+	}
+	n.closed = true
+	n.replied.Broadcast()
+
+	if n.listener != nil {
+		_ = n.listener.Close()
+	}
+	if n.conn != nil {
+		_ = n.conn.Close()
+	}
+}
+
+// Handle sets the handler for the action on the variable with the given ID,
+// which must be done before the node connects.
+func (n *Node) Handle(action string, id string, handler Handler) {
+	key := handlerKey{GetOpcode(action), id}
+	if _, exists := n.handlers[key]; exists {
+		panic(fmt.Errorf("duplicate handler for %s on %s", action, id))
+	}
+	n.handlers[key] = handler
+}
+
+func (n *Node) accept() {
+	conn, err := n.listener.Accept()
+	if err != nil {
+		if !errors.Is(err, net.ErrClosed) {
+			log.Errorf("io:node:accept:error: %v", err)
+		}
+		return
+	}
+
+	n.mut.Lock()
+	if n.closed {
+		n.mut.Unlock()
+		_ = conn.Close()
+		return
+	}
+	n.conn = conn
+	n.mut.Unlock()
+
+	go n.send()
+	n.dispatch(bufio.NewReader(conn))
+	n.cancelPending()
+
+	if n.onDisconnect != nil {
+		n.onDisconnect(n.index)
+	}
+}
+
+func (n *Node) dispatch(reader stdio.Reader) {
+	for {
+		f, err := readFrame(reader)
+		if err != nil {
+			if !errors.Is(err, stdio.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Errorf("io:node:read:error: %v", err)
+			}
+			return
+		}
+
+		if f.version != ProtocolVersion {
+			n.reject(f, StatusUnsupportedVersion, fmt.Sprintf("unsupported protocol version %d", f.version))
+			continue
+		}
+
+		if f.opcode == opcodeCancel {
+			n.cancel(f.requestID)
+			continue
+		}
+
+		handler, ok := n.handlers[handlerKey{f.opcode, f.id}]
+		if !ok {
+			n.reject(f, StatusUnknownOperation, fmt.Sprintf("no operation with opcode %d on %s", f.opcode, f.id))
+			continue
+		}
+
+		request := &Request{
+			Node:      n.index,
+			Payload:   f.payload,
+			node:      n,
+			opcode:    f.opcode,
+			id:        f.id,
+			requestID: f.requestID,
+		}
+		n.mut.Lock()
+		n.pending[f.requestID] = request
+		n.mut.Unlock()
+
+		handler(request)
+	}
+}
+
+// cancel replies to the request with the given ID with StatusCanceled, unless
+// it was already replied to, which the node sends once it gives up waiting on
+// the reply.  The handler then gets ErrCanceled when replying, see Request.
+// Cancel frames themselves are never replied to.
+func (n *Node) cancel(requestID uint32) {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+
+	request, ok := n.pending[requestID]
+	if !ok {
+		return
+	}
+	delete(n.pending, requestID)
+
+	request.cancel()
+
+	if !n.closed {
+		n.replies = append(n.replies, request.reply(StatusCanceled, []byte(ErrCanceled.Error())))
+		n.replied.Signal()
+	}
+}
+
+// cancelPending cancels the requests of the node yet to be replied to once its
+// connection ends, so that those waiting on other nodes give up.
+func (n *Node) cancelPending() {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+
+	for requestID, request := range n.pending {
+		delete(n.pending, requestID)
+		request.cancel()
+	}
+}
+
+// answer queues the reply to the request, unless the node canceled it.
+func (n *Node) answer(request *Request, f frame) error {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+
+	if request.isCanceled {
+		return ErrCanceled
+	}
+	delete(n.pending, request.requestID)
+
+	if n.closed {
+		return net.ErrClosed
+	}
+	n.replies = append(n.replies, f)
+	n.replied.Signal()
+	return nil
+}
+
+// reject replies to a request that can't be dispatched with an error, which
+// is left to the node to raise.
+func (n *Node) reject(request frame, status byte, message string) {
+	reply := frame{
+		version:   ProtocolVersion,
+		opcode:    request.opcode,
+		requestID: request.requestID,
+		status:    status,
+		id:        request.id,
+		payload:   []byte(message),
+	}
+	if err := n.write(reply); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Errorf("io:node:write:error: %v", err)
+	}
+}
+
+// write queues the reply to be sent, failing only if the node is closed.
+func (n *Node) write(f frame) error {
+	n.mut.Lock()
+	defer n.mut.Unlock()
+
+	if n.closed {
+		return net.ErrClosed
+	}
+	n.replies = append(n.replies, f)
+	n.replied.Signal()
+	return nil
+}
+
+// send sends the replies queued, in order, until the node is closed.
+func (n *Node) send() {
+	writer := bufio.NewWriter(n.conn)
+
+	for {
+		n.mut.Lock()
+		for len(n.replies) == 0 && !n.closed {
+			n.replied.Wait()
+		}
+		if n.closed {
+			n.mut.Unlock()
+			return
+		}
+		replies := n.replies
+		n.replies = nil
+		n.mut.Unlock()
+
+		for _, f := range replies {
+			if err := writeFrame(writer, f); err != nil {
+				n.logWriteError(err)
+				return
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			n.logWriteError(err)
+			return
+		}
+	}
+}
+
+func (n *Node) logWriteError(err error) {
+	n.mut.Lock()
+	closed := n.closed
+	n.mut.Unlock()
+
+	if !closed && !errors.Is(err, net.ErrClosed) {
+		log.Errorf("io:node:write:error: %v", err)
+	}
+}
+
+// NewNode returns the connection to the node with the given index, listening
+// on the given path.
+func NewNode(path string, index int) *Node {
+	n := &Node{
+		path:     path,
+		index:    index,
+		handlers: make(map[handlerKey]Handler),
+		pending:  make(map[uint32]*Request),
+	}
+	n.replied = sync.NewCond(&n.mut)
+	return n
+}
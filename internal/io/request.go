@@ -0,0 +1,95 @@
+package io
+
+import "errors"
+
+// ErrCanceled is returned when replying to a request the node has canceled,
+// which already got its reply (see Node.cancel).  The handler must then undo
+// what it did for the request (e.g. unlock the mutex it locked), as the node
+// never learns of it.
+var ErrCanceled = errors.New("request canceled")
+
+// Handler carries out the requests for one action on one variable, from every
+// node.  It's called by the reader of the node that sent the request, which
+// can't read the node's next request until it returns, so a handler must
+// never wait on another node (e.g. to lock a mutex held by one): such requests
+// are carried out on a goroutine of their own, which replies once done.
+type Handler func(request *Request)
+
+type handlerKey struct {
+	opcode byte
+	id     string
+}
+
+// Request is a request read from a node, which must be replied to exactly
+// once, with either Reply or ReplyError.
+type Request struct {
+	// Node is the index of the node that sent the request, in its array.
+	Node    int
+	Payload []byte
+
+	node      *Node
+	opcode    byte
+	id        string
+	requestID uint32
+	// canceled is closed when the node cancels the request, and only made
+	// once asked for, see Canceled.  Both fields are guarded by node.mut.
+	canceled   chan struct{}
+	isCanceled bool
+}
+
+// ID returns the ID of the variable the request is for.
+func (r *Request) ID() string {
+	return r.id
+}
+
+// Action returns the action requested, e.g. get.
+func (r *Request) Action() string {
+	action, _ := GetAction(r.opcode)
+	return action
+}
+
+// Canceled returns a channel closed once the node cancels the request, which
+// requests waiting on other nodes can give up on.
+func (r *Request) Canceled() <-chan struct{} {
+	r.node.mut.Lock()
+	defer r.node.mut.Unlock()
+
+	if r.canceled == nil {
+		r.canceled = make(chan struct{})
+		if r.isCanceled {
+			close(r.canceled)
+		}
+	}
+	return r.canceled
+}
+
+// cancel marks the request as canceled, with node.mut held.
+func (r *Request) cancel() {
+	r.isCanceled = true
+	if r.canceled != nil {
+		close(r.canceled)
+	}
+}
+
+// Reply replies with the payload, failing with ErrCanceled if the node has
+// canceled the request (or net.ErrClosed if it's gone).
+func (r *Request) Reply(payload []byte) error {
+	return r.node.answer(r, r.reply(StatusOK, payload))
+}
+
+// ReplyError replies with the status and message instead of a value, which the
+// node raises as a GothonError.
+func (r *Request) ReplyError(status byte, message string) error {
+	return r.node.answer(r, r.reply(status, []byte(message)))
+}
+
+func (r *Request) reply(status byte, payload []byte) frame {
+	return frame{
+		version:   ProtocolVersion,
+		opcode:    r.opcode,
+		requestID: r.requestID,
+		status:    status,
+		id:        r.id,
+		payload:   payload,
+	}
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"sync"
+	gio "tonysoft.com/gothon/internal/io"
+)
+
+type AtomicValueType interface {
+	bool | int64 | float64 | string
+}
+
+type NumericValueType interface {
+	int64 | float64
+}
+
+// newFetchAdder returns the handler adding each value received to the
+// register's value (guarded by mut), replying with the value held before the
+// addition.
+func newFetchAdder[T AtomicValueType](mut *sync.Mutex, val *T, add func(val T, delta T) T) gio.Handler {
+	return newExchanger(mut, val, add)
+}
+
+// newSwapper returns the handler replacing the register's value (guarded by
+// mut) with each value received, replying with the value it replaced.
+func newSwapper[T AtomicValueType](mut *sync.Mutex, val *T) gio.Handler {
+	return newExchanger(mut, val, func(_ T, newVal T) T {
+		return newVal
+	})
+}
+
+// newExtremumUpdater returns the handler replacing the register's value
+// (guarded by mut) with each value received that is greater than it (for the
+// max operator) or less than it (for min), replying with the value held before
+// the update.
+func newExtremumUpdater[T NumericValueType](operator string, mut *sync.Mutex, val *T) gio.Handler {
+	return newExchanger(mut, val, func(val T, arg T) T {
+		if (operator == "max" && arg > val) || (operator == "min" && arg < val) {
+			return arg
+		}
+		return val
+	})
+}
+
+func isExtremumOperator(operator string) bool {
+	return operator == "max" || operator == "min"
+}
+
+// newCompareAndSwapper returns the handler replacing the register's value
+// (guarded by mut) only if it currently holds the expected value, replying with
+// whether it did.  The expected value is sent prefixed
+// with its length, followed by the new value.
+func newCompareAndSwapper[T AtomicValueType](mut *sync.Mutex, val *T) gio.Handler {
+	return func(request *gio.Request) {
+		expected, newVal, ok := decodeKeyVal[T, T](request.Payload)
+		if ok {
+			mut.Lock()
+			ok = *val == expected
+			if ok {
+				*val = newVal
+			}
+			mut.Unlock()
+		}
+
+		reply(request, encodeVal(ok))
+	}
+}
+
+func newExchanger[T AtomicValueType](mut *sync.Mutex, val *T, exchange func(val T, arg T) T) gio.Handler {
+	return func(request *gio.Request) {
+		arg := decodeVal[T](request.Payload)
+		mut.Lock()
+		prev := *val
+		*val = exchange(prev, arg)
+		mut.Unlock()
+
+		reply(request, encodeVal(prev))
+	}
+}
+
+// newGetter returns the handler replying with the register's value (guarded
+// by mut).
+func newGetter[T AtomicValueType](mut *sync.Mutex, val *T) gio.Handler {
+	return func(request *gio.Request) {
+		if len(request.Payload) != 0 {
+			replyBadRequest(request, "expected no payload, got %v", request.Payload)
+			return
+		}
+
+		mut.Lock()
+		outBytes := encodeVal(*val)
+		mut.Unlock()
+
+		reply(request, outBytes)
+	}
+}
+
+// newUpdater returns the handler replacing the register's value (guarded by
+// mut) with the result of update, given the value held and that received.
+func newUpdater[T AtomicValueType](mut *sync.Mutex, val *T, update func(val T, arg T) T) gio.Handler {
+	return func(request *gio.Request) {
+		arg := decodeVal[T](request.Payload)
+		mut.Lock()
+		*val = update(*val, arg)
+		mut.Unlock()
+
+		ack(request)
+	}
+}
+
+func setVal[T AtomicValueType](_ T, val T) T {
+	return val
+}
+
+func add[T int64 | float64 | string](val T, delta T) T {
+	return val + delta
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"encoding/binary"
+	"sync"
+	gio "tonysoft.com/gothon/internal/io"
+	"tonysoft.com/gothon/pkg/log"
+)
+
+// Barrier is the number of parties that must arrive at the barrier before any
+// of them can continue.
+type Barrier int
+
+// BarrierRegister releases the parties waiting on it once all have arrived,
+// then resets so it can be waited on again (each such round being a
+// generation).  The indexes of parties that left before then are kept in free,
+// to be given to the next ones to arrive.
+type BarrierRegister struct {
+	RegisterBase
+	mut        sync.Mutex
+	parties    int
+	arrived    int
+	free       []int
+	generation chan struct{}
+}
+
+func (r *BarrierRegister) Init() {
+	r.generation = make(chan struct{})
+}
+
+func (r *BarrierRegister) Handler(action string) (gio.Handler, bool) {
+	if action == "call" {
+		return r.handleWaiter, true
+	}
+	return nil, false
+}
+
+// arrive counts the caller as arrived, returning the order in which it did (0
+// for the first) and the generation to wait on until all parties have.
+func (r *BarrierRegister) arrive() (int, chan struct{}) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	index := r.arrived
+	if len(r.free) > 0 {
+		index = r.free[len(r.free)-1]
+		r.free = r.free[:len(r.free)-1]
+	}
+	generation := r.generation
+
+	r.arrived++
+	if r.arrived == r.parties {
+		close(generation)
+		r.generation = make(chan struct{})
+		r.arrived = 0
+		r.free = nil
+	}
+
+	return index, generation
+}
+
+// leave undoes the arrival of a party that stopped waiting on the generation,
+// unless it has been released already.
+func (r *BarrierRegister) leave(index int, generation chan struct{}) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+
+	r.arrived--
+	r.free = append(r.free, index)
+}
+
+func (r *BarrierRegister) handleWaiter(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:barrier:wait:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	canceled := request.Canceled()
+	index, generation := r.arrive()
+	go func() {
+		select {
+		case <-generation:
+		case <-canceled:
+			r.leave(index, generation)
+			return
+		}
+
+		outBytes := make([]byte, int32Length)
+		binary.BigEndian.PutUint32(outBytes, uint32(index))
+		reply(request, outBytes)
+	}()
+}
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	gio "tonysoft.com/gothon/internal/io"
+)
+
+// operationHeaderLength is the length of the fixed part of each operation in a
+// batch or transaction, which is made up of the following:
+//
+//	opcode    uint8   the action, as in frames (e.g. that of add)
+//	idLength  uint16  the length of the variable ID
+//	id        []byte  the ID of the variable operated on
+//	length    uint32  the length of the request
+//	request   []byte  the request, as it would be sent on its own
+const operationHeaderLength = 7
+
+var errTruncatedOperation = errors.New("truncated operation")
+
+// Batch is the type of the register applying batches of writes to the other
+// registers.
+type Batch struct{}
+
+// BatchRegister applies each batch of writes it receives as a whole, holding
+// the lock of every register written to until all writes have been applied
+// so that no node can observe a batch half done.  If any of the writes can't
+// be applied then none are.
+type BatchRegister struct {
+	RegisterBase
+	registry Registry
+}
+
+// batchable is implemented by the registers whose operations can be batched or
+// made within transactions, which are applied to a copy of the register's
+// value that's only stored once every operation has been applied.
+type batchable interface {
+	Register
+	lock()
+	unlock()
+	load() any
+	apply(val any, action string, request []byte) (any, error)
+	encode(val any) []byte
+	store(val any)
+}
+
+type operation struct {
+	register batchable
+	action   string
+	request  []byte
+}
+
+func (r *BatchRegister) Handler(action string) (gio.Handler, bool) {
+	if action == "call" {
+		return r.handleBatch, true
+	}
+	return nil, false
+}
+
+func (r *BatchRegister) handleBatch(request *gio.Request) {
+	writes, status, err := r.decode(request.Payload)
+	if err == nil {
+		status, err = gio.StatusBadRequest, r.apply(writes)
+	}
+
+	if err != nil {
+		replyError(request, status, err)
+		return
+	}
+	ack(request)
+}
+
+// decode returns the writes in the batch, failing with the status of the reply
+// if it's malformed or writes to a register that doesn't support batching.
+func (r *BatchRegister) decode(buff []byte) ([]operation, byte, error) {
+	writes := make([]operation, 0)
+
+	for len(buff) > 0 {
+		write, rest, status, err := decodeOperation(r.registry, buff)
+		if err != nil {
+			return nil, status, err
+		}
+
+		writes = append(writes, write)
+		buff = rest
+	}
+
+	return writes, gio.StatusOK, nil
+}
+
+// apply applies the writes in order, storing the new values of the registers
+// only if all of them could be applied.
+func (r *BatchRegister) apply(writes []operation) error {
+	registers := make([]batchable, 0)
+	vals := make(map[string]any)
+	for _, w := range writes {
+		if _, ok := vals[w.register.ID()]; !ok {
+			registers = append(registers, w.register)
+			vals[w.register.ID()] = nil
+		}
+	}
+
+	lockAll(registers)
+	defer unlockAll(registers)
+	for _, reg := range registers {
+		vals[reg.ID()] = reg.load()
+	}
+
+	for _, w := range writes {
+		val, err := w.register.apply(vals[w.register.ID()], w.action, w.request)
+		if err != nil {
+			return fmt.Errorf("%s on %s: %v", w.action, w.register.ID(), err)
+		}
+		vals[w.register.ID()] = val
+	}
+
+	for _, reg := range registers {
+		reg.store(vals[reg.ID()])
+	}
+
+	return nil
+}
+
+// lockAll locks the registers in the order of their IDs, so that batches and
+// transactions locking several registers can't deadlock by taking their locks
+// in different orders.  The registers are sorted in place.
+func lockAll(registers []batchable) {
+	sort.Slice(registers, func(i, j int) bool {
+		return registers[i].ID() < registers[j].ID()
+	})
+
+	for _, reg := range registers {
+		reg.lock()
+	}
+}
+
+func unlockAll(registers []batchable) {
+	for _, reg := range registers {
+		reg.unlock()
+	}
+}
+
+// decodeOperation returns the first operation in the buffer along with the
+// rest of it, failing with the status of the reply if the operation is
+// malformed or made on a register that isn't batchable.
+func decodeOperation(registry Registry, buff []byte) (operation, []byte, byte, error) {
+	if len(buff) < operationHeaderLength {
+		return operation{}, nil, gio.StatusBadRequest, errTruncatedOperation
+	}
+
+	opcode := buff[0]
+	action, ok := gio.GetAction(opcode)
+	idLength := int(binary.BigEndian.Uint16(buff[1:]))
+	if len(buff) < operationHeaderLength+idLength {
+		return operation{}, nil, gio.StatusBadRequest, errTruncatedOperation
+	}
+
+	id := string(buff[3 : 3+idLength])
+	length := int(binary.BigEndian.Uint32(buff[3+idLength:]))
+	buff = buff[operationHeaderLength+idLength:]
+	if len(buff) < length {
+		return operation{}, nil, gio.StatusBadRequest, errTruncatedOperation
+	}
+
+	register, isBatchable := registry[id].(batchable)
+	if !ok || !isBatchable {
+		return operation{}, nil, gio.StatusUnknownOperation, fmt.Errorf("no batchable operation with opcode %d on %s", opcode, id)
+	}
+
+	return operation{register, action, buff[:length]}, buff[length:], gio.StatusOK, nil
+}
+
+func errUnbatchableAction(action string) error {
+	return fmt.Errorf("action %s can't be batched", action)
+}
+
+func errRequestLength(expected int, got int) error {
+	return fmt.Errorf("expected %d bytes, got %d", expected, got)
+}
+
+// newApplier returns the handler applying each request to the register as a
+// batch of one write, refusing it if it can't be applied.
+func newApplier(register batchable) gio.Handler {
+	return func(request *gio.Request) {
+		register.lock()
+		val, err := register.apply(register.load(), request.Action(), request.Payload)
+		if err == nil {
+			register.store(val)
+		}
+		register.unlock()
+
+		if err != nil {
+			refuse(request, "%v", err)
+		} else {
+			ack(request)
+		}
+	}
+}
@@ -1,11 +1,8 @@
 package memory
 
 import (
-	"errors"
-	"io"
-	"net"
 	"sync"
-	"tonysoft.com/gothon/pkg/log"
+	gio "tonysoft.com/gothon/internal/io"
 )
 
 const (
@@ -18,68 +15,46 @@ type BoolRegister struct {
 	val bool
 }
 
-func (r *BoolRegister) Init() {
-	for i, s := range r.settersIn {
-		go r.processSetter(s, r.settersOut[i])
-	}
-
-	for i, g := range r.gettersIn {
-		go r.processGetter(g, r.gettersOut[i])
+func (r *BoolRegister) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "set":
+		return newUpdater(&r.mut, &r.val, setVal[bool]), true
+	case "get":
+		return newGetter(&r.mut, &r.val), true
+	case "swap":
+		return newSwapper(&r.mut, &r.val), true
+	case "cas":
+		return newCompareAndSwapper(&r.mut, &r.val), true
 	}
+	return nil, false
 }
 
-func (r *BoolRegister) processSetter(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, boolLength)
-	var readErr, writeErr error
-
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			r.mut.Lock()
-			r.val = inBytes[0] != 0
-			r.mut.Unlock()
+func (r *BoolRegister) lock() {
+	r.mut.Lock()
+}
 
-			_, writeErr = out.Write(syncBytes)
-			if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-				log.Errorf("register:bool:set:write:error: %v", writeErr)
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:bool:set:read:error: %v", readErr)
-			return
-		}
-	}
+func (r *BoolRegister) unlock() {
+	r.mut.Unlock()
 }
 
-func (r *BoolRegister) processGetter(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, 1)
-	outBytes := make([]byte, boolLength)
-	var readErr, writeErr error
+func (r *BoolRegister) load() any {
+	return r.val
+}
 
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			if inBytes[0] == syncByte {
-				r.mut.Lock()
-				if r.val {
-					outBytes[0] = 1
-				} else {
-					outBytes[0] = 0
-				}
-				r.mut.Unlock()
+func (r *BoolRegister) store(val any) {
+	r.val = val.(bool)
+}
 
-				_, writeErr = out.Write(outBytes)
-				if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-					log.Errorf("register:bool:get:write:error: %v", writeErr)
-					return
-				}
-			} else {
-				log.Errorf("register:bool:get:read:error: expected byte 22, got %d", inBytes[0])
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:bool:get:read:error: %v", readErr)
-			return
-		}
+func (r *BoolRegister) apply(val any, action string, request []byte) (any, error) {
+	if action != "set" {
+		return val, errUnbatchableAction(action)
+	}
+	if len(request) != boolLength {
+		return val, errRequestLength(boolLength, len(request))
 	}
+	return request[0] != 0, nil
+}
+
+func (r *BoolRegister) encode(val any) []byte {
+	return encodeVal(val.(bool))
 }
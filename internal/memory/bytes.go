@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	gio "tonysoft.com/gothon/internal/io"
+	"tonysoft.com/gothon/internal/memory/config"
+	"tonysoft.com/gothon/pkg/log"
+)
+
+const (
+	bytesPrefixLength = 4
+)
+
+type BytesRegister struct {
+	RegisterBase
+	mut        sync.Mutex
+	val        []byte
+	bufferSize uint32
+}
+
+func (r *BytesRegister) Init() {
+	r.bufferSize = config.GetStringRegisterBufferSize() + bytesPrefixLength
+}
+
+func (r *BytesRegister) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "set", "add":
+		return newApplier(r), true
+	case "get":
+		return r.handleGetter, true
+	}
+	return nil, false
+}
+
+func (r *BytesRegister) handleGetter(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:bytes:get:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	r.mut.Lock()
+	outBytes := encodeBytes(r.val)
+	r.mut.Unlock()
+
+	reply(request, outBytes)
+}
+
+func (r *BytesRegister) lock() {
+	r.mut.Lock()
+}
+
+func (r *BytesRegister) unlock() {
+	r.mut.Unlock()
+}
+
+func (r *BytesRegister) load() any {
+	return r.val
+}
+
+func (r *BytesRegister) store(val any) {
+	r.val = val.([]byte)
+}
+
+func (r *BytesRegister) apply(val any, action string, request []byte) (any, error) {
+	if len(request) > int(r.bufferSize) {
+		return val, errors.New("bytes value exceeds the maximum size")
+	}
+
+	decoded, ok := decodeBytes(request)
+	if !ok {
+		return val, errors.New("invalid bytes value")
+	}
+
+	switch action {
+	case "set":
+		return decoded, nil
+	case "add":
+		// the stored value may share its array, so the new one can't
+		return append(append([]byte{}, val.([]byte)...), decoded...), nil
+	default:
+		return val, errUnbatchableAction(action)
+	}
+}
+
+func (r *BytesRegister) encode(val any) []byte {
+	return encodeBytes(val.([]byte))
+}
@@ -0,0 +1,217 @@
+package memory
+
+import (
+	"sync"
+	"time"
+	gio "tonysoft.com/gothon/internal/io"
+	"tonysoft.com/gothon/internal/queue"
+	"tonysoft.com/gothon/pkg/log"
+)
+
+// Chan is the capacity of a channel, which is unbuffered if zero.
+type Chan[T queue.ItemType] int64
+
+// ChanRegister passes the values sent by nodes through a Go channel, so sends
+// block while it's full (or, if unbuffered, until another node receives) and
+// receives block while it's empty.  The channel itself is never closed, as a
+// node could be sending to it at the time, instead the closed channel is, which
+// wakes the nodes waiting on it.  Values already sent can still be received.
+// Values received for nodes that didn't get them are kept in returned, which is
+// received from before the channel, and wake is signalled as they're added.
+type ChanRegister[T queue.ItemType] struct {
+	RegisterBase
+	mut      sync.Mutex
+	val      chan T
+	closed   chan struct{}
+	returned []T
+	wake     chan struct{}
+}
+
+func (r *ChanRegister[T]) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "send":
+		return r.handleSender, true
+	case "recv", "iter":
+		return r.handleReceiver, true
+	case "close":
+		return r.handleCloser, true
+	}
+	return nil, false
+}
+
+func (r *ChanRegister[T]) isClosed() bool {
+	select {
+	case <-r.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// send returns StatusOK if the value was sent, StatusClosed if the channel is
+// closed, StatusTimeout if the timeout elapsed first or StatusCanceled if the
+// request was.
+func (r *ChanRegister[T]) send(val T, timeout time.Duration, hasTimeout bool, canceled <-chan struct{}) byte {
+	if r.isClosed() {
+		return gio.StatusClosed
+	}
+
+	select {
+	case r.val <- val:
+		return gio.StatusOK
+	default:
+	}
+
+	var expired <-chan time.Time
+	if hasTimeout {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case r.val <- val:
+		return gio.StatusOK
+	case <-r.closed:
+		return gio.StatusClosed
+	case <-expired:
+		return gio.StatusTimeout
+	case <-canceled:
+		return gio.StatusCanceled
+	}
+}
+
+// putBack returns a value received for a node that didn't get it, so that it's
+// received again before the values still in the channel.
+func (r *ChanRegister[T]) putBack(val T) {
+	r.mut.Lock()
+	r.returned = append(r.returned, val)
+	r.mut.Unlock()
+	r.signal()
+}
+
+// takeReturned returns the first of the values put back, if any.
+func (r *ChanRegister[T]) takeReturned() (val T, ok bool) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if len(r.returned) == 0 {
+		return val, false
+	}
+
+	val = r.returned[0]
+	r.returned = r.returned[1:]
+	if len(r.returned) > 0 {
+		// pass the wakeup on to another waiting receiver
+		r.signal()
+	}
+	return val, true
+}
+
+func (r *ChanRegister[T]) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// receive returns the next value and StatusOK, or StatusClosed if the channel
+// is closed and drained, StatusTimeout if the timeout elapsed first or
+// StatusCanceled if the request was.
+func (r *ChanRegister[T]) receive(timeout time.Duration, hasTimeout bool, canceled <-chan struct{}) (val T, status byte) {
+	if val, ok := r.takeReturned(); ok {
+		return val, gio.StatusOK
+	}
+
+	select {
+	case val = <-r.val:
+		return val, gio.StatusOK
+	default:
+	}
+
+	var expired <-chan time.Time
+	if hasTimeout {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case val = <-r.val:
+			return val, gio.StatusOK
+		case <-r.wake:
+			if val, ok := r.takeReturned(); ok {
+				return val, gio.StatusOK
+			}
+		case <-r.closed:
+			// values sent before it was closed are still received
+			if val, ok := r.takeReturned(); ok {
+				return val, gio.StatusOK
+			}
+			select {
+			case val = <-r.val:
+				return val, gio.StatusOK
+			default:
+				return val, gio.StatusClosed
+			}
+		case <-expired:
+			return val, gio.StatusTimeout
+		case <-canceled:
+			return val, gio.StatusCanceled
+		}
+	}
+}
+
+func (r *ChanRegister[T]) handleSender(request *gio.Request) {
+	if len(request.Payload) < float64Length {
+		log.Errorf("register:chan:send:read:error: expected at least %d bytes, got %d", float64Length, len(request.Payload))
+		replyBadRequest(request, "expected at least %d bytes, got %d", float64Length, len(request.Payload))
+		return
+	}
+
+	timeout, hasTimeout := decodeTimeout(request.Payload)
+	val := decodeVal[T](request.Payload[float64Length:])
+	canceled := request.Canceled()
+	go func() {
+		replyStatus(request, r.send(val, timeout, hasTimeout, canceled), nil)
+	}()
+}
+
+// handleReceiver replies with the value received, or with the status saying
+// why there was none.  Used for both recv() and iterating over the channel.  A
+// value the node doesn't get the reply with is put back, see putBack.
+func (r *ChanRegister[T]) handleReceiver(request *gio.Request) {
+	timeout, hasTimeout := decodeTimeout(request.Payload)
+	canceled := request.Canceled()
+	go func() {
+		val, status := r.receive(timeout, hasTimeout, canceled)
+		if !replyStatus(request, status, encodeVal(val)) && status == gio.StatusOK {
+			r.putBack(val)
+		}
+	}()
+}
+
+// handleCloser closes the channel, replying with StatusClosed if it already
+// was.
+func (r *ChanRegister[T]) handleCloser(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:chan:close:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	ok := false
+	r.mut.Lock()
+	if !r.isClosed() {
+		close(r.closed)
+		ok = true
+	}
+	r.mut.Unlock()
+
+	if ok {
+		ack(request)
+	} else {
+		replyError(request, gio.StatusClosed, errClosed)
+	}
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+	"tonysoft.com/gothon/internal/queue"
+)
+
+func decodeVal[T queue.ItemType](buff []byte) T {
+	var val any
+	switch any(*new(T)).(type) {
+	case bool:
+		val = len(buff) > 0 && buff[0] != 0
+	case int64:
+		if len(buff) < int64Length {
+			return *new(T)
+		}
+		val = int64(binary.BigEndian.Uint64(buff))
+	case float64:
+		if len(buff) < float64Length {
+			return *new(T)
+		}
+		val = math.Float64frombits(binary.BigEndian.Uint64(buff))
+	case string:
+		val = string(buff)
+	case []byte:
+		val = append([]byte{}, buff...)
+	}
+	return val.(T)
+}
+
+func encodeVal[T queue.ItemType](val T) []byte {
+	switch v := any(val).(type) {
+	case bool:
+		if v {
+			return []byte{1}
+		}
+		return []byte{0}
+	case int64:
+		buff := make([]byte, int64Length)
+		binary.BigEndian.PutUint64(buff, uint64(v))
+		return buff
+	case float64:
+		buff := make([]byte, float64Length)
+		binary.BigEndian.PutUint64(buff, math.Float64bits(v))
+		return buff
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	}
+	return nil
+}
+
+// decodeKeyVal splits a buffer holding a length-prefixed key followed by a
+// value, as sent by the generated setters of keyed registers.
+func decodeKeyVal[K queue.ItemType, V queue.ItemType](buff []byte) (K, V, bool) {
+	if len(buff) < 4 {
+		return *new(K), *new(V), false
+	}
+
+	keyLength := int(binary.BigEndian.Uint32(buff))
+	if len(buff) < 4+keyLength {
+		return *new(K), *new(V), false
+	}
+
+	return decodeVal[K](buff[4 : 4+keyLength]), decodeVal[V](buff[4+keyLength:]), true
+}
+
+// encodeVals encodes a sequence of values, each prefixed with its length, as
+// read by the generated functions returning lists.
+func encodeVals[T queue.ItemType](vals []T) []byte {
+	buff := make([]byte, 0)
+	lengthBytes := make([]byte, 4)
+	for _, v := range vals {
+		valBytes := encodeVal(v)
+		binary.BigEndian.PutUint32(lengthBytes, uint32(len(valBytes)))
+		buff = append(append(buff, lengthBytes...), valBytes...)
+	}
+	return buff
+}
+
+// decodeBytes returns a copy of the value held in a length-prefixed buffer, as
+// sent by the generated functions for bytes, failing if the length doesn't
+// match.
+func decodeBytes(buff []byte) ([]byte, bool) {
+	if len(buff) < bytesPrefixLength {
+		return nil, false
+	}
+
+	length := int(binary.BigEndian.Uint32(buff))
+	if len(buff)-bytesPrefixLength != length {
+		return nil, false
+	}
+
+	return append([]byte{}, buff[bytesPrefixLength:]...), true
+}
+
+// encodeBytes prefixes the value with its length.
+func encodeBytes(val []byte) []byte {
+	buff := make([]byte, bytesPrefixLength, bytesPrefixLength+len(val))
+	binary.BigEndian.PutUint32(buff, uint32(len(val)))
+	return append(buff, val...)
+}
+
+// decodeTimeout returns the timeout of a blocking operation, given in seconds,
+// where a negative value means the operation never times out.
+func decodeTimeout(buff []byte) (time.Duration, bool) {
+	seconds := decodeVal[float64](buff)
+	if seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+	gio "tonysoft.com/gothon/internal/io"
+)
+
+// Lock is the mutex a condition is tied to, which must be held by the nodes
+// waiting on or notifying the condition.
+type Lock interface {
+	lock(node int)
+	unlock(node int) bool
+	// heldBy reports whether the node holds the mutex.
+	heldBy(node int) bool
+}
+
+// Condition is the lock of a condition, as held by the register of the mutex
+// it's tied to.
+type Condition struct {
+	L Lock
+}
+
+// ConditionRegister wakes the nodes waiting on it when notified, each waiting
+// node being given its own channel (closed to wake it) in order of arrival.
+type ConditionRegister struct {
+	RegisterBase
+	mut     sync.Mutex
+	lock    Lock
+	waiters []chan struct{}
+}
+
+func (r *ConditionRegister) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "wait":
+		return r.handleWaiter, true
+	case "notify", "notifyall":
+		return func(request *gio.Request) {
+			r.handleNotifier(action, request)
+		}, true
+	}
+	return nil, false
+}
+
+// release adds a waiter, then releases the lock held by the node until the
+// waiter is notified (see wait).
+func (r *ConditionRegister) release(node int) chan struct{} {
+	waiter := make(chan struct{})
+	r.mut.Lock()
+	r.waiters = append(r.waiters, waiter)
+	r.mut.Unlock()
+
+	r.lock.unlock(node)
+	return waiter
+}
+
+// wait waits for the waiter to be notified, or for the timeout to elapse or
+// the request to be canceled, reporting whether it was notified once the node
+// holds the lock again.
+func (r *ConditionRegister) wait(node int, waiter chan struct{}, timeout time.Duration, hasTimeout bool, canceled <-chan struct{}) bool {
+	defer r.lock.lock(node)
+
+	var expired <-chan time.Time
+	if hasTimeout {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case <-waiter:
+		return true
+	case <-expired:
+	case <-canceled:
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	for i, w := range r.waiters {
+		if w == waiter {
+			r.waiters = append(r.waiters[:i], r.waiters[i+1:]...)
+			return false
+		}
+	}
+
+	// notified after timing out but before being removed from the waiters
+	return true
+}
+
+func (r *ConditionRegister) notify(n int) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if n < 0 {
+		n = 0
+	} else if n > len(r.waiters) {
+		n = len(r.waiters)
+	}
+
+	for _, w := range r.waiters[:n] {
+		close(w)
+	}
+	r.waiters = r.waiters[n:]
+}
+
+// handleWaiter replies once notified, or with StatusTimeout if the timeout
+// received elapsed first.  Waiting without holding the lock is a bad request,
+// while the lock is released again if the node doesn't get the reply.
+func (r *ConditionRegister) handleWaiter(request *gio.Request) {
+	if !r.lock.heldBy(request.Node) {
+		replyBadRequest(request, "cannot wait on un-acquired lock")
+		return
+	}
+
+	timeout, hasTimeout := decodeTimeout(request.Payload)
+	waiter := r.release(request.Node)
+	go func() {
+		var replied bool
+		if r.wait(request.Node, waiter, timeout, hasTimeout, request.Canceled()) {
+			replied = ack(request)
+		} else {
+			replied = replyError(request, gio.StatusTimeout, errTimedOut)
+		}
+		if !replied {
+			r.lock.unlock(request.Node)
+		}
+	}()
+}
+
+// handleNotifier wakes the number of waiting nodes received (for notify) or
+// all of them (for notifyall).  Notifying without holding the lock is a bad
+// request.
+func (r *ConditionRegister) handleNotifier(operator string, request *gio.Request) {
+	n := math.MaxInt32
+	if operator == "notify" && len(request.Payload) == int32Length {
+		n = int(int32(binary.BigEndian.Uint32(request.Payload)))
+	}
+
+	if !r.lock.heldBy(request.Node) {
+		replyBadRequest(request, "cannot notify on un-acquired lock")
+		return
+	}
+
+	r.notify(n)
+	ack(request)
+}
@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	gio "tonysoft.com/gothon/internal/io"
+	"tonysoft.com/gothon/internal/queue"
+	"tonysoft.com/gothon/pkg/log"
+)
+
+type DictKeyType interface {
+	int64 | string
+}
+
+type DictRegister[K DictKeyType, V queue.ItemType] struct {
+	RegisterBase
+	mut sync.Mutex
+	val map[K]V
+}
+
+func (r *DictRegister[K, V]) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "set":
+		return r.handleSetter, true
+	case "get":
+		return r.handleGetter, true
+	case "add":
+		return r.handleAdder, true
+	case "del":
+		return r.handleDeleter, true
+	case "contains":
+		return r.handleContainsCaller, true
+	case "size":
+		return r.handleSizeCaller, true
+	case "keys":
+		return r.handleKeysCaller, true
+	}
+	return nil, false
+}
+
+func (r *DictRegister[K, V]) handleSetter(request *gio.Request) {
+	key, val, ok := decodeKeyVal[K, V](request.Payload)
+	if !ok {
+		replyBadRequest(request, "truncated key")
+		return
+	}
+
+	r.mut.Lock()
+	r.val[key] = val
+	r.mut.Unlock()
+
+	ack(request)
+}
+
+func (r *DictRegister[K, V]) handleGetter(request *gio.Request) {
+	r.mut.Lock()
+	val, found := r.val[decodeVal[K](request.Payload)]
+	r.mut.Unlock()
+
+	if found {
+		reply(request, encodeVal(val))
+	} else {
+		refuse(request, "key not found")
+	}
+}
+
+func (r *DictRegister[K, V]) handleAdder(request *gio.Request) {
+	key, delta, ok := decodeKeyVal[K, V](request.Payload)
+	if !ok {
+		replyBadRequest(request, "truncated key")
+		return
+	}
+
+	r.mut.Lock()
+	sum, ok := addVal(r.val[key], delta)
+	if ok {
+		r.val[key] = sum
+	}
+	r.mut.Unlock()
+
+	if ok {
+		ack(request)
+	} else {
+		refuse(request, "values can't be added")
+	}
+}
+
+func (r *DictRegister[K, V]) handleDeleter(request *gio.Request) {
+	key := decodeVal[K](request.Payload)
+	r.mut.Lock()
+	_, found := r.val[key]
+	delete(r.val, key)
+	r.mut.Unlock()
+
+	if found {
+		ack(request)
+	} else {
+		refuse(request, "key not found")
+	}
+}
+
+func (r *DictRegister[K, V]) handleContainsCaller(request *gio.Request) {
+	r.mut.Lock()
+	_, found := r.val[decodeVal[K](request.Payload)]
+	r.mut.Unlock()
+
+	reply(request, encodeVal(found))
+}
+
+func (r *DictRegister[K, V]) handleSizeCaller(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:dict:size:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	r.mut.Lock()
+	size := int64(len(r.val))
+	r.mut.Unlock()
+
+	reply(request, encodeVal(size))
+}
+
+func (r *DictRegister[K, V]) handleKeysCaller(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:dict:keys:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	r.mut.Lock()
+	keys := make([]K, 0, len(r.val))
+	for k := range r.val {
+		keys = append(keys, k)
+	}
+	r.mut.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	reply(request, encodeVals(keys))
+}
+
+func addVal[V queue.ItemType](val V, delta V) (V, bool) {
+	var sum any
+	switch v := any(val).(type) {
+	case int64:
+		sum = v + any(delta).(int64)
+	case float64:
+		sum = v + any(delta).(float64)
+	case string:
+		sum = v + any(delta).(string)
+	default:
+		return val, false
+	}
+	return sum.(V), true
+}
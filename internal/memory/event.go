@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"sync"
+	"time"
+	gio "tonysoft.com/gothon/internal/io"
+	"tonysoft.com/gothon/pkg/log"
+)
+
+// Event is the initial state of an event, which is set if true.
+type Event bool
+
+// EventRegister wakes the nodes waiting on it once set, which it stays until
+// cleared.  The set channel is closed when the event gets set, and replaced
+// when it gets cleared.
+type EventRegister struct {
+	RegisterBase
+	mut sync.Mutex
+	set chan struct{}
+}
+
+func (r *EventRegister) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "set", "clear", "isset":
+		return func(request *gio.Request) {
+			r.handleOperator(action, request)
+		}, true
+	case "wait":
+		return r.handleWaiter, true
+	}
+	return nil, false
+}
+
+func (r *EventRegister) isSet() bool {
+	select {
+	case <-r.set:
+		return true
+	default:
+		return false
+	}
+}
+
+// handleOperator sets or clears the event, or (for isset) replies with whether
+// it's set.
+func (r *EventRegister) handleOperator(operator string, request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:event:%s:read:error: expected no payload, got %v", operator, request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	var isSet bool
+	r.mut.Lock()
+	switch operator {
+	case "set":
+		if !r.isSet() {
+			close(r.set)
+		}
+	case "clear":
+		if r.isSet() {
+			r.set = make(chan struct{})
+		}
+	case "isset":
+		isSet = r.isSet()
+	}
+	r.mut.Unlock()
+
+	if operator == "isset" {
+		reply(request, encodeVal(isSet))
+	} else {
+		ack(request)
+	}
+}
+
+// handleWaiter replies once the event is set, or with StatusTimeout if the
+// timeout received elapses first.  It stops waiting if the node cancels the
+// request.
+func (r *EventRegister) handleWaiter(request *gio.Request) {
+	r.mut.Lock()
+	set := r.set
+	r.mut.Unlock()
+
+	select {
+	case <-set:
+		ack(request)
+		return
+	default:
+	}
+
+	timeout, hasTimeout := decodeTimeout(request.Payload)
+	go func() {
+		var expired <-chan time.Time
+		if hasTimeout {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			expired = timer.C
+		}
+
+		select {
+		case <-set:
+			ack(request)
+		case <-expired:
+			replyError(request, gio.StatusTimeout, errTimedOut)
+		case <-request.Canceled():
+		}
+	}()
+}
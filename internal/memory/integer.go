@@ -3,197 +3,190 @@ package memory
 import (
 	"encoding/binary"
 	"errors"
-	"io"
-	"net"
+	"fmt"
 	"sync"
-	"tonysoft.com/gothon/pkg/log"
+	gio "tonysoft.com/gothon/internal/io"
 )
 
 const (
 	int64Length = 8
 )
 
+// intOperators implement the augmented assignments (with Python's semantics)
+// that don't have their own register methods, returning false if the operand
+// is invalid for the operator.
+var intOperators = map[string]func(val int64, operand int64) (int64, bool){
+	"floordiv": func(val int64, operand int64) (int64, bool) {
+		if operand == 0 {
+			return val, false
+		}
+		quotient := val / operand
+		if val%operand != 0 && (val < 0) != (operand < 0) {
+			quotient--
+		}
+		return quotient, true
+	},
+	"mod": func(val int64, operand int64) (int64, bool) {
+		if operand == 0 {
+			return val, false
+		}
+		remainder := val % operand
+		if remainder != 0 && (remainder < 0) != (operand < 0) {
+			remainder += operand
+		}
+		return remainder, true
+	},
+	"pow": func(val int64, operand int64) (int64, bool) {
+		if operand < 0 {
+			return val, false
+		}
+		result := int64(1)
+		for ; operand > 0; operand >>= 1 {
+			if operand&1 == 1 {
+				result *= val
+			}
+			val *= val
+		}
+		return result, true
+	},
+	"and": func(val int64, operand int64) (int64, bool) {
+		return val & operand, true
+	},
+	"or": func(val int64, operand int64) (int64, bool) {
+		return val | operand, true
+	},
+	"xor": func(val int64, operand int64) (int64, bool) {
+		return val ^ operand, true
+	},
+	"lshift": func(val int64, operand int64) (int64, bool) {
+		if operand < 0 {
+			return val, false
+		}
+		return val << operand, true
+	},
+	"rshift": func(val int64, operand int64) (int64, bool) {
+		if operand < 0 {
+			return val, false
+		}
+		return val >> operand, true
+	},
+}
+
 type IntRegister struct {
 	RegisterBase
 	mut sync.Mutex
 	val int64
 }
 
-func (r *IntRegister) Init() {
-	for i, s := range r.settersIn {
-		go r.processSetter(s, r.settersOut[i])
-	}
-
-	for i, g := range r.gettersIn {
-		go r.processGetter(g, r.gettersOut[i])
+func (r *IntRegister) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "set":
+		return newUpdater(&r.mut, &r.val, setVal[int64]), true
+	case "get":
+		return newGetter(&r.mut, &r.val), true
+	case "add":
+		return newUpdater(&r.mut, &r.val, add[int64]), true
+	case "sub":
+		return newUpdater(&r.mut, &r.val, func(val int64, delta int64) int64 {
+			return val - delta
+		}), true
+	case "mul":
+		return newUpdater(&r.mut, &r.val, func(val int64, multiplier int64) int64 {
+			return val * multiplier
+		}), true
+	case "div":
+		return r.newOperator(func(val int64, divisor int64) (int64, bool) {
+			if divisor == 0 {
+				return val, false
+			}
+			return val / divisor, true
+		}), true
+	case "fetch_add":
+		return newFetchAdder(&r.mut, &r.val, add[int64]), true
+	case "swap":
+		return newSwapper(&r.mut, &r.val), true
+	case "cas":
+		return newCompareAndSwapper(&r.mut, &r.val), true
+	case "max", "min":
+		return newExtremumUpdater(action, &r.mut, &r.val), true
 	}
 
-	for i, a := range r.addersIn {
-		go r.processAdder(a, r.addersOut[i])
+	if apply, ok := intOperators[action]; ok {
+		return r.newOperator(apply), true
 	}
+	return nil, false
+}
 
-	for i, s := range r.subtractorsIn {
-		go r.processSubtractor(s, r.subtractorsOut[i])
-	}
+// newOperator returns the handler applying the operator, refusing the request
+// if the operand received is invalid for it.
+func (r *IntRegister) newOperator(apply func(val int64, operand int64) (int64, bool)) gio.Handler {
+	return func(request *gio.Request) {
+		operand := decodeVal[int64](request.Payload)
+		r.mut.Lock()
+		val, ok := apply(r.val, operand)
+		if ok {
+			r.val = val
+		}
+		r.mut.Unlock()
 
-	for i, m := range r.multipliersIn {
-		go r.processMultiplier(m, r.multipliersOut[i])
+		if ok {
+			ack(request)
+		} else {
+			refuse(request, "invalid operand %d", operand)
+		}
 	}
+}
 
-	for i, d := range r.dividersIn {
-		go r.processDivider(d, r.dividersOut[i])
-	}
+func (r *IntRegister) lock() {
+	r.mut.Lock()
 }
 
-func (r *IntRegister) processSetter(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, int64Length)
-	var val int64
-	var readErr, writeErr error
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			val = int64(binary.BigEndian.Uint64(inBytes))
-			r.mut.Lock()
-			r.val = val
-			r.mut.Unlock()
+func (r *IntRegister) unlock() {
+	r.mut.Unlock()
+}
 
-			_, writeErr = out.Write(syncBytes)
-			if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-				log.Errorf("register:int:set:write:error: %v", writeErr)
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:int:set:read:error: %v", readErr)
-			return
-		}
-	}
+func (r *IntRegister) load() any {
+	return r.val
 }
 
-func (r *IntRegister) processGetter(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, 1)
-	outBytes := make([]byte, int64Length)
-	var readErr, writeErr error
-
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			if inBytes[0] == syncByte {
-				r.mut.Lock()
-				binary.BigEndian.PutUint64(outBytes, uint64(r.val))
-				r.mut.Unlock()
-
-				_, writeErr = out.Write(outBytes)
-				if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-					log.Errorf("register:int:get:write:error: %v", writeErr)
-					return
-				}
-			} else {
-				log.Errorf("register:int:get:read:error: expected byte 22, got %d", inBytes[0])
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:int:get:read:error: %v", readErr)
-			return
-		}
-	}
+func (r *IntRegister) store(val any) {
+	r.val = val.(int64)
 }
 
-func (r *IntRegister) processAdder(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, int64Length)
-	var delta int64
-	var readErr, writeErr error
-
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			delta = int64(binary.BigEndian.Uint64(inBytes))
-			r.mut.Lock()
-			r.val += delta
-			r.mut.Unlock()
-
-			_, writeErr = out.Write(syncBytes)
-			if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-				log.Errorf("register:int:add:write:error: %v", writeErr)
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:int:add:read:error: %v", readErr)
-			return
+func (r *IntRegister) apply(val any, action string, request []byte) (any, error) {
+	if len(request) != int64Length {
+		return val, errRequestLength(int64Length, len(request))
+	}
+	operand := int64(binary.BigEndian.Uint64(request))
+
+	switch action {
+	case "set":
+		return operand, nil
+	case "add":
+		return val.(int64) + operand, nil
+	case "sub":
+		return val.(int64) - operand, nil
+	case "mul":
+		return val.(int64) * operand, nil
+	case "div":
+		if operand == 0 {
+			return val, errors.New("division by zero")
 		}
+		return val.(int64) / operand, nil
 	}
-}
 
-func (r *IntRegister) processSubtractor(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, int64Length)
-	var delta int64
-	var readErr, writeErr error
-
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			delta = int64(binary.BigEndian.Uint64(inBytes))
-			r.mut.Lock()
-			r.val -= delta
-			r.mut.Unlock()
-
-			_, writeErr = out.Write(syncBytes)
-			if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-				log.Errorf("register:int:sub:write:error: %v", writeErr)
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:int:sub:read:error: %v", readErr)
-			return
-		}
+	apply, ok := intOperators[action]
+	if !ok {
+		return val, errUnbatchableAction(action)
 	}
-}
 
-func (r *IntRegister) processMultiplier(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, int64Length)
-	var multiplier int64
-	var readErr, writeErr error
-
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			multiplier = int64(binary.BigEndian.Uint64(inBytes))
-			r.mut.Lock()
-			r.val *= multiplier
-			r.mut.Unlock()
-
-			_, writeErr = out.Write(syncBytes)
-			if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-				log.Errorf("register:int:mul:write:error: %v", writeErr)
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:int:mul:read:error: %v", readErr)
-			return
-		}
+	result, ok := apply(val.(int64), operand)
+	if !ok {
+		return val, fmt.Errorf("invalid operand for %s: %d", action, operand)
 	}
+	return result, nil
 }
 
-func (r *IntRegister) processDivider(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, int64Length)
-	var divider int64
-	var readErr, writeErr error
-
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			divider = int64(binary.BigEndian.Uint64(inBytes))
-			r.mut.Lock()
-			r.val /= divider
-			r.mut.Unlock()
-
-			_, writeErr = out.Write(syncBytes)
-			if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-				log.Errorf("register:int:div:write:error: %v", writeErr)
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:int:div:read:error: %v", readErr)
-			return
-		}
-	}
+func (r *IntRegister) encode(val any) []byte {
+	return encodeVal(val.(int64))
 }
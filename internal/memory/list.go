@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"encoding/binary"
+	"sync"
+	gio "tonysoft.com/gothon/internal/io"
+	"tonysoft.com/gothon/internal/queue"
+	"tonysoft.com/gothon/pkg/log"
+)
+
+type ListRegister[T queue.ItemType] struct {
+	RegisterBase
+	mut sync.Mutex
+	val []T
+}
+
+func (r *ListRegister[T]) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "set":
+		return r.handleSetter, true
+	case "get":
+		return r.handleGetter, true
+	case "append":
+		return r.handleAppender, true
+	case "slice":
+		return r.handleSliceCaller, true
+	case "size":
+		return r.handleSizeCaller, true
+	}
+	return nil, false
+}
+
+// index converts a (possibly negative) Python index into one within the list,
+// returning false if it's out of range.
+func (r *ListRegister[T]) index(i int64) (int64, bool) {
+	if i < 0 {
+		i += int64(len(r.val))
+	}
+	return i, i >= 0 && i < int64(len(r.val))
+}
+
+// bounds converts the start and stop of a Python slice into bounds within
+// the list, where nil indicates the bound was omitted.
+func (r *ListRegister[T]) bounds(start, stop *int64) (int64, int64) {
+	length := int64(len(r.val))
+	clamp := func(i *int64, def int64) int64 {
+		if i == nil {
+			return def
+		}
+		v := *i
+		if v < 0 {
+			v += length
+		}
+		if v < 0 {
+			return 0
+		}
+		if v > length {
+			return length
+		}
+		return v
+	}
+
+	from, to := clamp(start, 0), clamp(stop, length)
+	if from > to {
+		from = to
+	}
+	return from, to
+}
+
+func (r *ListRegister[T]) handleSetter(request *gio.Request) {
+	if len(request.Payload) < int64Length {
+		log.Errorf("register:list:set:read:error: expected at least %d bytes, got %d", int64Length, len(request.Payload))
+		replyBadRequest(request, "expected at least %d bytes, got %d", int64Length, len(request.Payload))
+		return
+	}
+
+	val := decodeVal[T](request.Payload[int64Length:])
+	r.mut.Lock()
+	i, ok := r.index(decodeVal[int64](request.Payload))
+	if ok {
+		r.val[i] = val
+	}
+	r.mut.Unlock()
+
+	if ok {
+		ack(request)
+	} else {
+		refuse(request, "index out of range")
+	}
+}
+
+func (r *ListRegister[T]) handleGetter(request *gio.Request) {
+	var val T
+	r.mut.Lock()
+	i, ok := r.index(decodeVal[int64](request.Payload))
+	if ok {
+		val = r.val[i]
+	}
+	r.mut.Unlock()
+
+	if ok {
+		reply(request, encodeVal(val))
+	} else {
+		refuse(request, "index out of range")
+	}
+}
+
+func (r *ListRegister[T]) handleAppender(request *gio.Request) {
+	val := decodeVal[T](request.Payload)
+	r.mut.Lock()
+	r.val = append(r.val, val)
+	r.mut.Unlock()
+
+	ack(request)
+}
+
+func (r *ListRegister[T]) handleSliceCaller(request *gio.Request) {
+	inBytes := request.Payload
+	if len(inBytes) != 2*(1+int64Length) {
+		log.Errorf("register:list:slice:read:error: %v", errRequestLength(2*(1+int64Length), len(inBytes)))
+		replyError(request, gio.StatusBadRequest, errRequestLength(2*(1+int64Length), len(inBytes)))
+		return
+	}
+
+	var start, stop *int64
+	if inBytes[0] != 0 {
+		v := int64(binary.BigEndian.Uint64(inBytes[1:]))
+		start = &v
+	}
+	if inBytes[1+int64Length] != 0 {
+		v := int64(binary.BigEndian.Uint64(inBytes[2+int64Length:]))
+		stop = &v
+	}
+
+	r.mut.Lock()
+	from, to := r.bounds(start, stop)
+	outBytes := encodeVals(r.val[from:to])
+	r.mut.Unlock()
+
+	reply(request, outBytes)
+}
+
+func (r *ListRegister[T]) handleSizeCaller(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:list:size:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	r.mut.Lock()
+	size := int64(len(r.val))
+	r.mut.Unlock()
+
+	reply(request, encodeVal(size))
+}
@@ -1,76 +1,100 @@
 package memory
 
 import (
-	"errors"
-	"io"
-	"net"
 	"sync"
+	gio "tonysoft.com/gothon/internal/io"
 	"tonysoft.com/gothon/pkg/log"
 )
 
+// MutexRegister is a mutex held by one node at a time, which is tracked so
+// that a node unlocking a mutex it doesn't hold gets an error back rather than
+// unlocking it for the holder (or crashing Gothon if it isn't held at all).
 type MutexRegister struct {
 	RegisterBase
-	val sync.Mutex
+	val    sync.Mutex
+	mut    sync.Mutex
+	locked bool
+	owner  int
 }
 
-func (r *MutexRegister) Init() {
-	for i, l := range r.lockersIn {
-		go r.processLocker(l, r.lockersOut[i])
+func (r *MutexRegister) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "lock":
+		return newLocker("mutex", action, r.lock, r.unlock), true
+	case "unlock":
+		return newUnlocker("mutex", action, r.unlock), true
 	}
+	return nil, false
+}
 
-	for i, u := range r.unlockersIn {
-		go r.processUnlocker(u, r.unlockersOut[i])
-	}
+// Locker returns the mutex, for the conditions tied to it.
+func (r *MutexRegister) Locker() Lock {
+	return r
+}
+
+func (r *MutexRegister) lock(node int) {
+	r.val.Lock()
+	r.mut.Lock()
+	r.locked = true
+	r.owner = node
+	r.mut.Unlock()
 }
 
-func (r *MutexRegister) processLocker(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, 1)
-	var readErr, writeErr error
+// unlock unlocks the mutex if the node holds it, reporting whether it did.
+func (r *MutexRegister) unlock(node int) bool {
+	r.mut.Lock()
+	if !r.locked || r.owner != node {
+		r.mut.Unlock()
+		return false
+	}
+	r.locked = false
+	r.mut.Unlock()
 
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			if inBytes[0] == syncByte {
-				r.val.Lock()
+	r.val.Unlock()
+	return true
+}
 
-				_, writeErr = out.Write(syncBytes)
-				if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-					log.Errorf("register:mutex:lock:write:error: %v", writeErr)
-					return
-				}
-			} else {
-				log.Errorf("register:mutex:lock:read:error: expected byte 22, got %d", inBytes[0])
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:mutex:lock:read:error: %v", readErr)
+func (r *MutexRegister) heldBy(node int) bool {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.locked && r.owner == node
+}
+
+// newLocker returns the handler locking a mutex for the node sending the
+// request, which is done on a goroutine of its own as the mutex may be held by
+// another node.  The mutex is unlocked again if the node canceled the request
+// in the meantime.
+func newLocker(kind string, action string, lockFunc func(node int), unlockFunc func(node int) bool) gio.Handler {
+	return func(request *gio.Request) {
+		if len(request.Payload) != 0 {
+			log.Errorf("register:%s:%s:read:error: expected no payload, got %v", kind, action, request.Payload)
+			replyBadRequest(request, "expected no payload, got %v", request.Payload)
 			return
 		}
+
+		go func() {
+			lockFunc(request.Node)
+			if !ack(request) {
+				unlockFunc(request.Node)
+			}
+		}()
 	}
 }
 
-func (r *MutexRegister) processUnlocker(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, 1)
-	var readErr, writeErr error
-
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			if inBytes[0] == syncByte {
-				r.val.Unlock()
+// newUnlocker returns the handler unlocking a mutex for the node sending the
+// request, which is a bad request if the node doesn't hold it.
+func newUnlocker(kind string, action string, unlockFunc func(node int) bool) gio.Handler {
+	return func(request *gio.Request) {
+		if len(request.Payload) != 0 {
+			log.Errorf("register:%s:%s:read:error: expected no payload, got %v", kind, action, request.Payload)
+			replyBadRequest(request, "expected no payload, got %v", request.Payload)
+			return
+		}
 
-				_, writeErr = out.Write(syncBytes)
-				if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-					log.Errorf("register:mutex:unlock:write:error: %v", writeErr)
-					return
-				}
-			} else {
-				log.Errorf("register:mutex:unlock:read:error: expected byte 22, got %d", inBytes[0])
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:mutex:unlock:read:error: %v", readErr)
+		if !unlockFunc(request.Node) {
+			replyBadRequest(request, "%s of a %s not held", action, kind)
 			return
 		}
+		ack(request)
 	}
 }
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	gio "tonysoft.com/gothon/internal/io"
+	"tonysoft.com/gothon/internal/memory/config"
+	"tonysoft.com/gothon/pkg/log"
+)
+
+// Object is the serialized (pickled) form of a Python object, which is stored
+// as-is without ever being decoded.
+type Object []byte
+
+type ObjectRegister struct {
+	RegisterBase
+	mut        sync.Mutex
+	val        Object
+	isSet      bool
+	bufferSize uint32
+}
+
+func (r *ObjectRegister) Init() {
+	r.bufferSize = config.GetStringRegisterBufferSize() + bytesPrefixLength + 1
+}
+
+// Handler returns the handler for get or set, the latter storing the received
+// object unless the leading byte marks it as an initial value and the object
+// has already been set (by any node).
+func (r *ObjectRegister) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "set":
+		return newApplier(r), true
+	case "get":
+		return r.handleGetter, true
+	}
+	return nil, false
+}
+
+func (r *ObjectRegister) handleGetter(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:object:get:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	r.mut.Lock()
+	outBytes := encodeBytes(r.val)
+	r.mut.Unlock()
+
+	reply(request, outBytes)
+}
+
+// objectValue is the value of an object register as loaded by batches, which
+// includes whether it has been set.
+type objectValue struct {
+	val   Object
+	isSet bool
+}
+
+func (r *ObjectRegister) lock() {
+	r.mut.Lock()
+}
+
+func (r *ObjectRegister) unlock() {
+	r.mut.Unlock()
+}
+
+func (r *ObjectRegister) load() any {
+	return objectValue{r.val, r.isSet}
+}
+
+func (r *ObjectRegister) store(val any) {
+	r.val = val.(objectValue).val
+	r.isSet = val.(objectValue).isSet
+}
+
+func (r *ObjectRegister) apply(val any, action string, request []byte) (any, error) {
+	if action != "set" {
+		return val, errUnbatchableAction(action)
+	}
+	if len(request) > int(r.bufferSize) {
+		return val, errors.New("pickled object exceeds the maximum size")
+	}
+
+	var decoded []byte
+	ok := len(request) > 0
+	if ok {
+		decoded, ok = decodeBytes(request[1:])
+	}
+	if !ok {
+		return val, errors.New("invalid object value")
+	}
+
+	if request[0] == 0 || !val.(objectValue).isSet {
+		return objectValue{decoded, true}, nil
+	}
+	return val, nil
+}
+
+func (r *ObjectRegister) encode(val any) []byte {
+	return encodeBytes(val.(objectValue).val)
+}
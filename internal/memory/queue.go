@@ -2,283 +2,372 @@ package memory
 
 import (
 	"encoding/binary"
-	"errors"
-	"io"
 	"math"
-	"net"
 	"sync"
+	"time"
+	gio "tonysoft.com/gothon/internal/io"
 	"tonysoft.com/gothon/internal/memory/config"
 	"tonysoft.com/gothon/internal/queue"
 	"tonysoft.com/gothon/pkg/log"
 )
 
+const (
+	// queueRequestLength is the size of the header prefixed to get/put
+	// requests: a bool saying whether to block, followed by the timeout.
+	queueRequestLength = boolLength + float64Length
+)
+
 type QueueRegister[T queue.ItemType] struct {
 	RegisterBase
 	mut        sync.Mutex
+	notEmpty   *sync.Cond
+	notFull    *sync.Cond
+	allDone    *sync.Cond
+	unfinished uint64
 	val        queue.Queue[T]
 	bufferSize uint32
-	readVal    func(buff []byte, count int) T
-	writeVal   func(val T, buff []byte) []byte
+	readVal    func(buff []byte, count int) (T, bool)
+	writeVal   func(val T) []byte
 }
 
 func (r *QueueRegister[T]) Init() {
-	for i, s := range r.settersIn {
-		go r.processSetter(s, r.settersOut[i])
-	}
-
-	for i, g := range r.gettersIn {
-		go r.processGetter(g, r.gettersOut[i], r.gettersOk[i])
-	}
-
-	for i, c := range r.sizeCallersIn {
-		go r.processSizeCaller(c, r.sizeCallersOut[i])
-	}
+	r.notEmpty = sync.NewCond(&r.mut)
+	r.notFull = sync.NewCond(&r.mut)
+	r.allDone = sync.NewCond(&r.mut)
 
-	for i, c := range r.emptyCallersIn {
-		go r.processEmptyCaller(c, r.emptyCallersOut[i])
-	}
+	r.bufferSize = itemBufferSize[T]()
+	r.readVal = itemReader[T]()
+	r.writeVal = itemWriter[T]()
+}
 
-	for i, c := range r.fullCallersIn {
-		go r.processFullCaller(c, r.fullCallersOut[i])
+func (r *QueueRegister[T]) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "set":
+		return r.handleSetter, true
+	case "get":
+		return r.handleGetter, true
+	case "size":
+		return r.handleSizeCaller, true
+	case "empty":
+		return r.handleEmptyCaller, true
+	case "full":
+		return r.handleFullCaller, true
+	case "taskdone", "join":
+		return func(request *gio.Request) {
+			r.handleTaskOperator(action, request)
+		}, true
 	}
-
-	r.setBufferSize()
-	r.setReadValFunc()
-	r.setWriteValFunc()
+	return nil, false
 }
 
-func (r *QueueRegister[T]) setBufferSize() {
+// itemBufferSize returns the largest size of an item of a queue (or topic)
+// once encoded.
+func itemBufferSize[T queue.ItemType]() uint32 {
 	switch any(*new(T)).(type) {
 	case bool:
-		r.bufferSize = boolLength
+		return boolLength
 	case int64:
-		r.bufferSize = int64Length
+		return int64Length
 	case float64:
-		r.bufferSize = float64Length
+		return float64Length
 	case string:
-		r.bufferSize = config.GetStringRegisterBufferSize()
+		return config.GetStringRegisterBufferSize()
+	case []byte:
+		return config.GetStringRegisterBufferSize() + bytesPrefixLength
 	}
+	return 0
 }
 
-func (r *QueueRegister[T]) setReadValFunc() {
+// itemReader returns the function decoding the items of a queue (or topic)
+// from the first count bytes of buff, which reports whether they were valid.
+func itemReader[T queue.ItemType]() func(buff []byte, count int) (T, bool) {
 	switch any(*new(T)).(type) {
 	case bool:
-		r.readVal = func(buff []byte, count int) T {
-			return any(buff[0] != 0).(T)
+		return func(buff []byte, count int) (T, bool) {
+			return any(buff[0] != 0).(T), true
 		}
 	case int64:
-		r.readVal = func(buff []byte, count int) T {
-			return any(int64(binary.BigEndian.Uint64(buff))).(T)
+		return func(buff []byte, count int) (T, bool) {
+			return any(int64(binary.BigEndian.Uint64(buff))).(T), true
 		}
 	case float64:
-		r.readVal = func(buff []byte, count int) T {
-			bits := binary.LittleEndian.Uint64(buff)
-			return any(math.Float64frombits(bits)).(T)
+		return func(buff []byte, count int) (T, bool) {
+			bits := binary.BigEndian.Uint64(buff)
+			return any(math.Float64frombits(bits)).(T), true
 		}
 	case string:
-		r.readVal = func(buff []byte, count int) T {
-			return any(string(buff[:count])).(T)
+		return func(buff []byte, count int) (T, bool) {
+			return any(string(buff[:count])).(T), true
+		}
+	case []byte:
+		return func(buff []byte, count int) (T, bool) {
+			val, ok := decodeBytes(buff[:count])
+			return any(val).(T), ok
 		}
 	}
+	return nil
 }
 
-func (r *QueueRegister[T]) setWriteValFunc() {
+// itemWriter returns the function encoding the items of a queue (or topic).
+func itemWriter[T queue.ItemType]() func(val T) []byte {
 	switch any(*new(T)).(type) {
-	case bool:
-		r.writeVal = func(val T, buff []byte) []byte {
-			if any(val).(bool) {
-				buff[0] = 1
-			} else {
-				buff[0] = 0
-			}
-			return buff
-		}
-	case int64:
-		r.writeVal = func(val T, buff []byte) []byte {
-			binary.BigEndian.PutUint64(buff, uint64(any(val).(int64)))
-			return buff
-		}
-	case float64:
-		r.writeVal = func(val T, buff []byte) []byte {
-			bits := math.Float64bits(any(val).(float64))
-			binary.LittleEndian.PutUint64(buff, bits)
-			return buff
-		}
-	case string:
-		r.writeVal = func(val T, buff []byte) []byte {
-			return []byte(any(val).(string))
+	case []byte:
+		return func(val T) []byte {
+			return encodeBytes(any(val).([]byte))
 		}
+	default:
+		return encodeVal[T]
 	}
 }
 
-func (r *QueueRegister[T]) processSetter(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, r.bufferSize)
+func (r *QueueRegister[T]) handleSetter(request *gio.Request) {
+	inBytes := request.Payload
+	ok := len(inBytes) >= queueRequestLength && isQueueRequest(inBytes[:queueRequestLength]) &&
+		uint32(len(inBytes)) <= queueRequestLength+r.bufferSize
 	var val T
-	var count int
-	var readErr, writeErr error
-	var ok bool
-
-	for {
-		count, readErr = in.Read(inBytes)
-		if readErr == nil {
-			val = r.readVal(inBytes, count)
-			r.mut.Lock()
-			ok = r.val.Put(val)
-			r.mut.Unlock()
+	if ok {
+		val, ok = r.readVal(inBytes[queueRequestLength:], len(inBytes)-queueRequestLength)
+	}
+	if !ok {
+		replyBadRequest(request, "invalid queue item")
+		return
+	}
 
-			if ok {
-				_, writeErr = out.Write(syncBytes)
-			} else {
-				_, writeErr = out.Write(nakBytes)
-			}
+	await(r.notFull, request, inBytes[:queueRequestLength], func() bool {
+		if !r.val.Put(val) {
+			return false
+		}
+		r.unfinished++
+		r.notEmpty.Broadcast()
+		return true
+	}, func(ok bool) {
+		if !ok {
+			refuseOrTimeOut(request, "queue full")
+			return
+		}
 
-			if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-				log.Errorf("register:queue:set:write:error: %v", writeErr)
-				return
+		// the item is taken back out if the node doesn't learn it was put,
+		// unless it has been got in the meantime
+		if !ack(request) {
+			r.mut.Lock()
+			if r.val.Unput(val) {
+				r.unfinished--
+				if r.unfinished == 0 {
+					r.allDone.Broadcast()
+				}
+				r.notFull.Broadcast()
 			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:queue:set:read:error: %v", readErr)
-			return
+			r.mut.Unlock()
 		}
-	}
+	})
 }
 
-func (r *QueueRegister[T]) processGetter(in io.Reader, out io.Writer, ok io.Writer) {
-	inBytes := make([]byte, 1)
-	outBytes := make([]byte, r.bufferSize)
-	var readErr, writeErr error
-	var val T
-	var isNotEmpty bool
+func (r *QueueRegister[T]) handleGetter(request *gio.Request) {
+	inBytes := request.Payload
+	if !isQueueRequest(inBytes) {
+		log.Errorf("register:queue:get:read:error: expected a block flag and a timeout, got %v", inBytes)
+		replyBadRequest(request, "expected a block flag and a timeout, got %v", inBytes)
+		return
+	}
 
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			if inBytes[0] == syncByte {
+	var val T
+	await(r.notEmpty, request, inBytes, func() bool {
+		var isNotEmpty bool
+		val, isNotEmpty = r.val.Get()
+		if isNotEmpty {
+			r.notFull.Broadcast()
+		}
+		return isNotEmpty
+	}, func(isNotEmpty bool) {
+		if isNotEmpty {
+			// the item goes back in the queue if the node doesn't get it
+			if !reply(request, r.writeVal(val)) {
 				r.mut.Lock()
-				val, isNotEmpty = r.val.Get()
+				r.val.Unget(val)
+				r.notEmpty.Broadcast()
 				r.mut.Unlock()
-
-				if isNotEmpty {
-					_, writeErr = ok.Write(syncBytes)
-					if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-						log.Errorf("register:queue:get:write:error: %v", writeErr)
-						return
-					}
-
-					outBytes = r.writeVal(val, outBytes)
-					_, writeErr = out.Write(outBytes)
-				} else {
-					_, writeErr = out.Write(nakBytes)
-				}
-
-				if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-					log.Errorf("register:queue:get:write:error: %v", writeErr)
-					return
-				}
-			} else {
-				log.Errorf("register:queue:get:read:error: expected byte 22, got %d", inBytes[0])
-				return
 			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:queue:get:read:error: %v", readErr)
-			return
+		} else {
+			refuseOrTimeOut(request, "queue empty")
 		}
+	})
+}
+
+// isQueueRequest reports whether the header of a get/put request is valid.
+func isQueueRequest(header []byte) bool {
+	return len(header) == queueRequestLength && header[0] <= 1
+}
+
+// blocks reports whether the block flag a request starts with is set.
+func blocks(payload []byte) bool {
+	return payload[0] == 1
+}
+
+// refuseOrTimeOut replies to a get or put that couldn't be made, with
+// StatusTimeout if it was to block (so its timeout elapsed) and otherwise by
+// refusing it.
+func refuseOrTimeOut(request *gio.Request, reason string) {
+	if blocks(request.Payload) {
+		replyError(request, gio.StatusTimeout, errTimedOut)
+	} else {
+		refuse(request, reason)
 	}
 }
 
-func (r *QueueRegister[T]) processSizeCaller(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, 1)
-	outBytes := make([]byte, int64Length)
-	var readErr, writeErr error
-	var size uint64
+// await calls try (with the lock of cond held), then done with whether it
+// succeeded.  If it didn't and the request header asks to block, try is instead
+// called again on a goroutine of its own until it succeeds, see waitFor.
+func await(cond *sync.Cond, request *gio.Request, header []byte, try func() bool, done func(ok bool)) {
+	cond.L.Lock()
+	ok := try()
+	cond.L.Unlock()
 
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			if inBytes[0] == syncByte {
-				r.mut.Lock()
-				size = r.val.Size()
-				r.mut.Unlock()
-				binary.BigEndian.PutUint64(outBytes, size)
+	if ok || !blocks(header) {
+		done(ok)
+		return
+	}
 
-				_, writeErr = out.Write(outBytes)
-				if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-					log.Errorf("register:queue:size:write:error: %v", writeErr)
-					return
-				}
-			} else {
-				log.Errorf("register:queue:size:read:error: expected byte 22, got %d", inBytes[0])
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:queue:size:read:error: %v", readErr)
-			return
+	canceled := request.Canceled()
+	go func() {
+		cond.L.Lock()
+		ok := waitFor(cond, header, canceled, try)
+		cond.L.Unlock()
+		done(ok)
+	}()
+}
+
+// waitFor calls try (with the lock of cond held) until it succeeds.  If the request
+// header asks not to block, waitFor gives up after the first attempt, otherwise
+// it waits on cond between attempts, for at most the requested timeout if one
+// was given and until the request is canceled.
+func waitFor(cond *sync.Cond, header []byte, canceled <-chan struct{}, try func() bool) bool {
+	if try() {
+		return true
+	}
+
+	if !blocks(header) {
+		return false
+	}
+
+	select {
+	case <-canceled:
+		return false
+	default:
+	}
+
+	stopped := false
+	stop := func() {
+		cond.L.Lock()
+		stopped = true
+		cond.Broadcast()
+		cond.L.Unlock()
+	}
+
+	timeout, hasTimeout := decodeTimeout(header[1:])
+	if hasTimeout {
+		timer := time.AfterFunc(timeout, stop)
+		defer timer.Stop()
+	}
+
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-canceled:
+			stop()
+		case <-finished:
+		}
+	}()
+
+	for !stopped {
+		cond.Wait()
+		if !stopped && try() {
+			return true
 		}
 	}
+	return false
 }
 
-func (r *QueueRegister[T]) processEmptyCaller(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, 1)
-	outBytes := make([]byte, boolLength)
-	var readErr, writeErr error
+func (r *QueueRegister[T]) handleSizeCaller(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:queue:size:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
 
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			if inBytes[0] == syncByte {
-				r.mut.Lock()
-				if r.val.Empty() {
-					outBytes[0] = 1
-				} else {
-					outBytes[0] = 0
-				}
-				r.mut.Unlock()
+	r.mut.Lock()
+	size := int64(r.val.Size())
+	r.mut.Unlock()
 
-				_, writeErr = out.Write(outBytes)
-				if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-					log.Errorf("register:queue:size:write:error: %v", writeErr)
-					return
-				}
-			} else {
-				log.Errorf("register:queue:size:read:error: expected byte 22, got %d", inBytes[0])
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:queue:size:read:error: %v", readErr)
-			return
-		}
+	reply(request, encodeVal(size))
+}
+
+func (r *QueueRegister[T]) handleEmptyCaller(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:queue:empty:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
 	}
+
+	r.mut.Lock()
+	empty := r.val.Empty()
+	r.mut.Unlock()
+
+	reply(request, encodeVal(empty))
 }
 
-func (r *QueueRegister[T]) processFullCaller(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, 1)
-	outBytes := make([]byte, boolLength)
-	var readErr, writeErr error
+func (r *QueueRegister[T]) handleFullCaller(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:queue:full:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
 
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			if inBytes[0] == syncByte {
-				r.mut.Lock()
-				if r.val.Full() {
-					outBytes[0] = 1
-				} else {
-					outBytes[0] = 0
-				}
-				r.mut.Unlock()
+	r.mut.Lock()
+	full := r.val.Full()
+	r.mut.Unlock()
 
-				_, writeErr = out.Write(outBytes)
-				if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-					log.Errorf("register:queue:size:write:error: %v", writeErr)
-					return
-				}
-			} else {
-				log.Errorf("register:queue:size:read:error: expected byte 22, got %d", inBytes[0])
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:queue:size:read:error: %v", readErr)
+	reply(request, encodeVal(full))
+}
+
+// handleTaskOperator marks a task as done (refusing the request if more tasks
+// were marked done than were ever put in the queue) or, for join, waits until
+// every task put in the queue has been marked done.
+func (r *QueueRegister[T]) handleTaskOperator(operator string, request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:queue:%s:read:error: expected no payload, got %v", operator, request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	switch operator {
+	case "taskdone":
+		if r.unfinished == 0 {
+			refuse(request, "task_done() called too many times")
+			return
+		}
+
+		r.unfinished--
+		if r.unfinished == 0 {
+			r.allDone.Broadcast()
+		}
+		ack(request)
+	case "join":
+		if r.unfinished == 0 {
+			ack(request)
 			return
 		}
+
+		go func() {
+			r.mut.Lock()
+			for r.unfinished > 0 {
+				r.allDone.Wait()
+			}
+			r.mut.Unlock()
+			ack(request)
+		}()
 	}
 }
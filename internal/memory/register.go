@@ -1,203 +1,124 @@
 package memory
 
 import (
-	"io"
+	"errors"
+	"fmt"
+	"net"
 	"sync"
+	gio "tonysoft.com/gothon/internal/io"
 	"tonysoft.com/gothon/internal/queue"
+	"tonysoft.com/gothon/pkg/log"
 )
 
 var (
-	nakByte  = byte(21)
-	syncByte = byte(22)
-
-	nakBytes  = []byte{nakByte}
-	syncBytes = []byte{syncByte}
+	errTimedOut = errors.New("timed out")
+	errClosed   = errors.New("channel closed")
 )
 
-type QueueRegisterType interface {
-	queue.Fifo[bool] | queue.Fifo[int64] | queue.Fifo[float64] | queue.Fifo[string] |
-		queue.Lifo[bool] | queue.Lifo[int64] | queue.Lifo[float64] | queue.Lifo[string]
-}
-
-type RegisterType interface {
-	bool | int64 | float64 | string |
-		sync.Mutex | *sync.WaitGroup |
-		QueueRegisterType
-}
-
-type Register interface {
-	ID() string
-
-	AddSetterIn(io.Reader)
-	AddSetterOut(io.Writer)
-
-	AddGetterIn(io.Reader)
-	AddGetterOut(io.Writer)
-	AddGetterOk(io.Writer)
-
-	AddAdderIn(io.Reader)
-	AddAdderOut(io.Writer)
-
-	AddSubtractorIn(io.Reader)
-	AddSubtractorOut(io.Writer)
-
-	AddMultiplierIn(io.Reader)
-	AddMultiplierOut(io.Writer)
-
-	AddDividerIn(io.Reader)
-	AddDividerOut(io.Writer)
-
-	AddLockerIn(io.Reader)
-	AddLockerOut(io.Writer)
-
-	AddUnlockerIn(io.Reader)
-	AddUnlockerOut(io.Writer)
-
-	AddSizeCallerIn(io.Reader)
-	AddSizeCallerOut(io.Writer)
-
-	AddEmptyCallerIn(io.Reader)
-	AddEmptyCallerOut(io.Writer)
-
-	AddFullCallerIn(io.Reader)
-	AddFullCallerOut(io.Writer)
-
-	Init()
-}
-
-type RegisterBase struct {
-	id string
-
-	settersIn  []io.Reader
-	settersOut []io.Writer
-
-	gettersIn  []io.Reader
-	gettersOut []io.Writer
-	gettersOk  []io.Writer
-
-	addersIn  []io.Reader
-	addersOut []io.Writer
-
-	subtractorsIn  []io.Reader
-	subtractorsOut []io.Writer
-
-	multipliersIn  []io.Reader
-	multipliersOut []io.Writer
-
-	dividersIn  []io.Reader
-	dividersOut []io.Writer
-
-	lockersIn  []io.Reader
-	lockersOut []io.Writer
-
-	unlockersIn  []io.Reader
-	unlockersOut []io.Writer
-
-	sizeCallersIn  []io.Reader
-	sizeCallersOut []io.Writer
-
-	emptyCallersIn  []io.Reader
-	emptyCallersOut []io.Writer
-
-	fullCallersIn  []io.Reader
-	fullCallersOut []io.Writer
+// reply replies to the request with the payload, reporting whether the node
+// gets the reply.  If it doesn't, as it canceled the request or is gone, the
+// caller must undo what it did for the request (e.g. unlock a mutex).  Other
+// errors are logged.
+func reply(request *gio.Request, payload []byte) bool {
+	return checkReplied(request, request.Reply(payload))
 }
 
-func (r *RegisterBase) ID() string {
-	return r.id
-}
-
-func (r *RegisterBase) AddSetterIn(reader io.Reader) {
-	r.settersIn = append(r.settersIn, reader)
-}
-
-func (r *RegisterBase) AddSetterOut(writer io.Writer) {
-	r.settersOut = append(r.settersOut, writer)
-}
-
-func (r *RegisterBase) AddGetterIn(reader io.Reader) {
-	r.gettersIn = append(r.gettersIn, reader)
-}
-
-func (r *RegisterBase) AddGetterOut(writer io.Writer) {
-	r.gettersOut = append(r.gettersOut, writer)
+// replyError replies with the error instead of a value, which the node raises,
+// reporting whether the node gets the reply (see reply).
+func replyError(request *gio.Request, status byte, err error) bool {
+	return checkReplied(request, request.ReplyError(status, err.Error()))
 }
 
-func (r *RegisterBase) AddGetterOk(writer io.Writer) {
-	r.gettersOk = append(r.gettersOk, writer)
+func checkReplied(request *gio.Request, err error) bool {
+	if err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, gio.ErrCanceled) {
+		log.Errorf("register:%s:%s:write:error: %v", request.ID(), request.Action(), err)
+	}
+	return err == nil
 }
 
-func (r *RegisterBase) AddAdderIn(reader io.Reader) {
-	r.addersIn = append(r.addersIn, reader)
+// ack replies to a request carried out that has no value to reply with.
+func ack(request *gio.Request) bool {
+	return reply(request, nil)
 }
 
-func (r *RegisterBase) AddAdderOut(writer io.Writer) {
-	r.addersOut = append(r.addersOut, writer)
+// replyStatus replies with the payload if the status is StatusOK, and otherwise
+// with the error matching the status (either StatusClosed or StatusTimeout).
+func replyStatus(request *gio.Request, status byte, payload []byte) bool {
+	switch status {
+	case gio.StatusOK:
+		return reply(request, payload)
+	case gio.StatusClosed:
+		return replyError(request, status, errClosed)
+	default:
+		return replyError(request, status, errTimedOut)
+	}
 }
 
-func (r *RegisterBase) AddSubtractorIn(reader io.Reader) {
-	r.subtractorsIn = append(r.subtractorsIn, reader)
+// refuse replies that the register's state doesn't allow the request, which
+// the node raises as the matching Python exception (e.g. a KeyError).
+func refuse(request *gio.Request, format string, args ...any) {
+	replyError(request, gio.StatusRefused, fmt.Errorf(format, args...))
 }
 
-func (r *RegisterBase) AddSubtractorOut(writer io.Writer) {
-	r.subtractorsOut = append(r.subtractorsOut, writer)
+// replyBadRequest replies to a malformed request with an error, so that the
+// node raises it rather than waiting on a reply that would never come.
+func replyBadRequest(request *gio.Request, format string, args ...any) {
+	replyError(request, gio.StatusBadRequest, fmt.Errorf(format, args...))
 }
 
-func (r *RegisterBase) AddMultiplierIn(reader io.Reader) {
-	r.multipliersIn = append(r.multipliersIn, reader)
+type QueueRegisterType interface {
+	queue.Fifo[bool] | queue.Fifo[int64] | queue.Fifo[float64] | queue.Fifo[string] | queue.Fifo[[]byte] |
+		queue.Lifo[bool] | queue.Lifo[int64] | queue.Lifo[float64] | queue.Lifo[string] | queue.Lifo[[]byte] |
+		queue.Priority[int64] | queue.Priority[float64] | queue.Priority[string]
 }
 
-func (r *RegisterBase) AddMultiplierOut(writer io.Writer) {
-	r.multipliersOut = append(r.multipliersOut, writer)
+type TopicRegisterType interface {
+	Topic[bool] | Topic[int64] | Topic[float64] | Topic[string] | Topic[[]byte]
 }
 
-func (r *RegisterBase) AddDividerIn(reader io.Reader) {
-	r.dividersIn = append(r.dividersIn, reader)
+type ChanRegisterType interface {
+	Chan[bool] | Chan[int64] | Chan[float64] | Chan[string] | Chan[[]byte]
 }
 
-func (r *RegisterBase) AddDividerOut(writer io.Writer) {
-	r.dividersOut = append(r.dividersOut, writer)
+type DictRegisterType interface {
+	map[string]bool | map[string]int64 | map[string]float64 | map[string]string |
+		map[int64]bool | map[int64]int64 | map[int64]float64 | map[int64]string
 }
 
-func (r *RegisterBase) AddLockerIn(reader io.Reader) {
-	r.lockersIn = append(r.lockersIn, reader)
+type ListRegisterType interface {
+	[]bool | []int64 | []float64 | []string
 }
 
-func (r *RegisterBase) AddLockerOut(writer io.Writer) {
-	r.lockersOut = append(r.lockersOut, writer)
+type SetRegisterType interface {
+	map[string]struct{} | map[int64]struct{}
 }
 
-func (r *RegisterBase) AddUnlockerIn(reader io.Reader) {
-	r.unlockersIn = append(r.unlockersIn, reader)
+type RegisterType interface {
+	bool | int64 | float64 | string | []byte | Object | Semaphore | Barrier | Batch | Transaction | Event | Condition |
+		sync.Mutex | sync.RWMutex | *sync.WaitGroup |
+		QueueRegisterType | TopicRegisterType | ChanRegisterType | DictRegisterType | ListRegisterType | SetRegisterType
 }
 
-func (r *RegisterBase) AddUnlockerOut(writer io.Writer) {
-	r.unlockersOut = append(r.unlockersOut, writer)
-}
+type Register interface {
+	ID() string
 
-func (r *RegisterBase) AddSizeCallerIn(reader io.Reader) {
-	r.sizeCallersIn = append(r.sizeCallersIn, reader)
-}
+	// Handler returns the handler for the action (e.g. get, or lock for a
+	// mutex), if the register supports it.
+	Handler(action string) (gio.Handler, bool)
 
-func (r *RegisterBase) AddSizeCallerOut(writer io.Writer) {
-	r.sizeCallersOut = append(r.sizeCallersOut, writer)
-}
-
-func (r *RegisterBase) AddEmptyCallerIn(reader io.Reader) {
-	r.emptyCallersIn = append(r.emptyCallersIn, reader)
+	Init()
 }
 
-func (r *RegisterBase) AddEmptyCallerOut(writer io.Writer) {
-	r.emptyCallersOut = append(r.emptyCallersOut, writer)
+type RegisterBase struct {
+	id string
 }
 
-func (r *RegisterBase) AddFullCallerIn(reader io.Reader) {
-	r.fullCallersIn = append(r.fullCallersIn, reader)
+func (r *RegisterBase) ID() string {
+	return r.id
 }
 
-func (r *RegisterBase) AddFullCallerOut(writer io.Writer) {
-	r.fullCallersOut = append(r.fullCallersOut, writer)
-}
+// Init does nothing, for the registers with nothing to set up once created.
+func (r *RegisterBase) Init() {}
 
 func NewRegister[T RegisterType](id string, defaultValue any) Register {
 	switch any(*new(T)).(type) {
@@ -221,10 +142,54 @@ func NewRegister[T RegisterType](id string, defaultValue any) Register {
 		reg.id = id
 		reg.val = defaultValue.(string)
 		return reg
+	case []byte:
+		reg := &BytesRegister{}
+		reg.id = id
+		reg.val = defaultValue.([]byte)
+		return reg
+	case Object:
+		reg := &ObjectRegister{}
+		reg.id = id
+		return reg
 	case sync.Mutex:
 		reg := &MutexRegister{}
 		reg.id = id
 		return reg
+	case sync.RWMutex:
+		reg := &RWMutexRegister{}
+		reg.id = id
+		return reg
+	case Semaphore:
+		reg := &SemaphoreRegister{}
+		reg.id = id
+		reg.val = defaultValue.(Semaphore)
+		return reg
+	case Barrier:
+		reg := &BarrierRegister{}
+		reg.id = id
+		reg.parties = int(defaultValue.(Barrier))
+		return reg
+	case Batch:
+		reg := &BatchRegister{}
+		reg.id = id
+		return reg
+	case Transaction:
+		reg := &TransactionRegister{}
+		reg.id = id
+		return reg
+	case Event:
+		reg := &EventRegister{}
+		reg.id = id
+		reg.set = make(chan struct{})
+		if defaultValue.(Event) {
+			close(reg.set)
+		}
+		return reg
+	case Condition:
+		reg := &ConditionRegister{}
+		reg.id = id
+		reg.lock = defaultValue.(Condition).L
+		return reg
 	case *sync.WaitGroup:
 		wg := defaultValue.(*sync.WaitGroup)
 		reg := &WaitGroupRegister{}
@@ -255,6 +220,12 @@ func NewRegister[T RegisterType](id string, defaultValue any) Register {
 		reg.id = id
 		reg.val = q
 		return reg
+	case queue.Fifo[[]byte]:
+		q := queue.New[[]byte](uint64(defaultValue.(int64)))
+		reg := &QueueRegister[[]byte]{}
+		reg.id = id
+		reg.val = q
+		return reg
 	case queue.Lifo[bool]:
 		q := queue.New[bool](uint64(defaultValue.(int64)), true)
 		reg := &QueueRegister[bool]{}
@@ -279,6 +250,160 @@ func NewRegister[T RegisterType](id string, defaultValue any) Register {
 		reg.id = id
 		reg.val = q
 		return reg
+	case queue.Lifo[[]byte]:
+		q := queue.New[[]byte](uint64(defaultValue.(int64)), true)
+		reg := &QueueRegister[[]byte]{}
+		reg.id = id
+		reg.val = q
+		return reg
+	case queue.Priority[int64]:
+		q := queue.NewPriority[int64](uint64(defaultValue.(int64)))
+		reg := &QueueRegister[int64]{}
+		reg.id = id
+		reg.val = q
+		return reg
+	case queue.Priority[float64]:
+		q := queue.NewPriority[float64](uint64(defaultValue.(int64)))
+		reg := &QueueRegister[float64]{}
+		reg.id = id
+		reg.val = q
+		return reg
+	case queue.Priority[string]:
+		q := queue.NewPriority[string](uint64(defaultValue.(int64)))
+		reg := &QueueRegister[string]{}
+		reg.id = id
+		reg.val = q
+		return reg
+	case Topic[bool]:
+		reg := &TopicRegister[bool]{}
+		reg.id = id
+		reg.cursors = newCursors(defaultValue.(int))
+		return reg
+	case Topic[int64]:
+		reg := &TopicRegister[int64]{}
+		reg.id = id
+		reg.cursors = newCursors(defaultValue.(int))
+		return reg
+	case Topic[float64]:
+		reg := &TopicRegister[float64]{}
+		reg.id = id
+		reg.cursors = newCursors(defaultValue.(int))
+		return reg
+	case Topic[string]:
+		reg := &TopicRegister[string]{}
+		reg.id = id
+		reg.cursors = newCursors(defaultValue.(int))
+		return reg
+	case Topic[[]byte]:
+		reg := &TopicRegister[[]byte]{}
+		reg.id = id
+		reg.cursors = newCursors(defaultValue.(int))
+		return reg
+	case Chan[bool]:
+		reg := &ChanRegister[bool]{}
+		reg.id = id
+		reg.val = make(chan bool, defaultValue.(Chan[bool]))
+		reg.closed = make(chan struct{})
+		reg.wake = make(chan struct{}, 1)
+		return reg
+	case Chan[int64]:
+		reg := &ChanRegister[int64]{}
+		reg.id = id
+		reg.val = make(chan int64, defaultValue.(Chan[int64]))
+		reg.closed = make(chan struct{})
+		reg.wake = make(chan struct{}, 1)
+		return reg
+	case Chan[float64]:
+		reg := &ChanRegister[float64]{}
+		reg.id = id
+		reg.val = make(chan float64, defaultValue.(Chan[float64]))
+		reg.closed = make(chan struct{})
+		reg.wake = make(chan struct{}, 1)
+		return reg
+	case Chan[string]:
+		reg := &ChanRegister[string]{}
+		reg.id = id
+		reg.val = make(chan string, defaultValue.(Chan[string]))
+		reg.closed = make(chan struct{})
+		reg.wake = make(chan struct{}, 1)
+		return reg
+	case Chan[[]byte]:
+		reg := &ChanRegister[[]byte]{}
+		reg.id = id
+		reg.val = make(chan []byte, defaultValue.(Chan[[]byte]))
+		reg.closed = make(chan struct{})
+		reg.wake = make(chan struct{}, 1)
+		return reg
+	case map[string]bool:
+		reg := &DictRegister[string, bool]{}
+		reg.id = id
+		reg.val = make(map[string]bool)
+		return reg
+	case map[string]int64:
+		reg := &DictRegister[string, int64]{}
+		reg.id = id
+		reg.val = make(map[string]int64)
+		return reg
+	case map[string]float64:
+		reg := &DictRegister[string, float64]{}
+		reg.id = id
+		reg.val = make(map[string]float64)
+		return reg
+	case map[string]string:
+		reg := &DictRegister[string, string]{}
+		reg.id = id
+		reg.val = make(map[string]string)
+		return reg
+	case map[int64]bool:
+		reg := &DictRegister[int64, bool]{}
+		reg.id = id
+		reg.val = make(map[int64]bool)
+		return reg
+	case map[int64]int64:
+		reg := &DictRegister[int64, int64]{}
+		reg.id = id
+		reg.val = make(map[int64]int64)
+		return reg
+	case map[int64]float64:
+		reg := &DictRegister[int64, float64]{}
+		reg.id = id
+		reg.val = make(map[int64]float64)
+		return reg
+	case map[int64]string:
+		reg := &DictRegister[int64, string]{}
+		reg.id = id
+		reg.val = make(map[int64]string)
+		return reg
+	case []bool:
+		reg := &ListRegister[bool]{}
+		reg.id = id
+		reg.val = make([]bool, 0)
+		return reg
+	case []int64:
+		reg := &ListRegister[int64]{}
+		reg.id = id
+		reg.val = make([]int64, 0)
+		return reg
+	case []float64:
+		reg := &ListRegister[float64]{}
+		reg.id = id
+		reg.val = make([]float64, 0)
+		return reg
+	case []string:
+		reg := &ListRegister[string]{}
+		reg.id = id
+		reg.val = make([]string, 0)
+		return reg
+	case map[string]struct{}:
+		reg := &SetRegister[string]{}
+		reg.id = id
+		reg.val = make(map[string]struct{})
+		return reg
+	case map[int64]struct{}:
+		reg := &SetRegister[int64]{}
+		reg.id = id
+		reg.val = make(map[int64]struct{})
+		return reg
 	}
 
 	return nil
@@ -3,11 +3,34 @@ package memory
 type Registry map[string]Register
 
 func (r Registry) Init() {
+	// batches and transactions are applied to the other registers, which are
+	// only known once the registry is complete
 	for _, reg := range r {
+		switch reg := reg.(type) {
+		case *BatchRegister:
+			reg.registry = r
+		case *TransactionRegister:
+			reg.registry = r
+		}
 		reg.Init()
 	}
 }
 
+// Disconnect lets the registers keeping state for each node (e.g. an open
+// transaction) drop that of the node, once its connection has ended.
+func (r Registry) Disconnect(node int) {
+	for _, reg := range r {
+		if reg, ok := reg.(disconnecter); ok {
+			reg.Disconnect(node)
+		}
+	}
+}
+
+// disconnecter is implemented by the registers keeping state for each node.
+type disconnecter interface {
+	Disconnect(node int)
+}
+
 func NewRegistry(registers []Register) Registry {
 	registry := make(map[string]Register)
 
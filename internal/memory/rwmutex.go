@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"sync"
+	gio "tonysoft.com/gothon/internal/io"
+)
+
+// RWMutexRegister is a mutex held by either one writer or any number of
+// readers at a time, which are tracked (see MutexRegister).
+type RWMutexRegister struct {
+	RegisterBase
+	val     sync.RWMutex
+	mut     sync.Mutex
+	writing bool
+	writer  int
+	readers map[int]int
+}
+
+func (r *RWMutexRegister) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "lock":
+		return newLocker("rwmutex", action, r.lock, r.unlock), true
+	case "unlock":
+		return newUnlocker("rwmutex", action, r.unlock), true
+	case "rlock":
+		return newLocker("rwmutex", action, r.rlock, r.runlock), true
+	case "runlock":
+		return newUnlocker("rwmutex", action, r.runlock), true
+	}
+	return nil, false
+}
+
+// Locker returns the mutex (as held by writers), for the conditions tied to it.
+func (r *RWMutexRegister) Locker() Lock {
+	return r
+}
+
+func (r *RWMutexRegister) lock(node int) {
+	r.val.Lock()
+	r.mut.Lock()
+	r.writing = true
+	r.writer = node
+	r.mut.Unlock()
+}
+
+// unlock unlocks the mutex if the node holds it as a writer, reporting whether
+// it did.
+func (r *RWMutexRegister) unlock(node int) bool {
+	r.mut.Lock()
+	if !r.writing || r.writer != node {
+		r.mut.Unlock()
+		return false
+	}
+	r.writing = false
+	r.mut.Unlock()
+
+	r.val.Unlock()
+	return true
+}
+
+func (r *RWMutexRegister) heldBy(node int) bool {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.writing && r.writer == node
+}
+
+func (r *RWMutexRegister) rlock(node int) {
+	r.val.RLock()
+	r.mut.Lock()
+	if r.readers == nil {
+		r.readers = make(map[int]int)
+	}
+	r.readers[node]++
+	r.mut.Unlock()
+}
+
+// runlock unlocks the mutex if the node holds it as a reader, reporting
+// whether it did.
+func (r *RWMutexRegister) runlock(node int) bool {
+	r.mut.Lock()
+	if r.readers[node] == 0 {
+		r.mut.Unlock()
+		return false
+	}
+	r.readers[node]--
+	if r.readers[node] == 0 {
+		delete(r.readers, node)
+	}
+	r.mut.Unlock()
+
+	r.val.RUnlock()
+	return true
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	gio "tonysoft.com/gothon/internal/io"
+	"tonysoft.com/gothon/pkg/log"
+)
+
+// Semaphore holds one element per acquisition, its capacity being the number
+// of nodes that can hold the semaphore at the same time.
+type Semaphore chan struct{}
+
+type SemaphoreRegister struct {
+	RegisterBase
+	val Semaphore
+}
+
+func (r *SemaphoreRegister) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "acquire":
+		return r.handleAcquirer, true
+	case "release":
+		return r.handleReleaser, true
+	}
+	return nil, false
+}
+
+// handleAcquirer blocks until the semaphore can be acquired if the request's
+// block flag is set, otherwise it refuses the request if it can't be acquired
+// right away.  The semaphore is released again if the node doesn't get the
+// reply, and no longer waited on once the node cancels the request.
+func (r *SemaphoreRegister) handleAcquirer(request *gio.Request) {
+	if len(request.Payload) != boolLength || request.Payload[0] > 1 {
+		log.Errorf("register:semaphore:acquire:read:error: expected a block flag, got %v", request.Payload)
+		replyBadRequest(request, "expected a block flag, got %v", request.Payload)
+		return
+	}
+
+	select {
+	case r.val <- struct{}{}:
+		r.ackAcquired(request)
+	default:
+		if !blocks(request.Payload) {
+			refuse(request, "semaphore not available")
+			return
+		}
+
+		go func() {
+			select {
+			case r.val <- struct{}{}:
+				r.ackAcquired(request)
+			case <-request.Canceled():
+			}
+		}()
+	}
+}
+
+// ackAcquired acknowledges the acquisition, releasing the semaphore if the node
+// doesn't get the reply.
+func (r *SemaphoreRegister) ackAcquired(request *gio.Request) {
+	if !ack(request) {
+		<-r.val
+	}
+}
+
+func (r *SemaphoreRegister) handleReleaser(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:semaphore:release:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	select {
+	case <-r.val:
+		ack(request)
+	default:
+		refuse(request, "semaphore released too many times")
+	}
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	gio "tonysoft.com/gothon/internal/io"
+	"tonysoft.com/gothon/pkg/log"
+)
+
+type SetRegister[K DictKeyType] struct {
+	RegisterBase
+	mut sync.Mutex
+	val map[K]struct{}
+}
+
+func (r *SetRegister[K]) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "add":
+		return r.handleAdder, true
+	case "del":
+		return r.handleDeleter, true
+	case "contains":
+		return r.handleContainsCaller, true
+	case "size":
+		return r.handleSizeCaller, true
+	case "keys":
+		return r.handleKeysCaller, true
+	}
+	return nil, false
+}
+
+func (r *SetRegister[K]) handleAdder(request *gio.Request) {
+	key := decodeVal[K](request.Payload)
+	r.mut.Lock()
+	_, found := r.val[key]
+	if !found {
+		r.val[key] = struct{}{}
+	}
+	r.mut.Unlock()
+
+	reply(request, encodeVal(!found))
+}
+
+func (r *SetRegister[K]) handleDeleter(request *gio.Request) {
+	key := decodeVal[K](request.Payload)
+	r.mut.Lock()
+	_, found := r.val[key]
+	delete(r.val, key)
+	r.mut.Unlock()
+
+	reply(request, encodeVal(found))
+}
+
+func (r *SetRegister[K]) handleContainsCaller(request *gio.Request) {
+	r.mut.Lock()
+	_, found := r.val[decodeVal[K](request.Payload)]
+	r.mut.Unlock()
+
+	reply(request, encodeVal(found))
+}
+
+func (r *SetRegister[K]) handleSizeCaller(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:set:size:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	r.mut.Lock()
+	size := int64(len(r.val))
+	r.mut.Unlock()
+
+	reply(request, encodeVal(size))
+}
+
+func (r *SetRegister[K]) handleKeysCaller(request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:set:keys:read:error: expected no payload, got %v", request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	r.mut.Lock()
+	keys := make([]K, 0, len(r.val))
+	for k := range r.val {
+		keys = append(keys, k)
+	}
+	r.mut.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	reply(request, encodeVals(keys))
+}
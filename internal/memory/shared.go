@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+)
+
+// SharedMemorySlotSize is the size of each slot of the shared memory file,
+// which holds a sequence number (used by nodes to detect torn reads) followed
+// by the value, both big-endian.
+const SharedMemorySlotSize = 16
+
+// NewSharedMemory creates the file that nodes map into memory to access the
+// values directly, rather than through registers, with each value (a bool,
+// int64 or float64) in its own slot in the order given.
+func NewSharedMemory(path string, values []any) error {
+	buffer := make([]byte, SharedMemorySlotSize*len(values))
+
+	for i, val := range values {
+		slot := buffer[i*SharedMemorySlotSize+8 : (i+1)*SharedMemorySlotSize]
+		switch v := val.(type) {
+		case bool:
+			if v {
+				binary.BigEndian.PutUint64(slot, 1)
+			}
+		case int64:
+			binary.BigEndian.PutUint64(slot, uint64(v))
+		case float64:
+			binary.BigEndian.PutUint64(slot, math.Float64bits(v))
+		default:
+			return fmt.Errorf("unsupported shared memory value type %T", val)
+		}
+	}
+
+	return os.WriteFile(path, buffer, 0664)
+}
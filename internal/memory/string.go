@@ -2,12 +2,10 @@ package memory
 
 import (
 	"errors"
-	"io"
-	"net"
 	"strings"
 	"sync"
+	gio "tonysoft.com/gothon/internal/io"
 	"tonysoft.com/gothon/internal/memory/config"
-	"tonysoft.com/gothon/pkg/log"
 )
 
 type StringRegister struct {
@@ -18,122 +16,62 @@ type StringRegister struct {
 }
 
 func (r *StringRegister) Init() {
-	for i, s := range r.settersIn {
-		go r.processSetter(s, r.settersOut[i])
-	}
-
-	for i, g := range r.gettersIn {
-		go r.processGetter(g, r.gettersOut[i])
-	}
-
-	for i, a := range r.addersIn {
-		go r.processAdder(a, r.addersOut[i])
-	}
-
-	for i, s := range r.subtractorsIn {
-		go r.processSubtractor(s, r.subtractorsOut[i])
-	}
-
 	r.bufferSize = config.GetStringRegisterBufferSize()
 }
 
-func (r *StringRegister) processSetter(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, float64Length)
-	count := 0
-	var readErr, writeErr error
-
-	for {
-		count, readErr = in.Read(inBytes)
-		if readErr == nil {
-			r.mut.Lock()
-			r.val = string(inBytes[:count])
-			r.mut.Unlock()
-
-			_, writeErr = out.Write(syncBytes)
-			if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-				log.Errorf("register:string:set:write:error: %v", writeErr)
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:string:set:read:error: %v", readErr)
-			return
-		}
+func (r *StringRegister) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "set":
+		return newUpdater(&r.mut, &r.val, setVal[string]), true
+	case "get":
+		return newGetter(&r.mut, &r.val), true
+	case "add":
+		return newUpdater(&r.mut, &r.val, add[string]), true
+	case "fetch_add":
+		return newFetchAdder(&r.mut, &r.val, add[string]), true
+	case "sub":
+		return newUpdater(&r.mut, &r.val, strings.TrimSuffix), true
+	case "swap":
+		return newSwapper(&r.mut, &r.val), true
+	case "cas":
+		return newCompareAndSwapper(&r.mut, &r.val), true
 	}
+	return nil, false
 }
 
-func (r *StringRegister) processGetter(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, 1)
-	var outBytes []byte
-	var readErr, writeErr error
+func (r *StringRegister) lock() {
+	r.mut.Lock()
+}
 
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			if inBytes[0] == syncByte {
-				r.mut.Lock()
-				outBytes = []byte(r.val)
-				r.mut.Unlock()
+func (r *StringRegister) unlock() {
+	r.mut.Unlock()
+}
 
-				_, writeErr = out.Write(outBytes)
-				if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-					log.Errorf("register:string:get:write:error: %v", writeErr)
-					return
-				}
-			} else {
-				log.Errorf("register:string:get:read:error: expected byte 22, got %d", inBytes[0])
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:string:get:read:error: %v", readErr)
-			return
-		}
-	}
+func (r *StringRegister) load() any {
+	return r.val
 }
 
-func (r *StringRegister) processAdder(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, r.bufferSize)
-	count := 0
-	var readErr, writeErr error
+func (r *StringRegister) store(val any) {
+	r.val = val.(string)
+}
 
-	for {
-		count, readErr = in.Read(inBytes)
-		if readErr == nil {
-			r.mut.Lock()
-			r.val += string(inBytes[:count])
-			r.mut.Unlock()
+func (r *StringRegister) apply(val any, action string, request []byte) (any, error) {
+	if len(request) > int(r.bufferSize) {
+		return val, errors.New("str value exceeds the maximum size")
+	}
 
-			_, writeErr = out.Write(syncBytes)
-			if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-				log.Errorf("register:string:add:write:error: %v", writeErr)
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:string:add:read:error: %v", readErr)
-			return
-		}
+	switch action {
+	case "set":
+		return string(request), nil
+	case "add":
+		return val.(string) + string(request), nil
+	case "sub":
+		return strings.TrimSuffix(val.(string), string(request)), nil
+	default:
+		return val, errUnbatchableAction(action)
 	}
 }
 
-func (r *StringRegister) processSubtractor(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, r.bufferSize)
-	count := 0
-	var readErr, writeErr error
-
-	for {
-		count, readErr = in.Read(inBytes)
-		if readErr == nil {
-			r.mut.Lock()
-			r.val = strings.TrimSuffix(r.val, string(inBytes[:count]))
-			r.mut.Unlock()
-
-			_, writeErr = out.Write(syncBytes)
-			if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-				log.Errorf("register:string:sub:write:error: %v", writeErr)
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:string:sub:read:error: %v", readErr)
-			return
-		}
-	}
+func (r *StringRegister) encode(val any) []byte {
+	return encodeVal(val.(string))
 }
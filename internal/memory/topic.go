@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"math"
+	"sync"
+	gio "tonysoft.com/gothon/internal/io"
+	"tonysoft.com/gothon/internal/queue"
+	"tonysoft.com/gothon/pkg/log"
+)
+
+// Topic is the list of messages published to a topic, each of which is
+// delivered to every node.
+type Topic[T queue.ItemType] []T
+
+// TopicRegister keeps a cursor per node into the messages published, so that
+// each node gets every message once, in the order they were published.  Every
+// node is subscribed from the start and unsubscribes by disconnecting.
+// Messages are dropped once every subscriber has gotten them, and kept while
+// there are none.
+type TopicRegister[T queue.ItemType] struct {
+	RegisterBase
+	mut        sync.Mutex
+	published  *sync.Cond
+	messages   []T
+	offset     uint64
+	cursors    map[int]uint64
+	bufferSize uint32
+	readVal    func(buff []byte, count int) (T, bool)
+	writeVal   func(val T) []byte
+}
+
+func (r *TopicRegister[T]) Init() {
+	r.published = sync.NewCond(&r.mut)
+
+	r.bufferSize = itemBufferSize[T]()
+	r.readVal = itemReader[T]()
+	r.writeVal = itemWriter[T]()
+}
+
+func (r *TopicRegister[T]) Handler(action string) (gio.Handler, bool) {
+	switch action {
+	case "set":
+		return r.handlePublisher, true
+	case "get":
+		return r.handleSubscriber, true
+	case "size", "empty", "full":
+		return func(request *gio.Request) {
+			r.handleUnreadCaller(action, request)
+		}, true
+	}
+	return nil, false
+}
+
+// newCursors returns the cursors of a topic's nodes, every one of which is
+// subscribed from the first message.
+func newCursors(nodeCount int) map[int]uint64 {
+	cursors := make(map[int]uint64, nodeCount)
+	for node := 0; node < nodeCount; node++ {
+		cursors[node] = 0
+	}
+	return cursors
+}
+
+// unread returns the number of messages the node has yet to get, which is
+// none once it has unsubscribed.
+func (r *TopicRegister[T]) unread(node int) uint64 {
+	c, ok := r.cursors[node]
+	if !ok {
+		return 0
+	}
+	return r.offset + uint64(len(r.messages)) - c
+}
+
+// next returns the node's next message, dropping the messages every subscriber
+// has gotten.
+func (r *TopicRegister[T]) next(node int) (val T, ok bool) {
+	if r.unread(node) == 0 {
+		return val, false
+	}
+
+	val = r.messages[r.cursors[node]-r.offset]
+	r.cursors[node]++
+	r.drop()
+
+	return val, true
+}
+
+// drop drops the messages every subscriber has gotten.
+func (r *TopicRegister[T]) drop() {
+	if len(r.cursors) == 0 {
+		return
+	}
+
+	oldest := uint64(math.MaxUint64)
+	for _, c := range r.cursors {
+		if c < oldest {
+			oldest = c
+		}
+	}
+	if oldest > r.offset {
+		var empty T
+		for i := uint64(0); i < oldest-r.offset; i++ {
+			r.messages[i] = empty
+		}
+		r.messages = r.messages[oldest-r.offset:]
+		r.offset = oldest
+	}
+}
+
+// Disconnect unsubscribes the node, so that the messages it has yet to get
+// aren't kept for it.
+func (r *TopicRegister[T]) Disconnect(node int) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	delete(r.cursors, node)
+	r.drop()
+}
+
+// unget moves the node's cursor back to the message it got last (val), which
+// the node didn't get the reply with, putting the message back if it was
+// dropped in the meantime.  The message is lost if the node is gone.
+func (r *TopicRegister[T]) unget(node int, val T) {
+	if _, ok := r.cursors[node]; !ok {
+		return
+	}
+
+	r.cursors[node]--
+	if r.cursors[node] < r.offset {
+		r.messages = append([]T{val}, r.messages...)
+		r.offset--
+	}
+}
+
+func (r *TopicRegister[T]) handlePublisher(request *gio.Request) {
+	// publishing never blocks, so the request header is ignored
+	inBytes := request.Payload
+	ok := len(inBytes) >= queueRequestLength && uint32(len(inBytes)) <= queueRequestLength+r.bufferSize
+	var val T
+	if ok {
+		val, ok = r.readVal(inBytes[queueRequestLength:], len(inBytes)-queueRequestLength)
+	}
+	if !ok {
+		replyBadRequest(request, "invalid topic message")
+		return
+	}
+
+	r.mut.Lock()
+	r.messages = append(r.messages, val)
+	r.published.Broadcast()
+	r.mut.Unlock()
+
+	ack(request)
+}
+
+func (r *TopicRegister[T]) handleSubscriber(request *gio.Request) {
+	inBytes := request.Payload
+	if !isQueueRequest(inBytes) {
+		log.Errorf("register:topic:get:read:error: expected a block flag and a timeout, got %v", inBytes)
+		replyBadRequest(request, "expected a block flag and a timeout, got %v", inBytes)
+		return
+	}
+
+	var val T
+	await(r.published, request, inBytes, func() bool {
+		var hasNext bool
+		val, hasNext = r.next(request.Node)
+		return hasNext
+	}, func(hasNext bool) {
+		if hasNext {
+			if !reply(request, r.writeVal(val)) {
+				r.mut.Lock()
+				r.unget(request.Node, val)
+				r.published.Broadcast()
+				r.mut.Unlock()
+			}
+		} else {
+			refuseOrTimeOut(request, "no unread messages")
+		}
+	})
+}
+
+// handleUnreadCaller replies with the number of messages the node has yet to
+// get (size), whether there are none (empty) or, as topics have no capacity
+// limit, false (full).
+func (r *TopicRegister[T]) handleUnreadCaller(operator string, request *gio.Request) {
+	if len(request.Payload) != 0 {
+		log.Errorf("register:topic:%s:read:error: expected no payload, got %v", operator, request.Payload)
+		replyBadRequest(request, "expected no payload, got %v", request.Payload)
+		return
+	}
+
+	r.mut.Lock()
+	unread := r.unread(request.Node)
+	r.mut.Unlock()
+
+	switch operator {
+	case "size":
+		reply(request, encodeVal(int64(unread)))
+	case "empty":
+		reply(request, encodeVal(unread == 0))
+	case "full":
+		reply(request, encodeVal(false))
+	}
+}
@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	gio "tonysoft.com/gothon/internal/io"
+)
+
+// The requests of a transaction, each starting with one of the following,
+// where an operation is followed by the operation itself (as in a batch).
+const (
+	transactionBegin byte = iota + 1
+	transactionOperation
+	transactionCommit
+	transactionAbort
+)
+
+// Transaction is the type of the register carrying out transactions, being
+// sets of operations on the other registers that are made as one.
+type Transaction struct{}
+
+// TransactionRegister carries out a transaction for each node that begins
+// one, without locking anything while it's open.  The value of each register
+// is copied when it's first operated on, reads return the copy and writes are
+// applied to it.  Registers are only locked (in the order of their IDs, so
+// that transactions can't deadlock) to check that none of those operated on
+// has changed since it was copied, which is done whenever another register is
+// copied, so that the transaction never sees values from different points in
+// time, and on commit, when the values written are stored.  If one has
+// changed, the request is replied to with StatusConflict, nothing being
+// stored.
+type TransactionRegister struct {
+	RegisterBase
+	registry Registry
+	mut      sync.Mutex
+	open     map[int]*transaction
+}
+
+// transaction is the state of an open transaction, where snapshots holds the
+// encoded values of the registers when they were first operated on.
+type transaction struct {
+	registers []batchable
+	vals      map[string]any
+	snapshots map[string][]byte
+	written   bool
+}
+
+func (r *TransactionRegister) Init() {
+	r.open = make(map[int]*transaction)
+}
+
+func (r *TransactionRegister) Handler(action string) (gio.Handler, bool) {
+	if action == "call" {
+		return r.handleTransaction, true
+	}
+	return nil, false
+}
+
+// Disconnect drops the transaction left open by the node, if any, which as
+// nothing is locked while a transaction is open is all there is to undo.
+func (r *TransactionRegister) Disconnect(node int) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	delete(r.open, node)
+}
+
+func (r *TransactionRegister) handleTransaction(request *gio.Request) {
+	if len(request.Payload) == 0 {
+		replyBadRequest(request, "empty transaction request")
+		return
+	}
+
+	// the requests of each node are handled one at a time, so only the map
+	// of open transactions is shared
+	r.mut.Lock()
+	tx := r.open[request.Node]
+	r.mut.Unlock()
+
+	var outBytes []byte
+	var status byte
+	var err error
+	switch kind := request.Payload[0]; {
+	case kind == transactionBegin && tx == nil:
+		r.setOpen(request.Node, &transaction{vals: make(map[string]any), snapshots: make(map[string][]byte)})
+	case kind == transactionOperation && tx != nil:
+		outBytes, status, err = tx.do(r.registry, request.Payload[1:])
+	case kind == transactionCommit && tx != nil:
+		r.setOpen(request.Node, nil)
+		status, err = tx.commit()
+	case kind == transactionAbort && tx != nil:
+		r.setOpen(request.Node, nil)
+	case tx == nil:
+		status, err = gio.StatusBadRequest, fmt.Errorf("no transaction open for request %d", kind)
+	default:
+		status, err = gio.StatusBadRequest, fmt.Errorf("transaction already open for request %d", kind)
+	}
+
+	if err != nil {
+		replyError(request, status, err)
+		return
+	}
+	reply(request, outBytes)
+}
+
+func (r *TransactionRegister) setOpen(node int, tx *transaction) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if tx == nil {
+		delete(r.open, node)
+	} else {
+		r.open[node] = tx
+	}
+}
+
+// do makes the operation, returning the value of the register if it's a read,
+// and otherwise applying the write to its copy of the value.
+func (t *transaction) do(registry Registry, buff []byte) ([]byte, byte, error) {
+	op, _, status, err := decodeOperation(registry, buff)
+	if err != nil {
+		return nil, status, err
+	}
+
+	id := op.register.ID()
+	if _, ok := t.vals[id]; !ok {
+		status, err = t.copy(op.register)
+		if err != nil {
+			return nil, status, err
+		}
+	}
+
+	if op.action == "get" {
+		return op.register.encode(t.vals[id]), gio.StatusOK, nil
+	}
+
+	val, err := op.register.apply(t.vals[id], op.action, op.request)
+	if err != nil {
+		return nil, gio.StatusBadRequest, fmt.Errorf("%s on %s: %v", op.action, id, err)
+	}
+	t.vals[id], t.written = val, true
+	return nil, gio.StatusOK, nil
+}
+
+// copy copies the value of the register, failing with StatusConflict if any of
+// the registers already copied has changed since.
+func (t *transaction) copy(register batchable) (byte, error) {
+	registers := append(append([]batchable{}, t.registers...), register)
+	lockAll(registers)
+	defer unlockAll(registers)
+
+	if status, err := t.validate(); err != nil {
+		return status, err
+	}
+
+	val := register.load()
+	t.registers = append(t.registers, register)
+	t.vals[register.ID()] = val
+	t.snapshots[register.ID()] = register.encode(val)
+	return gio.StatusOK, nil
+}
+
+// commit stores the values written, failing with StatusConflict if any of the
+// registers operated on has changed since it was copied.  A transaction that
+// only read has nothing to check, as its reads were consistent when the last
+// register was copied.
+func (t *transaction) commit() (byte, error) {
+	if !t.written {
+		return gio.StatusOK, nil
+	}
+
+	lockAll(t.registers)
+	defer unlockAll(t.registers)
+
+	if status, err := t.validate(); err != nil {
+		return status, err
+	}
+
+	for _, reg := range t.registers {
+		reg.store(t.vals[reg.ID()])
+	}
+	return gio.StatusOK, nil
+}
+
+// validate checks that none of the registers copied has changed since, which
+// must be called with their locks held.
+func (t *transaction) validate() (byte, error) {
+	for _, reg := range t.registers {
+		if !bytes.Equal(reg.encode(reg.load()), t.snapshots[reg.ID()]) {
+			return gio.StatusConflict, fmt.Errorf("%s was changed by another node during the transaction", reg.ID())
+		}
+	}
+	return gio.StatusOK, nil
+}
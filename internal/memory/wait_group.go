@@ -2,10 +2,8 @@ package memory
 
 import (
 	"encoding/binary"
-	"errors"
-	"io"
-	"net"
 	"sync"
+	gio "tonysoft.com/gothon/internal/io"
 	"tonysoft.com/gothon/pkg/log"
 )
 
@@ -18,40 +16,34 @@ type WaitGroupRegister struct {
 	val *sync.WaitGroup
 }
 
-func (r *WaitGroupRegister) Init() {
-	for i, s := range r.settersIn {
-		go r.processSetter(s, r.settersOut[i])
+func (r *WaitGroupRegister) Handler(action string) (gio.Handler, bool) {
+	if action == "call" {
+		return r.handleSetter, true
 	}
+	return nil, false
 }
 
-func (r *WaitGroupRegister) processSetter(in io.Reader, out io.Writer) {
-	inBytes := make([]byte, int32Length)
-	var val int32
-	var readErr, writeErr error
-	for {
-		_, readErr = in.Read(inBytes)
-		if readErr == nil {
-			val = int32(binary.BigEndian.Uint32(inBytes))
-			switch {
-			case val == 0:
-				r.val.Wait()
-			case val > 0:
-				for i := int32(0); i < val; i++ {
-					r.val.Done()
-				}
-			default:
-				log.Error("register:sync:error: sync val must not be negative")
-				return
-			}
+func (r *WaitGroupRegister) handleSetter(request *gio.Request) {
+	if len(request.Payload) != int32Length {
+		log.Errorf("register:sync:read:error: %v", errRequestLength(int32Length, len(request.Payload)))
+		replyError(request, gio.StatusBadRequest, errRequestLength(int32Length, len(request.Payload)))
+		return
+	}
 
-			_, writeErr = out.Write(syncBytes)
-			if writeErr != nil && !errors.Is(writeErr, net.ErrClosed) {
-				log.Errorf("register:sync:write:error: %v", writeErr)
-				return
-			}
-		} else if !errors.Is(readErr, net.ErrClosed) {
-			log.Errorf("register:sync:read:error: %v", readErr)
-			return
+	val := int32(binary.BigEndian.Uint32(request.Payload))
+	switch {
+	case val == 0:
+		go func() {
+			r.val.Wait()
+			ack(request)
+		}()
+	case val > 0:
+		for i := int32(0); i < val; i++ {
+			r.val.Done()
 		}
+		ack(request)
+	default:
+		log.Error("register:sync:error: sync val must not be negative")
+		replyBadRequest(request, "sync val must not be negative")
 	}
 }
@@ -1,7 +1,12 @@
 package queue
 
+import (
+	"bytes"
+	"math"
+)
+
 type ItemType interface {
-	bool | int64 | float64 | string
+	bool | int64 | float64 | string | []byte
 }
 
 type Base[T ItemType] struct {
@@ -21,3 +26,24 @@ func (q *Base[T]) Empty() bool {
 func (q *Base[T]) Full() bool {
 	return q.pointer >= q.maxSize
 }
+
+// lastIndex returns the index of the last of the items in the queue that is
+// equal to value, or -1 if there's none.
+func (q *Base[T]) lastIndex(value T) int {
+	for i := int(q.pointer) - 1; i >= 0; i-- {
+		if equal(q.items[i], value) {
+			return i
+		}
+	}
+	return -1
+}
+
+func equal[T ItemType](a, b T) bool {
+	switch a := any(a).(type) {
+	case []byte:
+		return bytes.Equal(a, any(b).([]byte))
+	case float64:
+		return math.Float64bits(a) == math.Float64bits(any(b).(float64))
+	}
+	return any(a) == any(b)
+}
@@ -25,3 +25,18 @@ func (q *Fifo[T]) Get() (val T, ok bool) {
 	q.items = q.items[1:]
 	return result, true
 }
+
+func (q *Fifo[T]) Unget(value T) {
+	q.items = append([]T{value}, q.items...)
+	q.pointer++
+}
+
+func (q *Fifo[T]) Unput(value T) bool {
+	i := q.lastIndex(value)
+	if i < 0 {
+		return false
+	}
+	q.items = append(q.items[:i], q.items[i+1:]...)
+	q.pointer--
+	return true
+}
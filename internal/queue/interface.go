@@ -7,6 +7,12 @@ type Queue[T ItemType] interface {
 	Full() bool
 	Put(T) bool
 	Get() (T, bool)
+	// Unget puts back an item got, so that it's the next one out again.  It
+	// ignores the capacity, as another item may have been put in the meantime.
+	Unget(T)
+	// Unput takes back the last item put that is equal to the one given,
+	// returning false if there's none left (i.e. it has been got since).
+	Unput(T) bool
 }
 
 // New Returns either a FiFo queue or LiFo queue (stack).
@@ -22,3 +28,11 @@ func New[T ItemType](maxSize uint64, lastInFirstOut ...bool) Queue[T] {
 	q.maxSize = maxSize
 	return q
 }
+
+// NewPriority Returns a priority queue, from which the lowest item is
+// always retrieved first.  Pass 0 for maxSize for no capacity limit.
+func NewPriority[T OrderedItemType](maxSize uint64) Queue[T] {
+	q := &Priority[T]{}
+	q.maxSize = maxSize
+	return q
+}
@@ -27,3 +27,25 @@ func (q *Lifo[T]) Get() (val T, ok bool) {
 	q.pointer--
 	return q.items[q.pointer], true
 }
+
+func (q *Lifo[T]) Unget(value T) {
+	if q.pointer < uint64(len(q.items)) {
+		q.items[q.pointer] = value
+	} else {
+		q.items = append(q.items, value)
+	}
+	q.pointer++
+}
+
+func (q *Lifo[T]) Unput(value T) bool {
+	i := q.lastIndex(value)
+	if i < 0 {
+		return false
+	}
+	copy(q.items[i:], q.items[i+1:q.pointer])
+	q.pointer--
+	if q.maxSize == 0 {
+		q.items = q.items[:q.pointer]
+	}
+	return true
+}
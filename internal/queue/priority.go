@@ -0,0 +1,86 @@
+package queue
+
+// OrderedItemType is the subset of ItemType whose values can be ordered,
+// which is what a Priority queue requires.
+type OrderedItemType interface {
+	int64 | float64 | string
+}
+
+// Priority NOT thread-safe!  Items are kept in a binary min-heap, so
+// the lowest item is always the next one out (like Python's PriorityQueue).
+type Priority[T OrderedItemType] struct {
+	Base[T]
+}
+
+func (q *Priority[T]) Put(value T) (ok bool) {
+	if q.maxSize != 0 && q.pointer >= q.maxSize {
+		return false
+	}
+	q.items = append(q.items, value)
+	q.up(q.pointer)
+	q.pointer++
+	return true
+}
+
+func (q *Priority[T]) Get() (val T, ok bool) {
+	if q.pointer == 0 {
+		var empty T
+		return empty, false
+	}
+	q.pointer--
+	result := q.items[0]
+	q.items[0] = q.items[q.pointer]
+	q.items = q.items[:q.pointer]
+	q.down(0)
+	return result, true
+}
+
+func (q *Priority[T]) Unget(value T) {
+	q.items = append(q.items, value)
+	q.up(q.pointer)
+	q.pointer++
+}
+
+func (q *Priority[T]) Unput(value T) bool {
+	i := q.lastIndex(value)
+	if i < 0 {
+		return false
+	}
+	q.pointer--
+	q.items[i] = q.items[q.pointer]
+	q.items = q.items[:q.pointer]
+	if uint64(i) < q.pointer {
+		q.down(uint64(i))
+		q.up(uint64(i))
+	}
+	return true
+}
+
+func (q *Priority[T]) up(i uint64) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if q.items[parent] <= q.items[i] {
+			return
+		}
+		q.items[parent], q.items[i] = q.items[i], q.items[parent]
+		i = parent
+	}
+}
+
+func (q *Priority[T]) down(i uint64) {
+	for {
+		lowest := i
+		left, right := 2*i+1, 2*i+2
+		if left < q.pointer && q.items[left] < q.items[lowest] {
+			lowest = left
+		}
+		if right < q.pointer && q.items[right] < q.items[lowest] {
+			lowest = right
+		}
+		if lowest == i {
+			return
+		}
+		q.items[lowest], q.items[i] = q.items[i], q.items[lowest]
+		i = lowest
+	}
+}
@@ -21,12 +21,19 @@ func Start(ctx context.Context, cancel context.CancelFunc, projectDir string, no
 		return err
 	}
 
-	socketArray, err := initIO(gothonDir, nodeCount, pkg)
+	err = initSharedMemory(gothonDir, pkg)
 	if err != nil {
 		return err
 	}
 
-	err = initMemory(socketArray, pkg, nodeCount)
+	nodeArray := initIO(gothonDir, nodeCount)
+
+	err = initMemory(nodeArray, pkg, nodeCount)
+	if err != nil {
+		return err
+	}
+
+	err = nodeArray.Listen()
 	if err != nil {
 		return err
 	}
@@ -36,7 +43,7 @@ func Start(ctx context.Context, cancel context.CancelFunc, projectDir string, no
 	go func() {
 		<-ctx.Done()
 		processGroup.Stop()
-		socketArray.Close()
+		nodeArray.Close()
 		closeSession(gothonDir)
 	}()
 
@@ -59,15 +66,14 @@ func initSession(projectDir string, nodeCount int) (gothonDir string, err error)
 	sockRootDir := filepath.Join(gothonDir, "sock")
 	srcRootDir := filepath.Join(gothonDir, "src")
 
+	err = os.MkdirAll(sockRootDir, 0775)
+	if err != nil {
+		return "", err
+	}
+
 	for i := 0; i < nodeCount; i++ {
-		sockDir := filepath.Join(sockRootDir, strconv.Itoa(i))
 		srcDir := filepath.Join(srcRootDir, strconv.Itoa(i))
 
-		err = os.MkdirAll(sockDir, 0775)
-		if err != nil {
-			return "", err
-		}
-
 		err = os.MkdirAll(srcDir, 0775)
 		if err != nil {
 			return "", err
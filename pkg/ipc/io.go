@@ -2,24 +2,21 @@ package ipc
 
 import (
 	"errors"
-	"fmt"
 	"path/filepath"
 	"tonysoft.com/gothon/internal/code"
 	"tonysoft.com/gothon/internal/io"
 )
 
-func initIO(gothonDir string, nodeCount int, pkg code.Package) (io.SocketArray, error) {
-	socketArray := io.NewSocketArray(filepath.Join(gothonDir, "sock"), getSocketPaths(pkg), nodeCount)
-	err := socketArray.Listen()
-	if err != nil {
-		return nil, err
-	}
-
-	return socketArray, nil
+// initIO creates the connections to the nodes, which only start listening once
+// the registers have set their handlers, see initMemory.
+func initIO(gothonDir string, nodeCount int) io.NodeArray {
+	return io.NewNodeArray(filepath.Join(gothonDir, "sock"), nodeCount)
 }
 
-func getSocketPaths(p code.Package) (paths []string) {
-	pathsMap := make(map[string]any)
+// getActionTags returns the actions made on the variables of the package, each
+// tagged with the ID of its variable, e.g. main/_counter_/add.
+func getActionTags(p code.Package) (tags []string) {
+	tagsMap := make(map[string]any)
 	invalidErr := errors.New("invalid action for variable type")
 
 	for _, mod := range p {
@@ -30,55 +27,47 @@ func getSocketPaths(p code.Package) (paths []string) {
 
 			if stmt.Actions.Contains(code.VariableDefinition) {
 				switch stmt.TargetVariable.Type {
-				case code.LockFunc, code.UnlockFunc, code.WaitGroup:
-					pathsMap[fmt.Sprintf("%s/%s_%s", mod.Name, stmt.TargetVariable.Name, "in")] = nil
-					pathsMap[fmt.Sprintf("%s/%s_%s", mod.Name, stmt.TargetVariable.Name, "out")] = nil
+				case code.LockFunc, code.UnlockFunc, code.RLockFunc, code.RUnlockFunc, code.AcquireFunc, code.ReleaseFunc,
+					code.WaitGroup, code.Barrier, code.Batch, code.Transaction:
+					tagsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "call")] = nil
 				default:
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "set_in")] = nil
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "set_out")] = nil
+					tagsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "set")] = nil
 				}
 			}
 
 			if stmt.Actions.Contains(code.VariableAssignment) || stmt.Actions.Contains(code.QueuePut) {
 				switch stmt.TargetVariable.Type {
-				case code.LockFunc, code.UnlockFunc:
+				case code.LockFunc, code.UnlockFunc, code.RLockFunc, code.RUnlockFunc:
 					panic(invalidErr)
 				default:
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "set_in")] = nil
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "set_out")] = nil
+					tagsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "set")] = nil
 				}
 			}
 
 			if stmt.Actions == code.QueueGet {
 				switch stmt.TargetVariable.Type {
-				case code.Queue, code.LifoQueue:
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "get_in")] = nil
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "get_out")] = nil
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "get_ok")] = nil
+				case code.Queue, code.LifoQueue, code.PriorityQueue, code.Topic:
+					tagsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "get")] = nil
 				}
 			}
 
 			if stmt.Actions.Contains(code.VariableUsage) || stmt.Actions == code.QueueGet {
 				for _, v := range stmt.UsedVariables {
 					switch v.Type {
-					case code.LockFunc, code.UnlockFunc:
+					case code.LockFunc, code.UnlockFunc, code.RLockFunc, code.RUnlockFunc:
 						panic(invalidErr)
-					case code.Queue, code.LifoQueue:
-						pathsMap[filepath.Join(mod.Name, v.Name, "get_in")] = nil
-						pathsMap[filepath.Join(mod.Name, v.Name, "get_out")] = nil
-						pathsMap[filepath.Join(mod.Name, v.Name, "get_ok")] = nil
+					case code.AcquireFunc, code.ReleaseFunc, code.Barrier, code.Batch, code.Transaction, code.Event, code.Condition:
+						// these are never read, only called (or their methods are)
 					default:
-						pathsMap[filepath.Join(mod.Name, v.Name, "get_in")] = nil
-						pathsMap[filepath.Join(mod.Name, v.Name, "get_out")] = nil
+						tagsMap[filepath.Join(mod.Name, v.Name, "get")] = nil
 					}
 				}
 			}
 
 			if stmt.Actions.Contains(code.VariableAdd) {
 				switch stmt.TargetVariable.Type {
-				case code.Int, code.Float, code.Str:
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "add_in")] = nil
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "add_out")] = nil
+				case code.Int, code.Float, code.Str, code.Bytes:
+					tagsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "add")] = nil
 				default:
 					panic(invalidErr)
 				}
@@ -87,8 +76,7 @@ func getSocketPaths(p code.Package) (paths []string) {
 			if stmt.Actions.Contains(code.VariableSubtract) {
 				switch stmt.TargetVariable.Type {
 				case code.Int, code.Float, code.Str:
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "sub_in")] = nil
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "sub_out")] = nil
+					tagsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "sub")] = nil
 				default:
 					panic(invalidErr)
 				}
@@ -97,8 +85,7 @@ func getSocketPaths(p code.Package) (paths []string) {
 			if stmt.Actions.Contains(code.VariableMultiply) {
 				switch stmt.TargetVariable.Type {
 				case code.Int, code.Float:
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "mul_in")] = nil
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "mul_out")] = nil
+					tagsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "mul")] = nil
 				default:
 					panic(invalidErr)
 				}
@@ -107,45 +94,54 @@ func getSocketPaths(p code.Package) (paths []string) {
 			if stmt.Actions.Contains(code.VariableDivide) {
 				switch stmt.TargetVariable.Type {
 				case code.Int, code.Float:
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "div_in")] = nil
-					pathsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "div_out")] = nil
+					tagsMap[filepath.Join(mod.Name, stmt.TargetVariable.Name, "div")] = nil
 				default:
 					panic(invalidErr)
 				}
 			}
 
+			for _, a := range stmt.GetRegisterActions() {
+				tagsMap[filepath.Join(mod.Name, a.Variable.Name, a.Action)] = nil
+			}
+
 			if stmt.Actions.Contains(code.QueueSize) {
 				for _, v := range stmt.UsedVariables {
-					if v.Type == code.Queue || v.Type == code.LifoQueue {
-						pathsMap[filepath.Join(mod.Name, v.Name, "size_in")] = nil
-						pathsMap[filepath.Join(mod.Name, v.Name, "size_out")] = nil
+					if v.IsQueue() {
+						tagsMap[filepath.Join(mod.Name, v.Name, "size")] = nil
 					}
 				}
 			}
 
 			if stmt.Actions.Contains(code.QueueEmpty) {
 				for _, v := range stmt.UsedVariables {
-					if v.Type == code.Queue || v.Type == code.LifoQueue {
-						pathsMap[filepath.Join(mod.Name, v.Name, "empty_in")] = nil
-						pathsMap[filepath.Join(mod.Name, v.Name, "empty_out")] = nil
+					if v.IsQueue() {
+						tagsMap[filepath.Join(mod.Name, v.Name, "empty")] = nil
 					}
 				}
 			}
 
 			if stmt.Actions.Contains(code.QueueFull) {
 				for _, v := range stmt.UsedVariables {
-					if v.Type == code.Queue || v.Type == code.LifoQueue {
-						pathsMap[filepath.Join(mod.Name, v.Name, "full_in")] = nil
-						pathsMap[filepath.Join(mod.Name, v.Name, "full_out")] = nil
+					if v.IsQueue() {
+						tagsMap[filepath.Join(mod.Name, v.Name, "full")] = nil
 					}
 				}
 			}
 		}
 	}
 
-	for k := range pathsMap {
-		paths = append(paths, k)
+	// variables kept in the shared memory file are never sent requests for
+	for _, v := range p.GetSharedMemoryVariables() {
+		for k := range tagsMap {
+			if filepath.Dir(k) == v.ID {
+				delete(tagsMap, k)
+			}
+		}
+	}
+
+	for k := range tagsMap {
+		tags = append(tags, k)
 	}
 
-	return paths
+	return tags
 }
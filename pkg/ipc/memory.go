@@ -3,6 +3,7 @@ package ipc
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,18 +14,34 @@ import (
 	"tonysoft.com/gothon/internal/queue"
 )
 
-func initMemory(socketArray io.SocketArray, pkg code.Package, nodeCount int) error {
+func initMemory(nodeArray io.NodeArray, pkg code.Package, nodeCount int) error {
 	err := configureGlobalOptions()
 	if err != nil {
 		return err
 	}
 
 	registry := memory.NewRegistry(getRegisters(pkg, nodeCount))
-	configureRegistry(registry, socketArray)
 	registry.Init()
+	configureRegistry(registry, nodeArray, pkg, getActionTags(pkg))
 	return nil
 }
 
+// initSharedMemory creates the shared memory file holding the values of the
+// variables that opted into it, if there are any.
+func initSharedMemory(gothonDir string, pkg code.Package) error {
+	vars := pkg.GetSharedMemoryVariables()
+	if len(vars) == 0 {
+		return nil
+	}
+
+	values := make([]any, len(vars))
+	for _, v := range vars {
+		values[v.SharedMemorySlot] = v.DefaultValue
+	}
+
+	return memory.NewSharedMemory(filepath.Join(gothonDir, "shm"), values)
+}
+
 func configureGlobalOptions() error {
 	strMaxSize := os.Getenv("GOTHON_STRING_MAX_SIZE")
 	if strMaxSize != "" {
@@ -39,11 +56,12 @@ func configureGlobalOptions() error {
 
 func getRegisters(pkg code.Package, nodeCount int) []memory.Register {
 	regMap := make(map[string]memory.Register)
+	conditions := make([]*code.Variable, 0)
 
 	for _, mod := range pkg {
 		for _, stmt := range mod.Statements {
 			if stmt.Actions.Contains(code.VariableDefinition) {
-				if stmt.ShouldSkip {
+				if stmt.ShouldSkip || stmt.TargetVariable.SharedMemory {
 					continue
 				}
 
@@ -56,26 +74,39 @@ func getRegisters(pkg code.Package, nodeCount int) []memory.Register {
 					regMap[stmt.TargetVariable.ID] = memory.NewRegister[float64](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
 				case code.Str:
 					regMap[stmt.TargetVariable.ID] = memory.NewRegister[string](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
-				case code.LockFunc, code.UnlockFunc:
+				case code.Bytes:
+					regMap[stmt.TargetVariable.ID] = memory.NewRegister[[]byte](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+				case code.Object:
+					regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Object](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+				case code.LockFunc, code.UnlockFunc, code.RLockFunc, code.RUnlockFunc:
 					pathParts := strings.Split(stmt.TargetVariable.ID, "/")
 					action := pathParts[len(pathParts)-1]
 					id := strings.TrimSuffix(stmt.TargetVariable.ID, action)
 					id += stmt.TargetVariable.Tag
-					regMap[id] = memory.NewRegister[sync.Mutex](id, sync.Mutex{})
-				case code.WaitGroup:
-					defaultVal := 0
-					if stmt.TargetVariable.DefaultValue == fmt.Sprintf("%snode_count%s", mod.VariablePrefix, mod.VariableSuffix) {
-						defaultVal = nodeCount
+					if hasReadLock(mod, stmt.TargetVariable.Tag) {
+						regMap[id] = memory.NewRegister[sync.RWMutex](id, sync.RWMutex{})
 					} else {
-						v, err := strconv.Atoi(stmt.TargetVariable.DefaultValue.(string))
-						if err != nil {
-							panic(err)
-						}
-						defaultVal = v
+						regMap[id] = memory.NewRegister[sync.Mutex](id, sync.Mutex{})
 					}
-
+				case code.AcquireFunc:
+					pathParts := strings.Split(stmt.TargetVariable.ID, "/")
+					action := pathParts[len(pathParts)-1]
+					id := strings.TrimSuffix(stmt.TargetVariable.ID, action)
+					id += stmt.TargetVariable.Tag
+					regMap[id] = memory.NewRegister[memory.Semaphore](id, make(memory.Semaphore, getCount(mod, stmt.TargetVariable, nodeCount)))
+				case code.Event:
+					regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Event](stmt.TargetVariable.ID, memory.Event(false))
+				case code.Condition:
+					conditions = append(conditions, stmt.TargetVariable)
+				case code.Barrier:
+					regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Barrier](stmt.TargetVariable.ID, memory.Barrier(getCount(mod, stmt.TargetVariable, nodeCount)))
+				case code.Batch:
+					regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Batch](stmt.TargetVariable.ID, memory.Batch{})
+				case code.Transaction:
+					regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Transaction](stmt.TargetVariable.ID, memory.Transaction{})
+				case code.WaitGroup:
 					var wg sync.WaitGroup
-					wg.Add(defaultVal)
+					wg.Add(getCount(mod, stmt.TargetVariable, nodeCount))
 					regMap[stmt.TargetVariable.ID] = memory.NewRegister[*sync.WaitGroup](stmt.TargetVariable.ID, &wg)
 				case code.Queue:
 					switch stmt.TargetVariable.SubType {
@@ -87,6 +118,8 @@ func getRegisters(pkg code.Package, nodeCount int) []memory.Register {
 						regMap[stmt.TargetVariable.ID] = memory.NewRegister[queue.Fifo[float64]](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
 					case code.Str:
 						regMap[stmt.TargetVariable.ID] = memory.NewRegister[queue.Fifo[string]](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					case code.Bytes, code.Object:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[queue.Fifo[[]byte]](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
 					}
 				case code.LifoQueue:
 					switch stmt.TargetVariable.SubType {
@@ -98,6 +131,87 @@ func getRegisters(pkg code.Package, nodeCount int) []memory.Register {
 						regMap[stmt.TargetVariable.ID] = memory.NewRegister[queue.Lifo[float64]](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
 					case code.Str:
 						regMap[stmt.TargetVariable.ID] = memory.NewRegister[queue.Lifo[string]](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					case code.Bytes, code.Object:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[queue.Lifo[[]byte]](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					}
+				case code.PriorityQueue:
+					switch stmt.TargetVariable.SubType {
+					case code.Int:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[queue.Priority[int64]](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					case code.Float:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[queue.Priority[float64]](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					case code.Str:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[queue.Priority[string]](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					}
+				case code.Topic:
+					switch stmt.TargetVariable.SubType {
+					case code.Bool:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Topic[bool]](stmt.TargetVariable.ID, nodeCount)
+					case code.Int:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Topic[int64]](stmt.TargetVariable.ID, nodeCount)
+					case code.Float:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Topic[float64]](stmt.TargetVariable.ID, nodeCount)
+					case code.Str:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Topic[string]](stmt.TargetVariable.ID, nodeCount)
+					case code.Bytes, code.Object:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Topic[[]byte]](stmt.TargetVariable.ID, nodeCount)
+					}
+				case code.Chan:
+					size := stmt.TargetVariable.DefaultValue.(int64)
+					switch stmt.TargetVariable.SubType {
+					case code.Bool:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Chan[bool]](stmt.TargetVariable.ID, memory.Chan[bool](size))
+					case code.Int:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Chan[int64]](stmt.TargetVariable.ID, memory.Chan[int64](size))
+					case code.Float:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Chan[float64]](stmt.TargetVariable.ID, memory.Chan[float64](size))
+					case code.Str:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Chan[string]](stmt.TargetVariable.ID, memory.Chan[string](size))
+					case code.Bytes, code.Object:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[memory.Chan[[]byte]](stmt.TargetVariable.ID, memory.Chan[[]byte](size))
+					}
+				case code.List:
+					switch stmt.TargetVariable.SubType {
+					case code.Bool:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[[]bool](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					case code.Int:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[[]int64](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					case code.Float:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[[]float64](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					case code.Str:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[[]string](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					}
+				case code.Set:
+					switch stmt.TargetVariable.KeyType {
+					case code.Str:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[map[string]struct{}](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					case code.Int:
+						regMap[stmt.TargetVariable.ID] = memory.NewRegister[map[int64]struct{}](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+					}
+				case code.Dict:
+					switch stmt.TargetVariable.KeyType {
+					case code.Str:
+						switch stmt.TargetVariable.SubType {
+						case code.Bool:
+							regMap[stmt.TargetVariable.ID] = memory.NewRegister[map[string]bool](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+						case code.Int:
+							regMap[stmt.TargetVariable.ID] = memory.NewRegister[map[string]int64](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+						case code.Float:
+							regMap[stmt.TargetVariable.ID] = memory.NewRegister[map[string]float64](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+						case code.Str:
+							regMap[stmt.TargetVariable.ID] = memory.NewRegister[map[string]string](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+						}
+					case code.Int:
+						switch stmt.TargetVariable.SubType {
+						case code.Bool:
+							regMap[stmt.TargetVariable.ID] = memory.NewRegister[map[int64]bool](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+						case code.Int:
+							regMap[stmt.TargetVariable.ID] = memory.NewRegister[map[int64]int64](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+						case code.Float:
+							regMap[stmt.TargetVariable.ID] = memory.NewRegister[map[int64]float64](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+						case code.Str:
+							regMap[stmt.TargetVariable.ID] = memory.NewRegister[map[int64]string](stmt.TargetVariable.ID, stmt.TargetVariable.DefaultValue)
+						}
 					}
 				}
 
@@ -105,6 +219,18 @@ func getRegisters(pkg code.Package, nodeCount int) []memory.Register {
 		}
 	}
 
+	// conditions are created once the registers of the mutexes they're tied to
+	// have been
+	for _, v := range conditions {
+		pathParts := strings.Split(v.ID, "/")
+		lockID := strings.TrimSuffix(v.ID, pathParts[len(pathParts)-1]) + v.Tag
+		lock, ok := regMap[lockID].(interface{ Locker() memory.Lock })
+		if !ok {
+			panic(fmt.Errorf("no mutex found for condition %s", v.ID))
+		}
+		regMap[v.ID] = memory.NewRegister[memory.Condition](v.ID, memory.Condition{L: lock.Locker()})
+	}
+
 	regs := make([]memory.Register, 0)
 	for _, reg := range regMap {
 		regs = append(regs, reg)
@@ -112,80 +238,67 @@ func getRegisters(pkg code.Package, nodeCount int) []memory.Register {
 	return regs
 }
 
-func configureRegistry(registry memory.Registry, socketArray io.SocketArray) {
-	for _, socket := range socketArray {
-		pathParts := strings.Split(socket.Path(), "/")
-		action := pathParts[len(pathParts)-1]
-		varId := strings.TrimSuffix(socket.Tag, "/"+action)
-
-		switch action {
-		case "set_in":
-			registry[varId].AddSetterIn(socket)
-		case "set_out":
-			registry[varId].AddSetterOut(socket)
-		case "get_in":
-			registry[varId].AddGetterIn(socket)
-		case "get_out":
-			registry[varId].AddGetterOut(socket)
-		case "get_ok":
-			registry[varId].AddGetterOk(socket)
-		case "add_in":
-			registry[varId].AddAdderIn(socket)
-		case "add_out":
-			registry[varId].AddAdderOut(socket)
-		case "sub_in":
-			registry[varId].AddSubtractorIn(socket)
-		case "sub_out":
-			registry[varId].AddSubtractorOut(socket)
-		case "mul_in":
-			registry[varId].AddMultiplierIn(socket)
-		case "mul_out":
-			registry[varId].AddMultiplierOut(socket)
-		case "div_in":
-			registry[varId].AddDividerIn(socket)
-		case "div_out":
-			registry[varId].AddDividerOut(socket)
-		case "size_in":
-			registry[varId].AddSizeCallerIn(socket)
-		case "size_out":
-			registry[varId].AddSizeCallerOut(socket)
-		case "empty_in":
-			registry[varId].AddEmptyCallerIn(socket)
-		case "empty_out":
-			registry[varId].AddEmptyCallerOut(socket)
-		case "full_in":
-			registry[varId].AddFullCallerIn(socket)
-		case "full_out":
-			registry[varId].AddFullCallerOut(socket)
-		default:
-			if strings.Contains(socket.Tag, "sync_") {
-				varId = socket.Tag
-				varId = strings.TrimSuffix(varId, "_in")
-				varId = strings.TrimSuffix(varId, "_out")
-				if strings.HasSuffix(socket.Tag, "_in") {
-					registry[varId].AddSetterIn(socket)
-				} else if strings.HasSuffix(socket.Tag, "_out") {
-					registry[varId].AddSetterOut(socket)
-				}
-			} else {
-				varId = socket.Tag
-				varId = strings.Replace(varId, "unlock_", "mutex_", 1)
-				varId = strings.Replace(varId, "unlock_", "mutex_", 1)
-				varId = strings.Replace(varId, "lock_", "mutex_", 1)
-				varId = strings.Replace(varId, "lock_", "mutex_", 1)
-				varId = strings.TrimSuffix(varId, "_in")
-				varId = strings.TrimSuffix(varId, "_out")
-
-				if strings.Contains(socket.Tag, "unlock_") && strings.HasSuffix(socket.Tag, "_in") {
-					registry[varId].AddUnlockerIn(socket)
-				} else if strings.Contains(socket.Tag, "unlock_") && strings.HasSuffix(socket.Tag, "_out") {
-					registry[varId].AddUnlockerOut(socket)
-				} else if strings.Contains(socket.Tag, "lock_") && strings.HasSuffix(socket.Tag, "_in") {
-					registry[varId].AddLockerIn(socket)
-				} else if strings.Contains(socket.Tag, "lock_") && strings.HasSuffix(socket.Tag, "_out") {
-					registry[varId].AddLockerOut(socket)
-				}
+// configureRegistry sets the handlers of the registers for the actions tagged,
+// on every node.  Callables are all called, so those of locks and semaphores
+// are handled by the register of their mutex or semaphore (see the variable's
+// tag), with the action of the callable.  Actions a register has no handler for
+// are left unhandled, so that the nodes are told the operation doesn't exist.
+func configureRegistry(registry memory.Registry, nodeArray io.NodeArray, pkg code.Package, tags []string) {
+	for _, tag := range tags {
+		action := filepath.Base(tag)
+		varId := filepath.Dir(tag)
+
+		if v := pkg.GetVariableByID(varId); v != nil && action == "call" {
+			if callAction, ok := callableActions[v.Type]; ok {
+				action = callAction
+				varId = filepath.Join(filepath.Dir(varId), v.Tag)
 			}
 		}
+
+		reg, ok := registry[varId]
+		if !ok {
+			continue
+		}
+		if handler, ok := reg.Handler(action); ok {
+			nodeArray.Handle(filepath.Base(tag), filepath.Dir(tag), handler)
+		}
+	}
+
+	nodeArray.OnDisconnect(registry.Disconnect)
+}
+
+// callableActions are the actions of the callables handled by the register of
+// their mutex or semaphore.
+var callableActions = map[code.VariableType]string{
+	code.LockFunc:    "lock",
+	code.UnlockFunc:  "unlock",
+	code.RLockFunc:   "rlock",
+	code.RUnlockFunc: "runlock",
+	code.AcquireFunc: "acquire",
+	code.ReleaseFunc: "release",
+}
+
+// hasReadLock reports whether the module declares a read lock (or unlock) for
+// the mutex with the given tag, in which case it's backed by a sync.RWMutex.
+func hasReadLock(mod *code.Module, tag string) bool {
+	for _, v := range mod.GetVariables() {
+		if (v.Type == code.RLockFunc || v.Type == code.RUnlockFunc) && v.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// getCount returns the count given as the default argument of a sync, semaphore
+// or barrier declaration, e.g. 8 for lambda n=8: ().
+func getCount(mod *code.Module, v *code.Variable, nodeCount int) int {
+	if v.DefaultValue == fmt.Sprintf("%snode_count%s", mod.VariablePrefix, mod.VariableSuffix) {
+		return nodeCount
+	}
+
+	count, err := strconv.Atoi(v.DefaultValue.(string))
+	if err != nil {
+		panic(err)
 	}
+	return count
 }
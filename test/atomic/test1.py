@@ -0,0 +1,44 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_ticket_: int = 0
+_issued_: set[int] = set()
+_total_: float = 0.0
+_log_: str = ''
+_leader_: int = -1
+_elections_: int = 0
+_done_: bool = False
+
+
+if __name__ == '__main__':
+    for _ in range(10):
+        assert _issued_.add(_ticket_.fetch_add(1))
+
+    _total_.fetch_add(0.5)
+    _log_.fetch_add(str(_node_))
+
+    if _leader_.compare_and_swap(-1, _node_):
+        _elections_ += 1
+
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _ticket_ == 10 * _node_count_
+        assert len(_issued_) == 10 * _node_count_
+        assert _total_ == 0.5 * _node_count_
+        assert sorted(_log_) == sorted(''.join(str(i) for i in range(_node_count_)))
+        assert _elections_ == 1 and 0 <= _leader_ < _node_count_
+        assert not _leader_.compare_and_swap(-1, 0)
+
+        log = _log_.swap('')
+        assert len(log) == _node_count_ and _log_ == ''
+        assert _log_.fetch_add('x') == '' and _log_ == 'x'
+
+        assert not _done_.swap(True)
+        assert _done_.swap(True)
+        assert _done_.compare_and_swap(True, False) and not _done_
+
+        print('leader:', _leader_, 'log:', log)
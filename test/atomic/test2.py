@@ -0,0 +1,33 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_highest_: int = -1
+_lowest_: int = 1000
+_best_: float = 0.0
+_worst_: float = 1000.0
+
+
+if __name__ == '__main__':
+    for i in range(10):
+        _highest_ = max(_highest_, _node_ * 10 + i)
+        _lowest_ = min(100 + _node_ * 10 + i, _lowest_)
+
+    _best_ = max(_best_, (_node_ + 1) * 1.5)
+    _worst_ = min(_worst_, (_node_ + 1) * 1.5)
+
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _highest_ == _node_count_ * 10 - 1
+        assert _lowest_ == 100
+        assert _best_ == _node_count_ * 1.5
+        assert _worst_ == 1.5
+
+        previous = _highest_
+        _highest_ = max(_highest_, -5)
+        assert _highest_ == previous
+
+        print('highest:', _highest_, 'best:', _best_)
@@ -0,0 +1,20 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_barrier_epoch_: callable = lambda n=_node_count_: ()
+
+_total_: int = 0
+_leaders_: int = 0
+
+
+if __name__ == '__main__':
+    for epoch in range(1, 6):
+        _total_ += 1
+        if _barrier_epoch_() == 0:
+            _leaders_ += 1
+
+        # no node can start the next epoch before every node has checked
+        assert _total_ == epoch * _node_count_
+        _barrier_epoch_()
+
+    assert _leaders_ == 5
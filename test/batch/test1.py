@@ -0,0 +1,79 @@
+from _gothon_ import GothonError
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+_batch_main_: callable = lambda: ()
+
+_counter_: int = 0
+_balance_a_: int = 1000
+_balance_b_: int = 0
+_bits_: int = 0
+_total_: float = 0.0
+_done_: bool = False
+_log_: str = ''
+_data_: bytes = b''
+_last_: object = None
+
+
+if __name__ == '__main__':
+    for _ in range(10):
+        with _batch_main_():
+            _balance_a_ -= 10
+            _balance_b_ += 10
+
+    with _batch_main_():
+        for _ in range(1000):
+            _counter_ += 1
+        _bits_ |= 1 << _node_
+        _total_ += 0.5
+        _log_ += 'x'
+        _data_ += b'y'
+
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _balance_a_ == 1000 - 100 * _node_count_
+        assert _balance_b_ == 100 * _node_count_
+        assert _counter_ == 1000 * _node_count_
+        assert _bits_ == (1 << _node_count_) - 1
+        assert _total_ == 0.5 * _node_count_
+        assert _log_ == 'x' * _node_count_
+        assert _data_ == b'y' * _node_count_
+
+        # writes are only applied when the block exits
+        with _batch_main_():
+            _counter_ = 5
+            _done_ = True
+            _last_ = {'node': _node_}
+            assert _counter_ == 1000 * _node_count_ and not _done_ and _last_ is None
+        assert _counter_ == 5 and _done_ and _last_ == {'node': 0}
+
+        # nested batches are applied with the outermost
+        with _batch_main_():
+            _counter_ += 1
+            with _batch_main_():
+                _counter_ += 1
+            assert _counter_ == 5
+        assert _counter_ == 7
+
+        # nothing is applied if the block raises
+        try:
+            with _batch_main_():
+                _counter_ = 8
+                raise ValueError()
+        except ValueError:
+            pass
+        assert _counter_ == 7
+
+        # nor if any of the writes fails
+        try:
+            with _batch_main_():
+                _counter_ = 9
+                _log_ += 'z' * 100000
+            assert False, 'expected the batch to fail'
+        except GothonError as e:
+            assert '_batch_main_' in str(e) and 'maximum size' in str(e)
+        assert _counter_ == 7 and _log_ == 'x' * _node_count_
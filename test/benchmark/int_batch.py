@@ -0,0 +1,21 @@
+import os
+import time
+
+_node_: int = 0
+_node_count_: int = 0
+
+_batch_main_: callable = lambda: ()
+_counter_: int = 0
+
+
+if __name__ == '__main__':
+    n = int(os.environ.get('GOTHON_BENCHMARK_N', '10000'))
+
+    start = time.perf_counter_ns()
+    with _batch_main_():
+        for _ in range(n):
+            _counter_ += 1
+    add = (time.perf_counter_ns() - start) / n
+
+    assert _counter_ >= n
+    print('benchmark', add)
@@ -0,0 +1,25 @@
+# gothon:shared_memory = True
+
+import os
+import time
+
+_node_: int = 0
+_node_count_: int = 0
+
+_counter_: int = 0
+
+
+if __name__ == '__main__':
+    n = int(os.environ.get('GOTHON_BENCHMARK_N', '10000'))
+
+    start = time.perf_counter_ns()
+    for _ in range(n):
+        _counter_ += 1
+    add = (time.perf_counter_ns() - start) / n
+
+    start = time.perf_counter_ns()
+    for _ in range(n):
+        assert _counter_ > 0
+    get = (time.perf_counter_ns() - start) / n
+
+    print('benchmark', add, get)
@@ -0,0 +1,35 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_header_: bytes = b'\x89PNG\r\n\x1a\n'
+_log_: bytes = b''
+
+
+def append_entry(entry):
+    global _log_
+    _log_ += entry
+
+
+if __name__ == '__main__':
+    append_entry(bytes([_node_, 0xff, 0x00]))
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _header_ == b'\x89PNG\r\n\x1a\n'
+
+        log = _log_
+        assert len(log) == 3 * _node_count_
+        assert sorted(log[0::3]) == list(range(_node_count_))
+        assert set(log[1::3]) == {0xff} and set(log[2::3]) == {0x00}
+
+        tile = bytes(range(256)) * 16
+        _header_ = tile
+        assert _header_ == tile
+
+        _header_ = b''
+        assert _header_ == b''
+
+        print('log:', log.hex())
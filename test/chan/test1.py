@@ -0,0 +1,53 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_jobs_: Chan[int] = Chan(4)
+_results_: Chan[str] = Chan(100)
+_done_: Chan[bool] = Chan()
+_blobs_: Chan[object] = Chan(1)
+
+
+if __name__ == '__main__':
+    if _node_ == 0:
+        for i in range(20):
+            _jobs_.send(i)
+        _jobs_.close()
+
+        try:
+            _jobs_.send(20)
+            assert False, 'expected send on closed channel to fail'
+        except ValueError:
+            pass
+
+        try:
+            _jobs_.close()
+            assert False, 'expected close of closed channel to fail'
+        except ValueError:
+            pass
+    else:
+        for job in _jobs_:
+            _results_.send(f'{_node_}:{job * job}')
+
+        _, ok = _jobs_.recv()
+        assert not ok
+
+        _done_.send(True)
+
+    if _node_ == 0:
+        for _ in range(_node_count_ - 1):
+            done, ok = _done_.recv(timeout=5)
+            assert ok and done
+        _results_.close()
+
+        squares = []
+        for result in _results_:
+            squares.append(int(result.split(':')[1]))
+        assert sorted(squares) == [i * i for i in range(20)]
+
+        done, ok = _done_.recv(timeout=0.1)
+        assert not ok and done is False
+
+        assert _blobs_.send({'node': _node_})
+        assert not _blobs_.send({'node': -1}, timeout=0.1)
+        blob, ok = _blobs_.recv()
+        assert ok and blob == {'node': 0}
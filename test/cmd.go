@@ -10,7 +10,7 @@ import (
 	"time"
 )
 
-func installGothon(t *testing.T) {
+func installGothon(t testing.TB) {
 	var err error
 	cmd := exec.Command("./install.sh")
 	cmd.Dir, err = filepath.Abs("..")
@@ -26,7 +26,7 @@ func installGothon(t *testing.T) {
 	}
 }
 
-func runGothon(t *testing.T, projectDir string, nodeCount int, modules ...string) {
+func runGothon(t testing.TB, projectDir string, nodeCount int, modules ...string) {
 	var err error
 
 	run := func(cmd *exec.Cmd) {
@@ -109,3 +109,60 @@ func runGothon(t *testing.T, projectDir string, nodeCount int, modules ...string
 		}
 	}
 }
+
+// benchmarkOperationCount is the number of times each node performs each of
+// the operations timed by a benchmark module.
+const benchmarkOperationCount = 10000
+
+// benchmarkGothon benchmarks each kind of operation timed by the module as a
+// sub-benchmark, running the module once per iteration.  Each node times its
+// operations itself and prints the mean time taken by each kind of operation on
+// a line starting with "benchmark", the mean of which over every node and run
+// is reported as the ns/op of the kind's sub-benchmark.  The ns/op measured by
+// the benchmark itself would include starting Gothon and the nodes, so it's
+// replaced.
+func benchmarkGothon(b *testing.B, projectDir string, nodeCount int, module string, kinds ...string) {
+	installGothon(b)
+
+	dir, err := filepath.Abs(projectDir)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i, kind := range kinds {
+		b.Run(kind, func(b *testing.B) {
+			total := 0.0
+			samples := 0
+
+			for n := 0; n < b.N; n++ {
+				cmd := exec.Command("gothon", strconv.Itoa(nodeCount), module)
+				cmd.Dir = dir
+				cmd.Env = append(os.Environ(), "GOTHON_BENCHMARK_N="+strconv.Itoa(benchmarkOperationCount))
+
+				output, e := cmd.Output()
+				if e != nil {
+					b.Fatal(e)
+				}
+
+				for _, line := range strings.Split(string(output), "\n") {
+					fields := strings.Fields(line)
+					if len(fields) != len(kinds)+2 || fields[1] != "benchmark" {
+						continue
+					}
+
+					val, e := strconv.ParseFloat(fields[i+2], 64)
+					if e != nil {
+						b.Fatal(e)
+					}
+					total += val
+					samples++
+				}
+			}
+
+			if samples == 0 {
+				b.Fatalf("no benchmark results from %s", module)
+			}
+			b.ReportMetric(total/float64(samples), "ns/op")
+		})
+	}
+}
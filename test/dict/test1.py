@@ -0,0 +1,52 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_counts_: dict[str, int] = {}
+_names_: dict[int, str] = {}
+_scores_: dict[str, float] = {}
+_flags_: dict[str, bool] = dict()
+
+
+def count_words(words):
+    for word in words:
+        _counts_[word] += 1
+
+
+if __name__ == '__main__':
+    count_words(['alpha', 'beta', 'alpha'])
+    _names_[_node_] = f'node {_node_}'
+    _scores_['sum'] += 0.5
+    _flags_[f'node_{_node_}'] = _node_ % 2 == 0
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _counts_['alpha'] == 2 * _node_count_
+        assert _counts_.get('beta') == _node_count_
+        assert _counts_.get('gamma') is None
+        assert _counts_.get('gamma', 0) == 0
+        assert 'alpha' in _counts_ and 'gamma' not in _counts_
+        assert len(_names_) == _node_count_
+        assert sorted(_names_.keys()) == list(range(_node_count_))
+        assert _names_[_node_count_ - 1] == f'node {_node_count_ - 1}'
+        assert _scores_['sum'] == 0.5 * _node_count_
+        assert _flags_['node_0'] and 'node_0' in _flags_
+
+        total = 0
+        for word in _counts_:
+            total += _counts_[word]
+        assert total == 3 * _node_count_
+
+        del _counts_['alpha']
+        assert 'alpha' not in _counts_
+        assert len(_counts_) == 1
+
+        try:
+            missing = _counts_['alpha']
+            assert False
+        except KeyError:
+            pass
+
+        print('words:', len(_counts_), 'names:', len(_names_))
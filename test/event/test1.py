@@ -0,0 +1,72 @@
+from threading import Condition, Event
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+_barrier_held_: callable = lambda n=_node_count_: ()
+
+_ready_: Event = Event()
+
+_lock_items_: callable = lambda: ()
+_unlock_items_: callable = lambda: ()
+_items_changed_: Condition = Condition(_lock_items_)
+_items_: int = 0
+
+
+if __name__ == '__main__':
+    if _node_ == 0:
+        assert not _ready_.is_set()
+        assert not _ready_.wait(0.05)
+        _ready_.set()
+    else:
+        assert _ready_.wait()
+    assert _ready_.is_set() and _ready_.wait(0)
+
+    if _node_ == 0:
+        for _ in range(_node_count_ - 1):
+            _lock_items_()
+            _items_ += 1
+            _items_changed_.notify()
+            _unlock_items_()
+    else:
+        _lock_items_()
+        while _items_ == 0:
+            _items_changed_.wait()
+        _items_ -= 1
+        _unlock_items_()
+
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _items_ == 0
+
+        _lock_items_()
+        assert not _items_changed_.wait(timeout=0.05)
+        _items_changed_.notify_all()
+        _unlock_items_()
+
+        try:
+            _items_changed_.notify()
+            assert False
+        except GothonError as e:
+            assert e.status == 1
+
+        _ready_.clear()
+        assert not _ready_.is_set()
+
+    # holding the lock is checked for the node itself, not only that it's held
+    _barrier_held_()
+    if _node_ == _node_count_ - 1:
+        _lock_items_()
+    _barrier_held_()
+    if _node_ != _node_count_ - 1:
+        try:
+            _items_changed_.wait(timeout=0.05)
+            assert False
+        except GothonError as e:
+            assert e.status == 1
+    _barrier_held_()
+    if _node_ == _node_count_ - 1:
+        _unlock_items_()
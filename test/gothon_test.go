@@ -1,6 +1,9 @@
 package test
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 const (
 	defaultNodeCount = 5
@@ -26,17 +29,126 @@ func TestString(t *testing.T) {
 	runGothon(t, "str", defaultNodeCount)
 }
 
+func TestBytes(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "bytes", defaultNodeCount)
+}
+
+func TestObject(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "object", defaultNodeCount)
+}
+
+func TestAtomic(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "atomic", defaultNodeCount)
+}
+
 func TestMutex(t *testing.T) {
 	installGothon(t)
 	runGothon(t, "mutex", defaultNodeCount)
 }
 
+func TestSemaphore(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "semaphore", defaultNodeCount)
+}
+
 func TestSync(t *testing.T) {
 	installGothon(t)
 	runGothon(t, "sync", defaultNodeCount)
 }
 
+func TestBarrier(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "barrier", defaultNodeCount)
+}
+
+func TestEvent(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "event", defaultNodeCount)
+}
+
 func TestQueue(t *testing.T) {
 	installGothon(t)
 	runGothon(t, "queue", defaultNodeCount)
 }
+
+func TestSyntax(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "syntax", defaultNodeCount)
+}
+
+func TestDict(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "dict", defaultNodeCount)
+}
+
+func TestList(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "list", defaultNodeCount)
+}
+
+func TestSet(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "set", defaultNodeCount)
+}
+
+func TestTimeout(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "timeout", defaultNodeCount)
+}
+
+func TestTopic(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "topic", defaultNodeCount)
+}
+
+func TestChan(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "chan", defaultNodeCount)
+}
+
+func TestProtocol(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "protocol", defaultNodeCount)
+}
+
+func TestSharedMemory(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "shared_memory", defaultNodeCount)
+}
+
+func TestBatch(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "batch", defaultNodeCount)
+}
+
+func TestTransaction(t *testing.T) {
+	installGothon(t)
+	runGothon(t, "transaction", defaultNodeCount)
+}
+
+func BenchmarkIntRegister(b *testing.B) {
+	for _, nodeCount := range []int{1, defaultNodeCount} {
+		b.Run(fmt.Sprintf("nodes=%d", nodeCount), func(b *testing.B) {
+			benchmarkGothon(b, "benchmark", nodeCount, "int_register", "add", "get")
+		})
+	}
+}
+
+func BenchmarkIntSharedMemory(b *testing.B) {
+	for _, nodeCount := range []int{1, defaultNodeCount} {
+		b.Run(fmt.Sprintf("nodes=%d", nodeCount), func(b *testing.B) {
+			benchmarkGothon(b, "benchmark", nodeCount, "int_shared_memory", "add", "get")
+		})
+	}
+}
+
+func BenchmarkIntBatch(b *testing.B) {
+	for _, nodeCount := range []int{1, defaultNodeCount} {
+		b.Run(fmt.Sprintf("nodes=%d", nodeCount), func(b *testing.B) {
+			benchmarkGothon(b, "benchmark", nodeCount, "int_batch", "add")
+		})
+	}
+}
@@ -0,0 +1,44 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_flags_: int = 0
+_v_: int = 0
+
+
+if __name__ == '__main__':
+    _flags_ |= 1 << _node_
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _flags_ == 2 ** _node_count_ - 1
+        _flags_ &= ~1
+        _flags_ ^= 0b11
+        assert _flags_ == ((2 ** _node_count_ - 1) & ~1) ^ 0b11
+
+        _v_ = -7
+        _v_ //= 2
+        assert _v_ == -4
+        _v_ %= 3
+        assert _v_ == 2
+        _v_ **= 10
+        assert _v_ == 1024
+        _v_ >>= 3
+        _v_ <<= 2
+        assert _v_ == 512
+        _v_ ^= 0xff
+        _v_ &= 0xf0
+        assert _v_ == 0xf0
+        _v_ %= -7
+        assert _v_ == 0xf0 % -7
+
+        try:
+            _v_ //= 0
+            assert False
+        except ArithmeticError:
+            pass
+        assert _v_ == 0xf0 % -7
+
+        print('flags:', bin(_flags_), 'v:', _v_)
@@ -0,0 +1,49 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_results_: list[int] = []
+_names_: list[str] = list()
+_ratios_: list[float] = []
+_flags_: list[bool] = []
+
+
+def square(n):
+    return n * n
+
+
+if __name__ == '__main__':
+    _results_.append(square(_node_))
+    _names_.append(f'node {_node_}')
+    _ratios_.append(_node_ / 2)
+    _flags_.append(_node_ % 2 == 0)
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert len(_results_) == _node_count_
+        assert sorted(_results_) == [square(n) for n in range(_node_count_)]
+        assert sum(_ratios_) == sum(n / 2 for n in range(_node_count_))
+        assert len([flag for flag in _flags_ if flag]) == (_node_count_ + 1) // 2
+        assert f'node {_node_count_ - 1}' in _names_ and 'node -1' not in _names_
+
+        _results_[0] = -1
+        _results_[-1] = -2
+        assert _results_[0] == -1 and _results_[-1] == -2
+        assert _results_[:1] == [-1] and _results_[-1:] == [-2]
+        assert len(_results_[1:-1]) == _node_count_ - 2
+        assert _results_[::_node_count_ - 1] == [-1, -2]
+
+        total = 0
+        for result in _results_:
+            total += 1
+        assert total == _node_count_
+
+        try:
+            missing = _results_[_node_count_]
+            assert False
+        except IndexError:
+            pass
+
+        print('results:', len(_results_), 'names:', sorted(_names_))
@@ -0,0 +1,67 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_readers_: callable = lambda n=_node_count_: ()
+_sync_main_: callable = lambda n=_node_count_: ()
+_barrier_held_: callable = lambda n=_node_count_: ()
+
+_lock_config_: callable = lambda: ()
+_unlock_config_: callable = lambda: ()
+_rlock_config_: callable = lambda: ()
+_runlock_config_: callable = lambda: ()
+
+_writers_: int = 0
+_writes_: int = 0
+
+
+if __name__ == '__main__':
+    # every node holds the read lock at the same time
+    _rlock_config_()
+    _sync_readers_(1)
+    _sync_readers_()
+    assert _writers_ == 0
+    _runlock_config_()
+
+    for _ in range(5):
+        _lock_config_()
+        _writers_ += 1
+        assert _writers_ == 1
+        _writes_ += 1
+        _writers_ -= 1
+        _unlock_config_()
+
+        _rlock_config_()
+        assert _writers_ == 0
+        _runlock_config_()
+
+    # unlocking a lock the node doesn't hold is refused
+    try:
+        _unlock_config_()
+        assert False, 'expected the unlock to be refused'
+    except GothonError as e:
+        assert e.status == 1
+    try:
+        _runlock_config_()
+        assert False, 'expected the runlock to be refused'
+    except GothonError as e:
+        assert e.status == 1
+
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _writes_ == 5 * _node_count_
+        _lock_config_()
+    _barrier_held_()
+
+    # even while another node holds it
+    if _node_ != 0:
+        try:
+            _unlock_config_()
+            assert False, 'expected the unlock to be refused'
+        except GothonError as e:
+            assert e.status == 1
+    _barrier_held_()
+
+    if _node_ == 0:
+        _unlock_config_()
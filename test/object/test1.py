@@ -0,0 +1,45 @@
+from dataclasses import dataclass
+from typing import Any
+
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+_lock_best_: callable = lambda: ()
+_unlock_best_: callable = lambda: ()
+
+
+@dataclass
+class Result:
+    node: int
+    score: float
+    tags: tuple
+
+
+_best_: object = Result(-1, 0.0, ())
+_config_: Any = {'retries': 3, 'hosts': ['a', 'b']}
+
+
+def report(result):
+    global _best_
+    _lock_best_()
+    if result.score > _best_.score:
+        _best_ = result
+    _unlock_best_()
+
+
+if __name__ == '__main__':
+    assert _config_['retries'] == 3
+    report(Result(_node_, _node_ * 1.5, ('node', _node_)))
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        best = _best_
+        assert best == Result(_node_count_ - 1, (_node_count_ - 1) * 1.5, ('node', _node_count_ - 1))
+
+        _config_ = None
+        assert _config_ is None
+
+        print('best:', best)
@@ -0,0 +1,41 @@
+import _gothon_
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_lock_counter_: callable = lambda: ()
+_unlock_counter_: callable = lambda: ()
+
+_counter_: int = 0
+
+
+def expect_error(status, version=_gothon_._GOTHON_PROTOCOL_VERSION):
+    current_version = _gothon_._GOTHON_PROTOCOL_VERSION
+    _gothon_._GOTHON_PROTOCOL_VERSION = version
+    try:
+        request_id = _gothon_._gothon_connection.send(2, b'test1/_bogus_', b'')
+    finally:
+        _gothon_._GOTHON_PROTOCOL_VERSION = current_version
+
+    reply_status, _ = _gothon_._gothon_connection.recv(5, request_id)
+    assert reply_status == status, 'expected the request to be rejected'
+
+
+if __name__ == '__main__':
+    # a request for an operation that doesn't exist is rejected
+    expect_error(2)
+
+    # as is one sent with an unsupported version of the protocol
+    expect_error(3, _gothon_._GOTHON_PROTOCOL_VERSION + 1)
+
+    # the connection is still usable afterwards, with replies matched to
+    # requests by their ID
+    _lock_counter_()
+    _counter_ += 1
+    _unlock_counter_()
+
+    _sync_main_(1)
+    _sync_main_()
+    assert _counter_ == _node_count_
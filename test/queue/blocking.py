@@ -0,0 +1,47 @@
+import time
+from queue import Queue
+
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_tasks_: Queue[int] = Queue(2)
+_results_: Queue[int] = Queue(100)
+_full_: Queue[int] = Queue(1)
+
+
+if __name__ == '__main__':
+    if _node_ == 0:
+        start = time.monotonic()
+        _, ok = _results_.get(block=True, timeout=0.1)
+        assert not ok and time.monotonic() - start >= 0.1
+        _, ok = _results_.get()
+        assert not ok
+
+        _, ok = _full_.put(1)
+        assert ok
+        start = time.monotonic()
+        _, ok = _full_.put(2, True, 0.05)
+        assert not ok and time.monotonic() - start >= 0.05
+
+        for i in range(1, (_node_count_ - 1) * 3 + 1):
+            _, ok = _tasks_.put(i, block=True)
+            assert ok
+
+        total = 0
+        for _ in range((_node_count_ - 1) * 3):
+            result, ok = _results_.get(True)
+            assert ok
+            total += result
+        n = (_node_count_ - 1) * 3
+        assert total == n * (n + 1)
+    else:
+        for _ in range(3):
+            task, ok = _tasks_.get(block=True)
+            assert ok
+            _results_.put(task * 2)
+
+    _sync_main_(1)
+    _sync_main_()
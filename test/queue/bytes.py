@@ -0,0 +1,39 @@
+from queue import Queue
+
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_blobs_: Queue[bytes] = Queue(10)
+blobs = []
+
+
+def add_blob_until_full():
+    current_blob = b''
+    while not _blobs_.full():
+        ok = _blobs_.put(current_blob)
+        if ok:
+            current_blob += b'\xff'
+
+
+def remove_blob_until_empty():
+    while not _blobs_.empty():
+        blob, ok = _blobs_.get()
+        if ok:
+            blobs.append(blob)
+
+
+if __name__ == '__main__':
+    add_blob_until_full()
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        print(f'size before empty: {_blobs_.qsize()}')
+        remove_blob_until_empty()
+        print(f'size after empty: {_blobs_.qsize()}')
+        blobs.sort()
+        for blob in blobs:
+            print(f'{blob} ')
@@ -0,0 +1,39 @@
+from queue import Queue
+
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_tasks_: Queue[object] = Queue(10)
+tasks = []
+
+
+def add_task_until_full():
+    current_task = 0
+    while not _tasks_.full():
+        ok = _tasks_.put(('task', current_task, {'node': _node_}))
+        if ok:
+            current_task += 1
+
+
+def remove_task_until_empty():
+    while not _tasks_.empty():
+        task, ok = _tasks_.get()
+        if ok:
+            tasks.append(task)
+
+
+if __name__ == '__main__':
+    add_task_until_full()
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        print(f'size before empty: {_tasks_.qsize()}')
+        remove_task_until_empty()
+        print(f'size after empty: {_tasks_.qsize()}')
+        tasks.sort(key=lambda t: t[1])
+        for task in tasks:
+            print(f'{task[0]} {task[1]} ')
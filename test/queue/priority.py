@@ -0,0 +1,43 @@
+from queue import PriorityQueue
+
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_jobs_: PriorityQueue[int] = PriorityQueue(100)
+_names_: PriorityQueue[str] = PriorityQueue(100)
+_costs_: PriorityQueue[float] = PriorityQueue(3)
+
+
+if __name__ == '__main__':
+    for i in range(10):
+        _, ok = _jobs_.put((i * 7 + _node_ * 3) % 50)
+        assert ok
+    _names_.put(f'node-{_node_}')
+
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _jobs_.qsize() == _node_count_ * 10
+        jobs = []
+        while not _jobs_.empty():
+            job, ok = _jobs_.get()
+            assert ok
+            jobs.append(job)
+        assert jobs == sorted(jobs) and len(jobs) == _node_count_ * 10
+
+        names = [_names_.get()[0] for _ in range(_node_count_)]
+        assert names == sorted(names)
+
+        for cost in [2.5, -1.0, 0.5]:
+            _costs_.put(cost)
+        assert _costs_.full()
+        _, ok = _costs_.put(9.9)
+        assert not ok
+        assert _costs_.get() == (-1.0, True)
+        assert _costs_.get() == (0.5, True)
+        assert _costs_.get() == (2.5, True)
+        assert _costs_.get(block=True, timeout=0.05) == (0, False)
@@ -0,0 +1,38 @@
+import time
+from queue import Queue
+
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_tasks_: Queue[int] = Queue(100)
+_done_: int = 0
+_idle_: Queue[int] = Queue(1)
+
+
+if __name__ == '__main__':
+    if _node_ == 0:
+        try:
+            _idle_.task_done()
+            assert False
+        except ValueError:
+            pass
+        _idle_.join()
+
+        for i in range(20):
+            _tasks_.put(i)
+        _tasks_.join()
+        assert _done_ == 20
+    else:
+        while True:
+            task, ok = _tasks_.get(block=True, timeout=1)
+            if not ok:
+                break
+            time.sleep(0.01)
+            _done_ += 1
+            _tasks_.task_done()
+
+    _sync_main_(1)
+    _sync_main_()
@@ -0,0 +1,45 @@
+import time
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_acquire_db_: callable = lambda n=2: ()
+_release_db_: callable = lambda: ()
+
+_holders_: int = 0
+_peak_: int = 0
+
+
+if __name__ == '__main__':
+    for _ in range(5):
+        _acquire_db_()
+        holders = _holders_.fetch_add(1) + 1
+        _peak_ = max(_peak_, holders)
+        time.sleep(0.01)
+        _holders_ -= 1
+        _release_db_()
+
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert 1 <= _peak_ <= 2
+        assert _holders_ == 0
+
+        assert _acquire_db_(False)
+        assert _acquire_db_()
+        assert not _acquire_db_(blocking=False)
+        _release_db_()
+        if _acquire_db_(blocking=False):
+            _release_db_()
+        _release_db_()
+
+        try:
+            _release_db_()
+            assert False
+        except ValueError:
+            pass
+
+        print('peak:', _peak_)
@@ -0,0 +1,31 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+# names containing another kind's prefix after their own
+_acquire_block_: callable = lambda n=1: ()
+_release_block_: callable = lambda: ()
+
+_lock_sync_db_: callable = lambda: ()
+_unlock_sync_db_: callable = lambda: ()
+
+_acquired_: int = 0
+_locked_: int = 0
+
+
+if __name__ == '__main__':
+    for _ in range(10):
+        _acquire_block_()
+        _acquired_ = _acquired_ + 1
+        _release_block_()
+
+        _lock_sync_db_()
+        _locked_ = _locked_ + 1
+        _unlock_sync_db_()
+
+    _sync_main_(1)
+    _sync_main_()
+
+    assert _acquired_ == 10 * _node_count_
+    assert _locked_ == 10 * _node_count_
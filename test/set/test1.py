@@ -0,0 +1,48 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_visited_: set[str] = set()
+_ids_: set[int] = set()
+_claimed_: int = 0
+
+
+def crawl(urls):
+    global _claimed_
+    for url in urls:
+        if _visited_.add(url):
+            _claimed_ += 1
+
+
+if __name__ == '__main__':
+    crawl([f'https://example.com/{i}' for i in range(10)])
+    _ids_.add(_node_)
+    _ids_.add(-1)
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _claimed_ == 10
+        assert len(_visited_) == 10
+        assert 'https://example.com/0' in _visited_ and 'https://example.com/10' not in _visited_
+        assert len(_ids_) == _node_count_ + 1
+        assert sorted(_ids_) == list(range(-1, _node_count_))
+
+        total = 0
+        for i in _ids_:
+            total += i
+        assert total == sum(range(_node_count_)) - 1
+
+        assert not _ids_.add(-1)
+        _ids_.discard(-1)
+        _ids_.discard(-1)
+        assert -1 not in _ids_
+
+        try:
+            _ids_.remove(-1)
+            assert False
+        except KeyError:
+            pass
+
+        print('visited:', len(_visited_), 'ids:', sorted(_ids_))
@@ -0,0 +1,71 @@
+# gothon:shared_memory = True
+# gothon:shared_memory:_label_ = False
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+_lock_counter_: callable = lambda: ()
+_unlock_counter_: callable = lambda: ()
+
+_counter_: int = 0
+_ticket_: int = 0
+_total_: float = 0.0
+_highest_: int = -1
+_leader_: int = -1
+_done_: bool = False
+_flags_: int = 0
+_label_: str = ''
+
+
+if __name__ == '__main__':
+    for _ in range(100):
+        _lock_counter_()
+        _counter_ += 1
+        _unlock_counter_()
+
+    tickets = [_ticket_.fetch_add(1) for _ in range(10)]
+    assert tickets == sorted(tickets)
+
+    _total_.fetch_add(0.5)
+    _highest_ = max(_highest_, _node_)
+    _flags_ |= 1 << _node_
+    _leader_.compare_and_swap(-1, _node_)
+
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _counter_ == 100 * _node_count_
+        assert _ticket_ == 10 * _node_count_
+        assert _total_ == 0.5 * _node_count_
+        assert _highest_ == _node_count_ - 1
+        assert _flags_ == (1 << _node_count_) - 1
+        assert 0 <= _leader_ < _node_count_
+
+        assert not _done_.swap(True)
+        assert _done_ and _done_.compare_and_swap(True, False) and not _done_
+
+        _counter_ = 7
+        _counter_ //= 2
+        _counter_ **= 3
+        _counter_ -= 28
+        _counter_ *= -1
+        _counter_ /= 3
+        assert _counter_ == 0
+
+        _ticket_ = 2 ** 62
+        _ticket_ <<= 1
+        assert _ticket_ == -(2 ** 63)
+
+        try:
+            _counter_ %= 0
+            assert False, 'expected modulo by zero to fail'
+        except ArithmeticError:
+            pass
+
+        _total_ /= 2
+        assert _total_ == 0.25 * _node_count_
+
+        _label_ = 'done'
+        assert _label_ == 'done'
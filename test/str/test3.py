@@ -0,0 +1,17 @@
+_node_: int = 0
+
+_total_: int = 0
+_name_: str = 'gothon'
+
+
+if __name__ == '__main__':
+    if _node_ == 0:
+        _total_ = 3
+
+        # only the uses of the variables are translated, not their names in
+        # strings (the replacement fields of f-strings being uses)
+        s = "_total_ is here"
+        assert s == '_tot' + 'al_ is here'
+        s = f'_total_ is {_total_}, {{_name_}} is {_name_!r}'
+        assert s == '_tot' + "al_ is 3, {_na" + "me_} is 'gothon'"
+        print('_total_:', _total_)  # _total_ stays as it is here
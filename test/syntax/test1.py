@@ -0,0 +1,28 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_lock_total_: callable = lambda: ()
+_unlock_total_: callable = lambda: ()
+
+_total_: int = 0
+
+"""
+_total_ = 100
+"""
+
+
+if __name__ == '__main__':
+    label = 'a=b'; _total_ += 1; note = 'x=1'  # _total_ = 5
+    if _node_ >= 0: _total_ += 1
+    _lock_total_(); _total_ += 1; _unlock_total_()
+
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        total = _total_
+        assert label == 'a=b' and note == 'x=1'
+        assert total == 3 * _node_count_, f'total: {total}'
+        print(f'total: {total}')
@@ -0,0 +1,43 @@
+from queue import Queue
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_total_: int = 0
+_numbers_: Queue[int] = Queue(
+    100
+)
+
+
+def get_val(
+    a: int,
+    b: int = 2,
+) -> int:
+    return a + b
+
+
+if __name__ == "__main__":
+    a, b = 1, 2
+    _total_ += (a +
+                b)
+    _total_ += \
+        get_val(1)
+    _numbers_.put(
+        get_val(
+            _node_,
+        )
+    ); _total_ += 1
+
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        values = [
+            _total_,
+            _node_count_,
+        ]
+        assert values[0] == 7 * values[1], f"total: {values[0]}"
+        assert _numbers_.qsize() == _node_count_
+        print(f"total: {values[0]}")
@@ -0,0 +1,21 @@
+from threading import Event
+
+# gothon:timeout:_never_ = 0.2
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_never_: Event = Event()
+
+
+if __name__ == '__main__':
+    try:
+        _never_.wait()
+        assert False
+    except TimeoutError as e:
+        assert '_never_' in str(e) and 'wait' in str(e)
+
+    _sync_main_(1)
+    _sync_main_()
@@ -0,0 +1,80 @@
+# gothon:timeout = 0.2
+# gothon:timeout:_sync_held_ = 30
+# gothon:timeout:_sync_done_ = 30
+# gothon:timeout:_sync_released_ = 30
+# gothon:timeout:_sync_left_ = 30
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_held_: callable = lambda n=_node_count_: ()
+_sync_done_: callable = lambda n=_node_count_: ()
+_sync_released_: callable = lambda n=_node_count_: ()
+_sync_left_: callable = lambda n=_node_count_: ()
+
+_acquire_slot_: callable = lambda n=1: ()
+_release_slot_: callable = lambda: ()
+
+_lock_file_: callable = lambda: ()
+_unlock_file_: callable = lambda: ()
+
+_barrier_pair_: callable = lambda n=2: ()
+
+
+if __name__ == '__main__':
+    if _node_ == 0:
+        _acquire_slot_()
+        _lock_file_()
+
+    _sync_held_(1)
+    _sync_held_()
+
+    if _node_ != 0:
+        try:
+            _acquire_slot_()
+            assert False
+        except TimeoutError as e:
+            assert '_acquire_slot_' in str(e) and 'acquire' in str(e)
+
+        try:
+            _lock_file_()
+            assert False
+        except TimeoutError as e:
+            assert '_lock_file_' in str(e)
+
+    _sync_done_(1)
+    _sync_done_()
+
+    if _node_ == 0:
+        _release_slot_()
+        _unlock_file_()
+
+    _sync_released_(1)
+    _sync_released_()
+
+    # the requests that timed out were canceled, rather than acquiring the
+    # semaphore and the lock for nodes no longer waiting on them
+    if _node_ == 0:
+        assert _acquire_slot_(False)
+        _release_slot_()
+    _lock_file_()
+    _unlock_file_()
+
+    # a node that stopped waiting on the barrier no longer counts as arrived, so
+    # the next node to arrive is left waiting for another one too
+    if _node_ == 0:
+        try:
+            _barrier_pair_()
+            assert False
+        except TimeoutError as e:
+            assert '_barrier_pair_' in str(e)
+
+    _sync_left_(1)
+    _sync_left_()
+
+    if _node_ == 1:
+        try:
+            _barrier_pair_()
+            assert False
+        except TimeoutError as e:
+            assert '_barrier_pair_' in str(e)
@@ -0,0 +1,45 @@
+from queue import Queue
+
+# gothon:timeout:_jobs_ = 0.2
+# gothon:timeout:_full_ = 0.2
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+
+_jobs_: Queue[int] = Queue(10)
+_full_: Queue[int] = Queue(1)
+
+
+if __name__ == '__main__':
+    if _node_ == 0:
+        try:
+            _jobs_.get(block=True)
+            assert False
+        except TimeoutError as e:
+            assert '_jobs_' in str(e) and 'get' in str(e)
+
+        # the get timing out was canceled, so it doesn't take the item put next
+        _, ok = _jobs_.put(1)
+        assert ok
+        val, ok = _jobs_.get()
+        assert ok and val == 1
+
+        # likewise the put timing out isn't made once there's room
+        _, ok = _full_.put(1)
+        assert ok
+        try:
+            _full_.put(2, block=True)
+            assert False
+        except TimeoutError as e:
+            assert '_full_' in str(e)
+
+        val, ok = _full_.get()
+        assert ok and val == 1
+        _full_.task_done()
+        assert _full_.empty()
+        _full_.join()
+
+    _sync_main_(1)
+    _sync_main_()
@@ -0,0 +1,53 @@
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+_sync_late_: callable = lambda n=_node_count_: ()
+
+_params_: Topic[str] = Topic()
+_steps_: Topic[int] = Topic()
+_late_: Topic[str] = Topic()
+
+
+if __name__ == '__main__':
+    if _node_ == 0:
+        _params_.put('lr=0.1')
+        _params_.put('lr=0.01')
+
+    received = []
+    while len(received) < 2:
+        msg, ok = _params_.get(block=True, timeout=5)
+        assert ok
+        received.append(msg)
+    assert received == ['lr=0.1', 'lr=0.01']
+    assert _params_.empty()
+    assert _params_.qsize() == 0
+    _, ok = _params_.get()
+    assert not ok
+
+    _steps_.put(_node_)
+    _sync_main_(1)
+    _sync_main_()
+
+    assert _steps_.qsize() == _node_count_
+    assert not _steps_.full()
+    steps = []
+    for _ in range(_node_count_):
+        step, ok = _steps_.get()
+        assert ok
+        steps.append(step)
+    assert sorted(steps) == list(range(_node_count_))
+    assert _steps_.empty()
+
+    # every node is subscribed from the start, so a message one node got before
+    # the others first called get() is kept for them
+    if _node_ == 0:
+        _late_.put('a')
+        msg, ok = _late_.get()
+        assert ok and msg == 'a'
+    _sync_late_(1)
+    _sync_late_()
+
+    msg, ok = _late_.get()
+    assert ok == (_node_ != 0)
+    assert _late_.empty()
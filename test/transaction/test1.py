@@ -0,0 +1,126 @@
+import threading
+
+_node_: int = 0
+_node_count_: int = 0
+
+_sync_main_: callable = lambda n=_node_count_: ()
+_transaction_main_: callable = lambda: ()
+_batch_main_: callable = lambda: ()
+
+_balance_a_: int = 1000
+_balance_b_: int = 0
+_transfers_: int = 0
+_owner_: int = -1
+_note_: str = ''
+_log_: list[int] = []
+
+
+def transfer():
+    global _balance_a_, _balance_b_, _transfers_
+
+    # transactions racing with those of other nodes are retried until they commit
+    while True:
+        try:
+            with _transaction_main_():
+                if _balance_a_ >= 10:
+                    _balance_a_ -= 10
+                    _balance_b_ += 10
+                    _transfers_ += 1
+            return
+        except GothonConflictError:
+            continue
+
+
+def check_balances():
+    while True:
+        try:
+            # the balances are never seen mid transfer
+            with _transaction_main_():
+                assert _balance_a_ + _balance_b_ == 1000
+            return
+        except GothonConflictError:
+            continue
+
+
+def write_note(note):
+    global _note_
+
+    # threads other than the one making a transaction write outside of it
+    _note_ = note
+
+
+if __name__ == '__main__':
+    for _ in range(50):
+        transfer()
+        check_balances()
+
+    _sync_main_(1)
+    _sync_main_()
+
+    if _node_ == 0:
+        assert _transfers_ == min(50 * _node_count_, 100)
+        assert _balance_a_ == 1000 - 10 * _transfers_
+        assert _balance_b_ == 10 * _transfers_
+
+        # reads within a transaction see its writes
+        with _transaction_main_():
+            _note_ = 'a'
+            _note_ += 'b'
+            assert _note_ == 'ab'
+        assert _note_ == 'ab'
+
+        # nested transactions and batches are part of the outermost
+        with _transaction_main_():
+            _balance_a_ = 1
+            with _transaction_main_():
+                _balance_a_ += 1
+            with _batch_main_():
+                _balance_a_ += 1
+            assert _balance_a_ == 3
+        assert _balance_a_ == 3
+
+        # nothing is written if the block raises
+        try:
+            with _transaction_main_():
+                _balance_a_ = 0
+                _note_ = 'c'
+                raise ValueError()
+        except ValueError:
+            pass
+        assert _balance_a_ == 3 and _note_ == 'ab'
+
+        # atomic operations can't be made within a transaction
+        try:
+            with _transaction_main_():
+                _owner_.compare_and_swap(-1, _node_)
+            assert False, 'expected the operation to be refused'
+        except RuntimeError as e:
+            assert "'cas'" in str(e) and 'transaction' in str(e)
+        assert _owner_ == -1
+
+        # neither can operations on variables transactions don't cover
+        try:
+            with _transaction_main_():
+                _log_.append(_node_)
+            assert False, 'expected the operation to be refused'
+        except RuntimeError as e:
+            assert 'transaction' in str(e)
+        assert len(_log_) == 0
+
+        # nothing is written if a variable used was changed in the meantime
+        try:
+            with _transaction_main_():
+                _note_ += 'c'
+                _balance_b_ = 0
+                writer = threading.Thread(target=write_note, args=('d',))
+                writer.start()
+                writer.join()
+            assert False, 'expected a conflict'
+        except GothonConflictError:
+            pass
+        assert _note_ == 'd' and _balance_b_ == 10 * _transfers_
+
+        # transactions can still be made after a conflict
+        with _transaction_main_():
+            _balance_b_ = 0
+        assert _balance_b_ == 0